@@ -0,0 +1,34 @@
+// Command candidgen writes Go bindings for a canister's Candid interface. It is run
+// through go:generate, see internal/motoko/canister.go.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"pedulicarbon/internal/candidgen"
+)
+
+func main() {
+	didPath := flag.String("did", "", "path to the .did file")
+	out := flag.String("out", "", "output Go file")
+	pkg := flag.String("package", "", "Go package name")
+	actor := flag.String("actor", "", "Go type that receives the method wrappers")
+	flag.Parse()
+	if *didPath == "" || *out == "" || *pkg == "" || *actor == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(*didPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := candidgen.Generate(src, candidgen.Config{Package: *pkg, Source: *didPath, Actor: *actor})
+	if err != nil {
+		log.Fatalf("candidgen %s: %v", *didPath, err)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressHandler struct {
+	AddressService *service.AddressService
+}
+
+func NewAddressHandler(addressService *service.AddressService) *AddressHandler {
+	return &AddressHandler{AddressService: addressService}
+}
+
+type addressRequest struct {
+	Label         string `json:"label"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	AddressLine   string `json:"address_line"`
+	City          string `json:"city"`
+	Province      string `json:"province"`
+	PostalCode    string `json:"postal_code"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+func (r addressRequest) toModel() *model.ShippingAddress {
+	return &model.ShippingAddress{
+		Label:         r.Label,
+		RecipientName: r.RecipientName,
+		Phone:         r.Phone,
+		AddressLine:   r.AddressLine,
+		City:          r.City,
+		Province:      r.Province,
+		PostalCode:    r.PostalCode,
+		IsDefault:     r.IsDefault,
+	}
+}
+
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	addresses, err := h.AddressService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, addresses)
+}
+
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	address := req.toModel()
+	if err := h.AddressService.Create(userID, address); err != nil {
+		writeAddressError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, address)
+}
+
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	addressID, err := strconv.ParseUint(c.Param("address_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address id"})
+		return
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	address := req.toModel()
+	if err := h.AddressService.Update(userID, uint(addressID), address); err != nil {
+		writeAddressError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, address)
+}
+
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	addressID, err := strconv.ParseUint(c.Param("address_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address id"})
+		return
+	}
+	if err := h.AddressService.Delete(userID, uint(addressID)); err != nil {
+		writeAddressError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func writeAddressError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAddress), errors.Is(err, service.ErrAddressLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
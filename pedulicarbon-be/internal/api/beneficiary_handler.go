@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BeneficiaryHandler struct {
+	BeneficiaryService *service.BeneficiaryService
+}
+
+func NewBeneficiaryHandler(beneficiaryService *service.BeneficiaryService) *BeneficiaryHandler {
+	return &BeneficiaryHandler{BeneficiaryService: beneficiaryService}
+}
+
+func (h *BeneficiaryHandler) ListBeneficiaries(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	list, err := h.BeneficiaryService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateBeneficiary menambah rekening tujuan: channel bank dengan provider kode
+// bank, atau channel ewallet dengan provider gopay/ovo/dana dan nomor HP.
+func (h *BeneficiaryHandler) CreateBeneficiary(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Label         string `json:"label"`
+		Channel       string `json:"channel" binding:"required"`
+		Provider      string `json:"provider" binding:"required"`
+		AccountNumber string `json:"account_number" binding:"required"`
+		HolderName    string `json:"holder_name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b := &model.Beneficiary{
+		Label:         req.Label,
+		Channel:       req.Channel,
+		Provider:      req.Provider,
+		AccountNumber: req.AccountNumber,
+		HolderName:    req.HolderName,
+	}
+	if err := h.BeneficiaryService.Create(userID, b); err != nil {
+		writeBeneficiaryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+func (h *BeneficiaryHandler) DeleteBeneficiary(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("beneficiary_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid beneficiary id"})
+		return
+	}
+	if err := h.BeneficiaryService.Remove(userID, uint(id)); err != nil {
+		writeBeneficiaryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// VerifyBeneficiary menandai rekening tujuan terverifikasi (finance admin).
+func (h *BeneficiaryHandler) VerifyBeneficiary(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid beneficiary id"})
+		return
+	}
+	b, err := h.BeneficiaryService.Verify(currentUserID(c), uint(id))
+	if err != nil {
+		writeBeneficiaryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+func writeBeneficiaryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrBeneficiaryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidBeneficiary), errors.Is(err, service.ErrBeneficiaryLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSelfVerification):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBeneficiaryExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
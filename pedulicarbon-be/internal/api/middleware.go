@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"pedulicarbon/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ctxUserIDKey = "auth_user_id"
+	ctxRoleKey   = "auth_role"
+)
+
+// AuthMiddleware rejects requests without a valid bearer token and stores the caller's user ID and role in the context.
+func AuthMiddleware(authService *service.AuthService, userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		userID, err := authService.ParseToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		// Role dibaca dari DB supaya perubahan role langsung berlaku
+		user, err := userService.GetProfile(userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": service.ErrInvalidToken.Error()})
+			return
+		}
+		c.Set(ctxUserIDKey, user.ID)
+		c.Set(ctxRoleKey, user.Role)
+		c.Next()
+	}
+}
+
+// currentUserID returns the authenticated caller set by AuthMiddleware.
+func currentUserID(c *gin.Context) uint {
+	return c.GetUint(ctxUserIDKey)
+}
+
+// currentRole returns the authenticated caller's role set by AuthMiddleware.
+func currentRole(c *gin.Context) string {
+	return c.GetString(ctxRoleKey)
+}
+
+// requireSelf parses the user ID path param and makes sure it refers to the caller,
+// unless the caller may read any user's data.
+func requireSelf(c *gin.Context, param string) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	if uint(userID) != currentUserID(c) && !HasPermission(currentRole(c), PermUserReadAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return 0, false
+	}
+	return uint(userID), true
+}
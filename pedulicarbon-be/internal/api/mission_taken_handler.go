@@ -1,11 +1,11 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/service"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +31,7 @@ func (h *MissionTakenHandler) TakeMission(c *gin.Context) {
 		return
 	}
 	req.MissionID = uint(missionID)
+	req.UserID = currentUserID(c)
 	if err := h.MissionTakenService.TakeMission(&req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -39,13 +40,11 @@ func (h *MissionTakenHandler) TakeMission(c *gin.Context) {
 }
 
 func (h *MissionTakenHandler) GetUserMissions(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
 		return
 	}
-	missions, err := h.MissionTakenService.GetUserMissions(uint(userID))
+	missions, err := h.MissionTakenService.GetUserMissions(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -68,7 +67,15 @@ func (h *MissionTakenHandler) SubmitProof(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.MissionTakenService.UpdateProof(uint(mtID), req.ProofURL, req.GPS); err != nil {
+	if err := h.MissionTakenService.UpdateProof(currentUserID(c), uint(mtID), req.ProofURL, req.GPS); err != nil {
+		if errors.Is(err, service.ErrNotOwner) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTransition) || errors.Is(err, service.ErrResubmitLimit) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -82,27 +89,60 @@ func (h *MissionTakenHandler) VerifyMission(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mission taken id"})
 		return
 	}
-	if err := h.MissionTakenService.VerifyMission(uint(mtID)); err != nil {
-		if err != nil && (err.Error() == "user belum punya ii_principal (ICP principal)" ||
-			containsIgnoreCase(err.Error(), "ii_principal")) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User belum punya ICP principal (ii_principal). Silakan update profil Anda."})
+	job, err := h.MissionTakenService.RequestVerification(uint(mtID), currentUserID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrPrincipalNotVerified) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User belum memverifikasi ICP principal (ii_principal). Silakan hubungkan Internet Identity Anda."})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "mission verified"})
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":       "verification queued",
+		"operation_id": job.ID,
+	})
 }
 
-func (h *MissionTakenHandler) GetUserNFTs(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+func (h *MissionTakenHandler) RejectMission(c *gin.Context) {
+	mtIDStr := c.Param("id")
+	mtID, err := strconv.ParseUint(mtIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mission taken id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.MissionTakenService.RejectMission(uint(mtID), currentUserID(c), req.Reason); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "mission rejected"})
+}
+
+func (h *MissionTakenHandler) GetUserNFTs(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
 		return
 	}
 	// Ambil NFT yang dimiliki user dari DB (mapping lokal)
-	nfts, err := h.MissionTakenService.UserNFTRepo.GetNFTsByUserID(uint(userID))
+	nfts, err := h.MissionTakenService.UserNFTRepo.GetNFTsByUserID(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -113,21 +153,26 @@ func (h *MissionTakenHandler) GetUserNFTs(c *gin.Context) {
 func (h *MissionTakenHandler) ClaimNFT(c *gin.Context) {
 	nftID := c.Param("id")
 	var req struct {
-		UserID         uint   `json:"user_id" binding:"required"`
 		CertificateURL string `json:"certificate_url"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.MissionTakenService.ClaimNFT(req.UserID, nftID, req.CertificateURL); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	job, err := h.MissionTakenService.RequestClaim(currentUserID(c), nftID, req.CertificateURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNFTNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNFTAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
-	c.JSON(200, gin.H{"status": "NFT claimed & burned"})
-}
-
-// containsIgnoreCase helper
-func containsIgnoreCase(s, substr string) bool {
-	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":       "claim queued",
+		"operation_id": job.ID,
+	})
 }
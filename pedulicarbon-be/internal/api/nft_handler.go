@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NFTHandler struct {
+	NFTService *service.NFTService
+}
+
+func NewNFTHandler(s *service.NFTService) *NFTHandler {
+	return &NFTHandler{NFTService: s}
+}
+
+// GetNFT mengembalikan baris UserNFT beserta data on-chain dari get_nft_detail.
+// on_chain bernilai null bila NFT sudah tidak ada di canister (mis. sudah di-claim).
+func (h *NFTHandler) GetNFT(c *gin.Context) {
+	detail, err := h.NFTService.GetNFTDetail(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNFTNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	nft := detail.UserNFT
+	userID := currentUserID(c)
+	claimedBySelf := nft.ClaimedBy != nil && *nft.ClaimedBy == userID
+	if nft.UserID != userID && !claimedBySelf && !HasPermission(currentRole(c), PermUserReadAny) {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrNFTNotFound.Error()})
+		return
+	}
+
+	var onChain gin.H
+	if d := detail.OnChain; d != nil {
+		onChain = gin.H{
+			"id":            d.ID,
+			"owner":         d.Owner.String(),
+			"mission_id":    d.MissionID.BigInt().Uint64(),
+			"carbon_amount": d.CarbonAmount,
+			"minted_at":     time.Unix(0, d.Timestamp.BigInt().Int64()).UTC(),
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"nft": nft, "on_chain": onChain})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	NotificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{NotificationService: notificationService}
+}
+
+// ListNotifications mengembalikan notifikasi user, terbaru dulu, beserta jumlah yang belum dibaca.
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultNotificationLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	notifications, unread, err := h.NotificationService.List(userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": unread, "notifications": notifications})
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+		return
+	}
+	if err := h.NotificationService.MarkRead(userID, uint(id)); err != nil {
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "read"})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"pedulicarbon/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OperationHandler struct {
+	JobService *service.JobService
+}
+
+func NewOperationHandler(s *service.JobService) *OperationHandler {
+	return &OperationHandler{JobService: s}
+}
+
+func (h *OperationHandler) GetOperation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid operation id"})
+		return
+	}
+	job, err := h.JobService.GetOperation(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+	if job.CreatedBy != currentUserID(c) && !HasPermission(currentRole(c), PermUserReadAny) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+	var result interface{}
+	if job.Result != "" {
+		json.Unmarshal([]byte(job.Result), &result)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":          job.ID,
+		"type":        job.Type,
+		"status":      job.Status,
+		"attempts":    job.Attempts,
+		"next_run_at": job.RunAt,
+		"last_error":  job.LastError,
+		"result":      result,
+		"created_at":  job.CreatedAt,
+		"finished_at": job.FinishedAt,
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"pedulicarbon/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is an action guarded by RequirePermission. All role rules live in
+// rolePermissions below so they can be reviewed and tested in one place.
+type Permission string
+
+const (
+	PermMissionManage Permission = "mission:manage"
+	PermMissionVerify Permission = "mission:verify"
+	PermNFTClaim      Permission = "nft:claim"
+	PermRewardManage  Permission = "reward:manage"
+	PermCatalogManage Permission = "catalog:manage"
+	// PermCatalogManageAny mengelola item katalog milik merchant mana pun dan item platform.
+	PermCatalogManageAny Permission = "catalog:manage_any"
+	PermWalletManage     Permission = "wallet:manage"
+	PermPayoutManage     Permission = "payout:manage"
+	PermUserManage       Permission = "user:manage"
+	PermUserReadAny      Permission = "user:read_any"
+)
+
+var rolePermissions = map[string][]Permission{
+	model.RoleUser:         {PermNFTClaim},
+	model.RoleInstitution:  {PermNFTClaim},
+	model.RoleVerifier:     {PermMissionVerify},
+	model.RoleFinanceAdmin: {PermPayoutManage, PermUserReadAny},
+	model.RoleMerchant:     {PermCatalogManage, PermRewardManage},
+	model.RoleAdmin: {
+		PermMissionManage, PermRewardManage, PermCatalogManage, PermCatalogManageAny,
+		PermWalletManage, PermUserManage, PermUserReadAny,
+	},
+}
+
+// HasPermission reports whether role is allowed to perform perm.
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission aborts with 403 unless the caller's role grants perm. It must run after AuthMiddleware.
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(currentRole(c), perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pedulicarbon/internal/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHasPermission(t *testing.T) {
+	cases := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{model.RoleVerifier, PermMissionVerify, true},
+		{model.RoleAdmin, PermMissionVerify, false},
+		{model.RoleUser, PermMissionVerify, false},
+		{model.RoleFinanceAdmin, PermPayoutManage, true},
+		{model.RoleAdmin, PermPayoutManage, false},
+		{model.RoleMerchant, PermPayoutManage, false},
+		{model.RoleMerchant, PermCatalogManage, true},
+		{model.RoleAdmin, PermCatalogManage, true},
+		{model.RoleUser, PermCatalogManage, false},
+		{model.RoleMerchant, PermCatalogManageAny, false},
+		{model.RoleAdmin, PermCatalogManageAny, true},
+		{model.RoleAdmin, PermMissionManage, true},
+		{model.RoleVerifier, PermMissionManage, false},
+		{model.RoleAdmin, PermWalletManage, true},
+		{model.RoleUser, PermWalletManage, false},
+		{model.RoleInstitution, PermNFTClaim, true},
+		{"", PermNFTClaim, false},
+		{"superuser", PermUserManage, false},
+	}
+	for _, tc := range cases {
+		if got := HasPermission(tc.role, tc.perm); got != tc.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tc.role, tc.perm, got, tc.want)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		role string
+		want int
+	}{
+		{model.RoleVerifier, http.StatusOK},
+		{model.RoleUser, http.StatusForbidden},
+	} {
+		r := gin.New()
+		r.POST("/verify", func(c *gin.Context) {
+			c.Set(ctxRoleKey, tc.role)
+		}, RequirePermission(PermMissionVerify), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/verify", nil))
+		if w.Code != tc.want {
+			t.Errorf("role %q: status = %d, want %d", tc.role, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PointHandler struct {
+	PointService *service.PointService
+	UserService  *service.UserService
+}
+
+func NewPointHandler(pointService *service.PointService, userService *service.UserService) *PointHandler {
+	return &PointHandler{PointService: pointService, UserService: userService}
+}
+
+// GetHistory mengembalikan saldo dan baris ledger point user, terbaru dulu.
+// Query: limit (default 50, maks 200) dan offset.
+func (h *PointHandler) GetHistory(c *gin.Context) {
+	id, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	user, err := h.UserService.GetProfile(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultPointHistoryLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	entries, total, err := h.PointService.History(id, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"balance":      user.Points,
+		"total":        total,
+		"transactions": entries,
+	})
+}
+
+// AdjustPoints memposting koreksi manual (positif atau negatif) ke saldo user.
+func (h *PointHandler) AdjustPoints(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req struct {
+		Amount int    `json:"amount" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, err := h.PointService.Post(service.PointPosting{
+		UserID:      uint(id),
+		Amount:      req.Amount,
+		EntryType:   model.PointEntryAdjustment,
+		RefType:     "admin",
+		Description: req.Reason + " (oleh user " + strconv.FormatUint(uint64(currentUserID(c)), 10) + ")",
+	})
+	switch {
+	case errors.Is(err, service.ErrInsufficientPoints), errors.Is(err, service.ErrInvalidPointAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/service"
+
+	"github.com/aviate-labs/agent-go/certification/ii"
+	"github.com/gin-gonic/gin"
+)
+
+type PrincipalHandler struct {
+	PrincipalService *service.PrincipalService
+}
+
+func NewPrincipalHandler(s *service.PrincipalService) *PrincipalHandler {
+	return &PrincipalHandler{PrincipalService: s}
+}
+
+func (h *PrincipalHandler) IssueChallenge(c *gin.Context) {
+	ch, err := h.PrincipalService.IssueChallenge(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"challenge":  ch.Challenge,
+		"expires_at": ch.ExpiresAt,
+	})
+}
+
+func (h *PrincipalHandler) VerifyPrincipal(c *gin.Context) {
+	var req struct {
+		Challenge       string              `json:"challenge" binding:"required"`
+		DelegationChain *ii.DelegationChain `json:"delegation_chain" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.PrincipalService.VerifyPrincipal(currentUserID(c), req.Challenge, *req.DelegationChain)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrChallengeInvalid), errors.Is(err, service.ErrDelegationInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPrincipalTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "principal verified",
+		"ii_principal": p,
+	})
+}
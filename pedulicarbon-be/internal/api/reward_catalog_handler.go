@@ -1,35 +1,47 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/service"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type RewardCatalogHandler struct {
 	CatalogService *service.RewardCatalogService
-	UserService    *service.UserService
-	RewardService  *service.RewardService
 }
 
-func NewRewardCatalogHandler(catalogService *service.RewardCatalogService, userService *service.UserService, rewardService *service.RewardService) *RewardCatalogHandler {
-	return &RewardCatalogHandler{
-		CatalogService: catalogService,
-		UserService:    userService,
-		RewardService:  rewardService,
-	}
+func NewRewardCatalogHandler(catalogService *service.RewardCatalogService) *RewardCatalogHandler {
+	return &RewardCatalogHandler{CatalogService: catalogService}
 }
 
+// ListCatalog mengembalikan item katalog yang sedang tersedia, terbaru dulu.
+// Query: category, in_stock=true, affordable=true (point cukup untuk saldo caller),
+// include_inactive=true (khusus pengelola katalog: ikut tampilkan item arsip/terjadwal),
+// limit (default 20, maks 100) dan offset.
 func (h *RewardCatalogHandler) ListCatalog(c *gin.Context) {
-	catalog, err := h.CatalogService.ListCatalog()
+	q := service.CatalogQuery{
+		Category:        c.Query("category"),
+		InStock:         c.Query("in_stock") == "true",
+		IncludeInactive: c.Query("include_inactive") == "true" && HasPermission(currentRole(c), PermCatalogManage),
+	}
+	if c.Query("affordable") == "true" {
+		q.AffordableForUserID = currentUserID(c)
+	}
+	q.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultCatalogListLimit)))
+	q.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	catalog, total, err := h.CatalogService.ListCatalog(q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, catalog)
+	c.JSON(http.StatusOK, gin.H{"total": total, "catalog": catalog})
 }
 
 func (h *RewardCatalogHandler) GetCatalog(c *gin.Context) {
@@ -47,6 +59,9 @@ func (h *RewardCatalogHandler) GetCatalog(c *gin.Context) {
 	c.JSON(http.StatusOK, item)
 }
 
+// RedeemCatalog menukar point user dengan item katalog. Header Idempotency-Key
+// opsional; retry dengan key yang sama mengembalikan reward yang sama. Body opsional
+// {"address_id"} memilih alamat kirim untuk reward produk (default: alamat default user).
 func (h *RewardCatalogHandler) RedeemCatalog(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
@@ -54,65 +69,221 @@ func (h *RewardCatalogHandler) RedeemCatalog(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
 		return
 	}
+	key := c.GetHeader("Idempotency-Key")
+	if len(key) > service.MaxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key terlalu panjang"})
+		return
+	}
 	var req struct {
-		UserID uint `json:"user_id" binding:"required"`
+		AddressID uint `json:"address_id"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	reward, replayed, err := h.CatalogService.Redeem(currentUserID(c), uint(id), req.AddressID, key)
+	switch {
+	case errors.Is(err, service.ErrCatalogNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "catalog not found"})
+		return
+	case errors.Is(err, service.ErrShippingAddressRequired), errors.Is(err, service.ErrAddressNotFound):
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
+	case errors.Is(err, service.ErrInsufficientPoints):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "not enough points"})
+		return
+	case errors.Is(err, service.ErrOutOfStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "out of stock"})
+		return
+	case errors.Is(err, service.ErrCatalogUnavailable), errors.Is(err, service.ErrRedemptionLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	user, err := h.UserService.GetProfile(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "redeemed", "reward": reward})
+}
+
+// catalogInput adalah body POST dan PUT /rewards/catalog. PUT mengganti semua field
+// yang bisa diubah; field opsional yang tidak dikirim menjadi kosong.
+type catalogInput struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Category       string     `json:"category"`
+	ImageURLs      []string   `json:"image_urls"`
+	PointsRequired int        `json:"points_required"`
+	Stock          int        `json:"stock"`
+	Type           string     `json:"type"`        // hanya saat create; type tidak bisa diubah
+	MerchantID     *uint      `json:"merchant_id"` // hanya saat create oleh admin; merchant selalu pemilik item yang dibuatnya
+	MaxPerUser     int        `json:"max_per_user"`
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+}
+
+func (h *RewardCatalogHandler) CreateCatalog(c *gin.Context) {
+	var req catalogInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	catalog, err := h.CatalogService.GetCatalog(uint(id))
+	catalog := &model.RewardCatalog{
+		Name:           req.Name,
+		Description:    req.Description,
+		Category:       req.Category,
+		ImageURLs:      req.ImageURLs,
+		PointsRequired: req.PointsRequired,
+		Stock:          req.Stock,
+		Type:           req.Type,
+		MaxPerUser:     req.MaxPerUser,
+		AvailableFrom:  req.AvailableFrom,
+		AvailableUntil: req.AvailableUntil,
+		MerchantID:     req.MerchantID,
+	}
+	if err := h.CatalogService.CreateCatalog(catalogActor(c), catalog); err != nil {
+		writeCatalogError(c, err)
+		return
+	}
+	c.JSON(201, catalog)
+}
+
+// ReplaceCatalog (PUT) mengganti semua field item katalog yang bisa diubah.
+func (h *RewardCatalogHandler) ReplaceCatalog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "catalog not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
 		return
 	}
-	if user.Points < catalog.PointsRequired {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "not enough points"})
+	var req catalogInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Kurangi point user
-	if err := h.UserService.AddPoints(user.ID, -catalog.PointsRequired); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if req.ImageURLs == nil {
+		req.ImageURLs = []string{}
+	}
+	catalog, err := h.CatalogService.UpdateCatalog(catalogActor(c), uint(id), service.CatalogPatch{
+		Name:           &req.Name,
+		Description:    &req.Description,
+		Category:       &req.Category,
+		ImageURLs:      &req.ImageURLs,
+		PointsRequired: &req.PointsRequired,
+		Stock:          &req.Stock,
+		MaxPerUser:     &req.MaxPerUser,
+		AvailableFrom:  &req.AvailableFrom,
+		AvailableUntil: &req.AvailableUntil,
+	})
+	if err != nil {
+		writeCatalogError(c, err)
 		return
 	}
-	// Kurangi stok reward
-	if err := h.CatalogService.RedeemCatalog(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, catalog)
+}
+
+// PatchCatalog (PATCH) hanya mengubah field yang dikirim. available_from dan
+// available_until bisa dikosongkan dengan null.
+func (h *RewardCatalogHandler) PatchCatalog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
 		return
 	}
-	// Catat reward ke tabel Reward
-	reward := &model.Reward{
-		UserID:      user.ID,
-		Points:      catalog.PointsRequired,
-		AssetType:   catalog.Type,
-		AssetAmount: 0, // bisa diisi sesuai kebutuhan
-		Status:      "redeemed",
+	var raw map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if err := h.RewardService.CreateReward(reward); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	patch, err := decodeCatalogPatch(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	catalog, err := h.CatalogService.UpdateCatalog(catalogActor(c), uint(id), patch)
+	if err != nil {
+		writeCatalogError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "redeemed"})
+	c.JSON(http.StatusOK, catalog)
 }
 
-func (h *RewardCatalogHandler) CreateCatalog(c *gin.Context) {
-	var req model.RewardCatalog
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+// ArchiveCatalog (DELETE) mengarsipkan item; riwayat redeem tetap utuh.
+func (h *RewardCatalogHandler) ArchiveCatalog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
 		return
 	}
-	if req.Name == "" || req.PointsRequired <= 0 || req.Stock < 0 || req.Type == "" {
-		c.JSON(400, gin.H{"error": "Field name, points_required (>0), stock (>=0), type wajib diisi"})
+	if err := h.CatalogService.ArchiveCatalog(catalogActor(c), uint(id)); err != nil {
+		writeCatalogError(c, err)
 		return
 	}
-	if err := h.CatalogService.CreateCatalog(&req); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+	c.JSON(http.StatusOK, gin.H{"status": "archived"})
+}
+
+func decodeCatalogPatch(raw map[string]json.RawMessage) (service.CatalogPatch, error) {
+	var p service.CatalogPatch
+	for key, value := range raw {
+		var target interface{}
+		switch key {
+		case "name":
+			p.Name = new(string)
+			target = p.Name
+		case "description":
+			p.Description = new(string)
+			target = p.Description
+		case "category":
+			p.Category = new(string)
+			target = p.Category
+		case "image_urls":
+			p.ImageURLs = &[]string{}
+			target = p.ImageURLs
+		case "points_required":
+			p.PointsRequired = new(int)
+			target = p.PointsRequired
+		case "stock":
+			p.Stock = new(int)
+			target = p.Stock
+		case "max_per_user":
+			p.MaxPerUser = new(int)
+			target = p.MaxPerUser
+		case "available_from":
+			p.AvailableFrom = new(*time.Time)
+			target = p.AvailableFrom
+		case "available_until":
+			p.AvailableUntil = new(*time.Time)
+			target = p.AvailableUntil
+		default:
+			return p, fmt.Errorf("field %q tidak bisa diubah", key)
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			return p, fmt.Errorf("field %q: %v", key, err)
+		}
+	}
+	return p, nil
+}
+
+// catalogActor: merchant hanya mengelola item miliknya, admin semua item.
+func catalogActor(c *gin.Context) service.CatalogActor {
+	return service.CatalogActor{UserID: currentUserID(c), AnyOwner: HasPermission(currentRole(c), PermCatalogManageAny)}
+}
+
+func writeCatalogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCatalogNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "catalog not found"})
+	case errors.Is(err, service.ErrNotCatalogOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCatalog):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
-	c.JSON(201, req)
 }
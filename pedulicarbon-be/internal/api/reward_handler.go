@@ -1,10 +1,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
 	"pedulicarbon/internal/service"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,13 +34,11 @@ func (h *RewardHandler) CreateReward(c *gin.Context) {
 }
 
 func (h *RewardHandler) GetUserRewards(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
 		return
 	}
-	rewards, err := h.RewardService.GetUserRewards(uint(userID))
+	rewards, err := h.RewardService.GetUserRewards(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,10 +46,47 @@ func (h *RewardHandler) GetUserRewards(c *gin.Context) {
 	c.JSON(http.StatusOK, rewards)
 }
 
+// ListRewards adalah antrian fulfilment untuk operator. Query: status, asset_type,
+// catalog_id, user_id, limit (default 50, maks 200) dan offset.
+func (h *RewardHandler) ListRewards(c *gin.Context) {
+	var f repository.RewardFilter
+	f.Status = c.Query("status")
+	f.AssetType = c.Query("asset_type")
+	if v := c.Query("catalog_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog_id"})
+			return
+		}
+		f.CatalogID = uint(id)
+	}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		f.UserID = uint(id)
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultRewardListLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	rewards, total, err := h.RewardService.ListRewards(f, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "rewards": rewards})
+}
+
+// UpdateRewardStatus memajukan status reward produk: packed, shipped (wajib
+// tracking_number), delivered, atau cancelled (wajib reason, point dikembalikan).
 func (h *RewardHandler) UpdateRewardStatus(c *gin.Context) {
 	rewardIDStr := c.Param("id")
 	var req struct {
-		Status string `json:"status" binding:"required"`
+		Status         string `json:"status" binding:"required"`
+		Courier        string `json:"courier"`
+		TrackingNumber string `json:"tracking_number"`
+		Reason         string `json:"reason"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -59,9 +97,25 @@ func (h *RewardHandler) UpdateRewardStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward id"})
 		return
 	}
-	if err := h.RewardService.UpdateRewardStatus(uint(rewardID), req.Status); err != nil {
+	reward, err := h.RewardService.UpdateRewardStatus(uint(rewardID), service.RewardStatusUpdate{
+		Status:         req.Status,
+		Courier:        strings.TrimSpace(req.Courier),
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+		Reason:         strings.TrimSpace(req.Reason),
+	})
+	switch {
+	case errors.Is(err, service.ErrRewardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrInvalidRewardTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrTrackingNumberRequired), errors.Is(err, service.ErrCancelReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+	c.JSON(http.StatusOK, reward)
 }
@@ -1,19 +1,25 @@
 package api
 
 import (
+	"context"
+	"log"
+	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/motoko"
+	"pedulicarbon/internal/payout"
 	"pedulicarbon/internal/repository"
 	"pedulicarbon/internal/service"
 
-	// "os"
-
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func InitRouter(db *gorm.DB) *gin.Engine {
+// InitRouter wires repositories, services and handlers. Job handlers are registered
+// on jobService; the caller is responsible for starting its workers.
+func InitRouter(db *gorm.DB, jobService *service.JobService) *gin.Engine {
 	// Repository
 	userRepo := repository.NewUserRepository(db)
 	missionRepo := repository.NewMissionRepository(db)
@@ -23,72 +29,328 @@ func InitRouter(db *gorm.DB) *gin.Engine {
 	rewardCatalogRepo := repository.NewRewardCatalogRepository(db)
 	withdrawRepo := repository.NewWithdrawRepository(db)
 	userNFTRepo := repository.NewUserNFTRepository(db)
+	principalChallengeRepo := repository.NewPrincipalChallengeRepository(db)
+	verificationSagaRepo := repository.NewVerificationSagaRepository(db)
+	nftReconciliationRepo := repository.NewNFTReconciliationRepository(db)
+	pointTransactionRepo := repository.NewPointTransactionRepository(db)
+	voucherCodeRepo := repository.NewVoucherCodeRepository(db)
+	shippingAddressRepo := repository.NewShippingAddressRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	walletTransactionRepo := repository.NewWalletTransactionRepository(db)
+	walletQuarantineRepo := repository.NewWalletQuarantineRepository(db)
+	payoutEventRepo := repository.NewPayoutEventRepository(db)
+	beneficiaryRepo := repository.NewBeneficiaryRepository(db)
+	withdrawDecisionRepo := repository.NewWithdrawDecisionRepository(db)
 
 	// Service
-	userService := service.NewUserService(userRepo)
+	authService := service.NewAuthService(os.Getenv("JWT_SECRET"), tokenTTL())
+	userService := service.NewUserService(userRepo, walletRepo)
+	pointService := service.NewPointService(userRepo, pointTransactionRepo)
+	notificationService := service.NewNotificationService(notificationRepo)
+	pointExpiryService := service.NewPointExpiryService(pointService, notificationService, pointExpiryPolicy())
+	if pointExpiryService.Policy.Enabled() {
+		go expirePointsLoop(pointExpiryService, time.Duration(max(EnvInt("POINTS_EXPIRY_INTERVAL_MINUTES", 60), 1))*time.Minute)
+	}
+	principalService, err := service.NewPrincipalService(principalChallengeRepo, userRepo, os.Getenv("II_CANISTER_ID"), os.Getenv("IC_ROOT_KEY"))
+	if err != nil {
+		log.Fatal("Failed to init principal service: ", err)
+	}
 	missionService := service.NewMissionService(missionRepo)
-	rewardService := service.NewRewardService(rewardRepo)
-	walletService := service.NewWalletService(walletRepo)
-	canisterHost := os.Getenv("ICP_CANISTER_HOST")
-	canisterID := os.Getenv("ICP_CANISTER_ID")
-	motokoClient := motoko.NewMotokoClient(canisterHost, canisterID)
-	missionTakenService := service.NewMissionTakenService(missionTakenRepo, userRepo, missionRepo, motokoClient, userNFTRepo)
-	rewardCatalogService := service.NewRewardCatalogService(rewardCatalogRepo)
-	withdrawService := service.NewWithdrawService(withdrawRepo)
+	rewardService := service.NewRewardService(rewardRepo, rewardCatalogRepo, pointService)
+	addressService := service.NewAddressService(shippingAddressRepo)
+	walletService := service.NewWalletService(walletRepo, walletTransactionRepo, userRepo, userNFTRepo, walletQuarantineRepo)
+	canister := NewCanisterClient()
+	missionTakenService := service.NewMissionTakenService(missionTakenRepo, userRepo, missionRepo, canister, userNFTRepo, verificationSagaRepo, jobService, pointService)
+	missionTakenService.MaxResubmissions = EnvInt("MISSION_MAX_RESUBMISSIONS", service.DefaultMaxResubmissions)
+	go resumeVerificationsLoop(missionTakenService)
+	nftService := service.NewNFTService(userNFTRepo, userRepo, verificationSagaRepo, nftReconciliationRepo, canister)
+	if interval := EnvInt("NFT_RECONCILE_INTERVAL_MINUTES", 0); interval > 0 {
+		go reconcileNFTsLoop(nftService, time.Duration(interval)*time.Minute, os.Getenv("NFT_RECONCILE_REPAIR") == "true")
+	}
+	rewardCatalogService := service.NewRewardCatalogService(rewardCatalogRepo, rewardRepo, voucherCodeRepo, shippingAddressRepo, pointService)
+	voucherService := service.NewVoucherService(voucherCodeRepo, rewardCatalogRepo, rewardRepo)
+	beneficiaryService := service.NewBeneficiaryService(beneficiaryRepo, notificationService, time.Duration(EnvInt("WITHDRAW_BENEFICIARY_COOLDOWN_HOURS", 24))*time.Hour)
+	withdrawService := service.NewWithdrawService(withdrawRepo, payoutEventRepo, withdrawDecisionRepo, walletService, beneficiaryService, payoutProvider(), jobService, withdrawPolicy())
+	if withdrawService.Provider != nil {
+		go pollPayoutsLoop(withdrawService, time.Duration(max(EnvInt("PAYOUT_POLL_INTERVAL_MINUTES", 10), 1))*time.Minute)
+	}
 
 	// Handler
-	userHandler := NewUserHandler(userService)
+	userHandler := NewUserHandler(userService, authService, pointExpiryService)
+	notificationHandler := NewNotificationHandler(notificationService)
+	pointHandler := NewPointHandler(pointService, userService)
+	addressHandler := NewAddressHandler(addressService)
+	principalHandler := NewPrincipalHandler(principalService)
+	operationHandler := NewOperationHandler(jobService)
 	missionHandler := NewMissionHandler(missionService)
 	rewardHandler := NewRewardHandler(rewardService)
 	walletHandler := NewWalletHandler(walletService)
 	missionTakenHandler := NewMissionTakenHandler(missionTakenService)
-	rewardCatalogHandler := NewRewardCatalogHandler(rewardCatalogService, userService, rewardService)
+	nftHandler := NewNFTHandler(nftService)
+	rewardCatalogHandler := NewRewardCatalogHandler(rewardCatalogService)
+	voucherHandler := NewVoucherHandler(voucherService)
 	withdrawHandler := NewWithdrawHandler(withdrawService)
+	beneficiaryHandler := NewBeneficiaryHandler(beneficiaryService)
 
 	r := gin.Default()
 
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		c.JSON(200, gin.H{"status": "ok", "canister_mode": canisterMode(canister)})
 	})
 
 	// User
 	r.POST("/auth/register", userHandler.Register)
 	r.POST("/auth/login", userHandler.Login)
-	r.GET("/users/profile/:id", userHandler.GetProfile)
+
+	// Webhook payout provider; diautentikasi lewat signature callback, bukan token
+	r.POST("/payouts/callback/:provider", withdrawHandler.PayoutCallback)
+
+	// Route di bawah ini wajib membawa access token
+	auth := r.Group("/", AuthMiddleware(authService, userService))
+	auth.GET("/users/profile/:id", userHandler.GetProfile)
+	auth.POST("/users/principal/challenge", principalHandler.IssueChallenge)
+	auth.POST("/users/principal/verify", principalHandler.VerifyPrincipal)
+	auth.PUT("/users/:user_id/role", RequirePermission(PermUserManage), userHandler.SetRole)
+	auth.GET("/users/:user_id/points/history", pointHandler.GetHistory)
+	auth.POST("/users/:user_id/points/adjustments", RequirePermission(PermUserManage), pointHandler.AdjustPoints)
+	auth.GET("/users/:user_id/notifications", notificationHandler.ListNotifications)
+	auth.POST("/users/:user_id/notifications/:notification_id/read", notificationHandler.MarkRead)
+	auth.GET("/users/:user_id/addresses", addressHandler.ListAddresses)
+	auth.POST("/users/:user_id/addresses", addressHandler.CreateAddress)
+	auth.PUT("/users/:user_id/addresses/:address_id", addressHandler.UpdateAddress)
+	auth.DELETE("/users/:user_id/addresses/:address_id", addressHandler.DeleteAddress)
+	auth.GET("/users/:user_id/beneficiaries", beneficiaryHandler.ListBeneficiaries)
+	auth.POST("/users/:user_id/beneficiaries", beneficiaryHandler.CreateBeneficiary)
+	auth.DELETE("/users/:user_id/beneficiaries/:beneficiary_id", beneficiaryHandler.DeleteBeneficiary)
+	auth.POST("/beneficiaries/:id/verify", RequirePermission(PermPayoutManage), beneficiaryHandler.VerifyBeneficiary)
 
 	// Mission
-	r.GET("/missions", missionHandler.ListMissions)
-	r.GET("/missions/:id", missionHandler.GetMission)
-	r.POST("/missions", missionHandler.CreateMission)
+	auth.GET("/missions", missionHandler.ListMissions)
+	auth.GET("/missions/:id", missionHandler.GetMission)
+	auth.POST("/missions", RequirePermission(PermMissionManage), missionHandler.CreateMission)
 
 	// Mission Taken
-	r.POST("/missions/:id/take", missionTakenHandler.TakeMission)
-	r.GET("/users/:user_id/missions", missionTakenHandler.GetUserMissions)
-	r.POST("/missions/:id/submit-proof", missionTakenHandler.SubmitProof)
-	r.POST("/missions/:id/verify", missionTakenHandler.VerifyMission)
-	r.GET("/users/:user_id/nfts", missionTakenHandler.GetUserNFTs)
-	r.POST("/nfts/:id/claim", missionTakenHandler.ClaimNFT)
+	auth.POST("/missions/:id/take", missionTakenHandler.TakeMission)
+	auth.GET("/users/:user_id/missions", missionTakenHandler.GetUserMissions)
+	auth.POST("/missions/:id/submit-proof", missionTakenHandler.SubmitProof)
+	auth.POST("/missions/:id/verify", RequirePermission(PermMissionVerify), missionTakenHandler.VerifyMission)
+	auth.POST("/missions/:id/reject", RequirePermission(PermMissionVerify), missionTakenHandler.RejectMission)
+	auth.GET("/users/:user_id/nfts", missionTakenHandler.GetUserNFTs)
+	auth.GET("/nfts/:id", nftHandler.GetNFT)
+	auth.POST("/nfts/:id/claim", RequirePermission(PermNFTClaim), missionTakenHandler.ClaimNFT)
+
+	// Operation (job canister async)
+	auth.GET("/operations/:id", operationHandler.GetOperation)
 
 	// Reward
-	r.POST("/rewards", rewardHandler.CreateReward)
-	r.GET("/rewards/user/:user_id", rewardHandler.GetUserRewards)
-	r.PUT("/rewards/:id/status", rewardHandler.UpdateRewardStatus)
+	auth.GET("/rewards", RequirePermission(PermRewardManage), rewardHandler.ListRewards)
+	auth.POST("/rewards", RequirePermission(PermRewardManage), rewardHandler.CreateReward)
+	auth.GET("/rewards/user/:user_id", rewardHandler.GetUserRewards)
+	auth.PUT("/rewards/:id/status", RequirePermission(PermRewardManage), rewardHandler.UpdateRewardStatus)
 
 	// Reward Catalog
-	r.GET("/rewards/catalog", rewardCatalogHandler.ListCatalog)
-	r.GET("/rewards/catalog/:id", rewardCatalogHandler.GetCatalog)
-	r.POST("/rewards/catalog/:id/redeem", rewardCatalogHandler.RedeemCatalog)
-	r.POST("/rewards/catalog", rewardCatalogHandler.CreateCatalog)
+	auth.GET("/rewards/catalog", rewardCatalogHandler.ListCatalog)
+	auth.GET("/rewards/catalog/:id", rewardCatalogHandler.GetCatalog)
+	auth.POST("/rewards/catalog/:id/redeem", rewardCatalogHandler.RedeemCatalog)
+	auth.POST("/rewards/catalog", RequirePermission(PermCatalogManage), rewardCatalogHandler.CreateCatalog)
+	auth.PUT("/rewards/catalog/:id", RequirePermission(PermCatalogManage), rewardCatalogHandler.ReplaceCatalog)
+	auth.PATCH("/rewards/catalog/:id", RequirePermission(PermCatalogManage), rewardCatalogHandler.PatchCatalog)
+	auth.DELETE("/rewards/catalog/:id", RequirePermission(PermCatalogManage), rewardCatalogHandler.ArchiveCatalog)
+	auth.POST("/rewards/catalog/:id/vouchers", RequirePermission(PermCatalogManage), voucherHandler.UploadCodes)
+	auth.POST("/rewards/catalog/:id/vouchers/use", RequirePermission(PermCatalogManage), voucherHandler.MarkUsed)
 
 	// Wallet
-	r.GET("/wallets/user/:user_id", walletHandler.GetWallet)
-	r.POST("/wallets", walletHandler.CreateWallet)
-	r.PUT("/wallets", walletHandler.UpdateWallet)
+	auth.GET("/wallets/user/:user_id", walletHandler.GetWallet)
+	auth.POST("/wallets", walletHandler.CreateWallet)
+	auth.GET("/wallets/user/:user_id/transactions", walletHandler.GetTransactions)
+	auth.POST("/wallets/user/:user_id/adjustments", RequirePermission(PermWalletManage), walletHandler.AdjustRupiah)
+	auth.GET("/wallets/quarantine", RequirePermission(PermWalletManage), walletHandler.ListQuarantine)
+	auth.POST("/wallets/quarantine/:id/resolve", RequirePermission(PermWalletManage), walletHandler.ResolveQuarantine)
 
 	// Withdraw
-	r.POST("/wallets/withdraw", withdrawHandler.CreateWithdraw)
-	r.GET("/wallets/withdraw/user/:user_id", withdrawHandler.GetUserWithdraws)
-	r.PUT("/wallets/withdraw/:id/status", withdrawHandler.UpdateWithdrawStatus)
+	auth.POST("/wallets/withdraw", withdrawHandler.CreateWithdraw)
+	auth.GET("/wallets/withdraw/user/:user_id", withdrawHandler.GetUserWithdraws)
+	auth.GET("/wallets/withdraw", RequirePermission(PermPayoutManage), withdrawHandler.ListWithdraws)
+	auth.GET("/wallets/withdraw/:id", RequirePermission(PermPayoutManage), withdrawHandler.GetWithdraw)
+	auth.PUT("/wallets/withdraw/:id/status", RequirePermission(PermPayoutManage), withdrawHandler.UpdateWithdrawStatus)
+	auth.POST("/wallets/withdraw/:id/approve", RequirePermission(PermPayoutManage), withdrawHandler.ApproveWithdraw)
+	auth.POST("/wallets/withdraw/:id/reject", RequirePermission(PermPayoutManage), withdrawHandler.RejectWithdraw)
+	auth.GET("/wallets/withdraw/:id/payout-events", RequirePermission(PermPayoutManage), withdrawHandler.ListPayoutEvents)
 
 	return r
 }
+
+// tokenTTL membaca masa berlaku access token dari JWT_TTL_HOURS (default 24 jam).
+func tokenTTL() time.Duration {
+	return time.Duration(max(EnvInt("JWT_TTL_HOURS", 24), 1)) * time.Hour
+}
+
+// EnvInt membaca bilangan bulat non-negatif dari env. Kosong, bukan angka atau
+// negatif memakai def; 0 dipakai apa adanya karena banyak setting memakai 0 untuk
+// nonaktif atau tanpa batas (WITHDRAW_DAILY_CAP, NFT_RECONCILE_INTERVAL_MINUTES,
+// batas review penarikan). Setting yang harus positif dijaga pemanggil dengan
+// max(EnvInt(...), 1).
+func EnvInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+// pointExpiryPolicy membaca POINTS_EXPIRY_MONTHS (0/kosong = point tidak kedaluwarsa)
+// dan POINTS_EXPIRY_NOTICE_DAYS (default 30).
+func pointExpiryPolicy() service.PointExpiryPolicy {
+	return service.PointExpiryPolicy{
+		Months:     EnvInt("POINTS_EXPIRY_MONTHS", 0),
+		NoticeDays: EnvInt("POINTS_EXPIRY_NOTICE_DAYS", 30),
+	}
+}
+
+// withdrawPolicy membaca WITHDRAW_MIN_AMOUNT (default Rp10.000), WITHDRAW_DAILY_CAP
+// (default Rp5.000.000, 0 = tanpa batas) dan biaya per channel: WITHDRAW_FEE_BANK /
+// WITHDRAW_FEE_EWALLET dalam rupiah plus WITHDRAW_FEE_BANK_BPS / WITHDRAW_FEE_EWALLET_BPS.
+func withdrawPolicy() service.WithdrawPolicy {
+	return service.WithdrawPolicy{
+		MinAmount:                  int64(EnvInt("WITHDRAW_MIN_AMOUNT", 10000)),
+		DailyCap:                   int64(EnvInt("WITHDRAW_DAILY_CAP", 5000000)),
+		RequireVerifiedBeneficiary: os.Getenv("WITHDRAW_REQUIRE_VERIFIED_BENEFICIARY") == "true",
+		ApprovalThreshold:          int64(EnvInt("WITHDRAW_APPROVAL_THRESHOLD", 2000000)),
+		VelocityMaxCount:           EnvInt("WITHDRAW_VELOCITY_MAX_COUNT", 5),
+		VelocityWindow:             time.Duration(EnvInt("WITHDRAW_VELOCITY_WINDOW_HOURS", 24)) * time.Hour,
+		CumulativeLimit:            int64(EnvInt("WITHDRAW_CUMULATIVE_LIMIT", 20000000)),
+		CumulativeWindow:           time.Duration(EnvInt("WITHDRAW_CUMULATIVE_WINDOW_DAYS", 30)) * 24 * time.Hour,
+		Fees: map[string]service.WithdrawFee{
+			model.WithdrawChannelBank: {
+				Flat:        int64(EnvInt("WITHDRAW_FEE_BANK", 6500)),
+				BasisPoints: int64(EnvInt("WITHDRAW_FEE_BANK_BPS", 0)),
+			},
+			model.WithdrawChannelEWallet: {
+				Flat:        int64(EnvInt("WITHDRAW_FEE_EWALLET", 2500)),
+				BasisPoints: int64(EnvInt("WITHDRAW_FEE_EWALLET_BPS", 0)),
+			},
+		},
+	}
+}
+
+// payoutProvider memilih gateway pencairan dari PAYOUT_PROVIDER: "xendit" (butuh
+// XENDIT_SECRET_KEY dan XENDIT_CALLBACK_TOKEN), "fake" untuk development (callback
+// ditandatangani PAYOUT_WEBHOOK_SECRET), atau kosong untuk pencairan manual oleh admin.
+func payoutProvider() payout.Provider {
+	switch os.Getenv("PAYOUT_PROVIDER") {
+	case "":
+		return nil
+	case "xendit":
+		if os.Getenv("XENDIT_SECRET_KEY") == "" || os.Getenv("XENDIT_CALLBACK_TOKEN") == "" {
+			log.Fatal("PAYOUT_PROVIDER=xendit requires XENDIT_SECRET_KEY and XENDIT_CALLBACK_TOKEN")
+		}
+		return payout.NewXenditProvider(os.Getenv("XENDIT_BASE_URL"), os.Getenv("XENDIT_SECRET_KEY"), os.Getenv("XENDIT_CALLBACK_TOKEN"))
+	case "fake":
+		log.Println("[WARNING] PAYOUT_PROVIDER=fake: penarikan tidak benar-benar dicairkan")
+		fake := payout.NewFakeProvider(os.Getenv("PAYOUT_WEBHOOK_SECRET"))
+		fake.AutoComplete = os.Getenv("PAYOUT_FAKE_AUTO_COMPLETE") == "true"
+		return fake
+	default:
+		log.Fatalf("Unknown PAYOUT_PROVIDER %q", os.Getenv("PAYOUT_PROVIDER"))
+		return nil
+	}
+}
+
+// pollPayoutsLoop menanyakan status disbursement yang belum final ke provider,
+// sebagai cadangan bila callback tidak sampai.
+func pollPayoutsLoop(s *service.WithdrawService, interval time.Duration) {
+	for range time.Tick(interval) {
+		report, err := s.PollPayouts(context.Background())
+		if err != nil {
+			log.Printf("[ERROR] Payout poll failed: %v", err)
+			continue
+		}
+		log.Printf("[INFO] Payout poll: %d checked, %d completed, %d failed, %d requeued, %d errors",
+			report.Checked, report.Completed, report.Failed, report.Requeued, len(report.Errors))
+	}
+}
+
+// expirePointsLoop menghanguskan point yang lewat masa berlaku dan mengirim
+// pemberitahuan kedaluwarsa, saat startup lalu setiap interval.
+func expirePointsLoop(s *service.PointExpiryService, interval time.Duration) {
+	run := func() {
+		report, err := s.ExpirePoints(time.Now())
+		if err != nil {
+			log.Printf("[ERROR] Points expiry failed: %v", err)
+			return
+		}
+		log.Printf("[INFO] Points expiry: %d users checked, %d points expired from %d users, %d notices, %d errors",
+			report.UsersChecked, report.PointsExpired, report.UsersExpired, report.NoticesSent, len(report.Errors))
+	}
+	run()
+	for range time.Tick(interval) {
+		run()
+	}
+}
+
+// resumeVerificationsLoop menyelesaikan saga VerifyMission yang terhenti saat startup
+// dan secara berkala sesudahnya (lease saga yang mati akan kadaluarsa).
+func resumeVerificationsLoop(s *service.MissionTakenService) {
+	s.ResumeVerifications()
+	for range time.Tick(5 * time.Minute) {
+		s.ResumeVerifications()
+	}
+}
+
+// reconcileNFTsLoop menjalankan rekonsiliasi UserNFT vs canister secara berkala.
+// Hasilnya hanya di-log; laporan lengkap tersedia lewat `pedulicarbon reconcile-nfts`.
+func reconcileNFTsLoop(s *service.NFTService, interval time.Duration, repair bool) {
+	for range time.Tick(interval) {
+		report, err := s.Reconcile(context.Background(), repair)
+		if err != nil {
+			log.Printf("[ERROR] NFT reconciliation failed: %v", err)
+			continue
+		}
+		repaired := 0
+		for _, issue := range report.Issues {
+			if issue.Repaired {
+				repaired++
+			}
+		}
+		log.Printf("[INFO] NFT reconciliation %s: %d users, %d issues, %d repaired, %d errors",
+			report.RunID, report.UsersChecked, len(report.Issues), repaired, len(report.Errors))
+	}
+}
+
+// NewCanisterClient memilih implementasi canister. ICP_SIMULATION_MODE=true memakai
+// canister simulasi in-memory untuk development lokal tanpa replica dfx; NFT yang
+// dicetak ditandai simulated dan tidak pernah ada on-chain.
+func NewCanisterClient() motoko.CanisterClient {
+	if os.Getenv("ICP_SIMULATION_MODE") == "true" {
+		log.Println("[WARNING] ================================================================")
+		log.Println("[WARNING] ICP SIMULATION MODE AKTIF (ICP_SIMULATION_MODE=true)")
+		log.Println("[WARNING] Canister tidak dipanggil: verifikasi selalu lolos, NFT hanya ada di memori")
+		log.Println("[WARNING] dan setiap UserNFT dicatat dengan simulated=true. Jangan pakai di production.")
+		log.Println("[WARNING] ================================================================")
+		return motoko.NewSimulationCanister()
+	}
+	passphrase := os.Getenv("IDENTITY_PASSPHRASE")
+	if passphrase == "" {
+		passphrase = "pedulicarbon" // default passphrase
+	}
+	client, err := motoko.NewMotokoClient(os.Getenv("ICP_CANISTER_HOST"), os.Getenv("ICP_CANISTER_ID"), os.Getenv("IDENTITY_PATH"), passphrase)
+	if err != nil {
+		log.Fatal("Failed to init canister client: ", err)
+	}
+	log.Printf("[INFO] Canister client ready: canister %s, identity %s", client.CanisterID, client.Principal())
+	if expected := os.Getenv("ICP_PRINCIPAL_ID"); expected != "" {
+		if err := client.ExpectPrincipal(expected); err != nil {
+			log.Fatal("ICP identity self-check failed: ", err)
+		}
+	} else {
+		log.Println("[WARNING] ICP_PRINCIPAL_ID not set, skipping identity self-check")
+	}
+	return client
+}
+
+func canisterMode(canister motoko.CanisterClient) string {
+	if canister.Simulated() {
+		return "simulation"
+	}
+	return "ic"
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"pedulicarbon/internal/repository"
+	"pedulicarbon/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Gin panic saat route bertabrakan (mis. wildcard :id dan :user_id di posisi yang
+// sama), jadi router harus bisa dibangun utuh.
+func TestInitRouterRegistersRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("ICP_SIMULATION_MODE", "true")
+	t.Setenv("PAYOUT_PROVIDER", "")
+	t.Setenv("POINTS_EXPIRY_MONTHS", "0")
+	t.Setenv("NFT_RECONCILE_INTERVAL_MINUTES", "0")
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := InitRouter(db, service.NewJobService(repository.NewJobRepository(db)))
+	if len(r.Routes()) == 0 {
+		t.Fatal("no routes registered")
+	}
+}
+
+func TestEnvInt(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int
+	}{
+		{"", 7},
+		{"abc", 7},
+		{"-1", 7},
+		{"0", 0},
+		{"12", 12},
+	}
+	for _, c := range cases {
+		t.Setenv("PEDULICARBON_TEST_INT", c.value)
+		if got := EnvInt("PEDULICARBON_TEST_INT", 7); got != c.want {
+			t.Errorf("EnvInt(%q) = %d, want %d", c.value, got, c.want)
+		}
+	}
+}
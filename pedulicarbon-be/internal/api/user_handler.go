@@ -1,21 +1,25 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	UserService *service.UserService
+	UserService   *service.UserService
+	AuthService   *service.AuthService
+	ExpiryService *service.PointExpiryService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{UserService: userService}
+func NewUserHandler(userService *service.UserService, authService *service.AuthService, expiryService *service.PointExpiryService) *UserHandler {
+	return &UserHandler{UserService: userService, AuthService: authService, ExpiryService: expiryService}
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -30,23 +34,20 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Buat user model
+	// Buat user model; principal ICP dihubungkan belakangan lewat /users/principal/verify
 	user := model.User{
-		Name:        req.Name,
-		Email:       req.Email,
-		IIPrincipal: os.Getenv("ICP_PRINCIPAL_ID"),
-		Points:      0,
+		Name:   req.Name,
+		Email:  req.Email,
+		Points: 0,
 	}
 
-	fmt.Printf("[DEBUG] Register user: name=%s, email=%s, principal=%s\n", user.Name, user.Email, user.IIPrincipal)
+	fmt.Printf("[DEBUG] Register user: name=%s, email=%s\n", user.Name, user.Email)
 
-	if user.IIPrincipal == "" {
-		fmt.Println("[ERROR] ICP_PRINCIPAL_ID di env kosong!")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ICP_PRINCIPAL_ID environment variable is required"})
-		return
-	}
-
-	if err := h.UserService.RegisterUser(&user); err != nil {
+	if err := h.UserService.RegisterUser(&user, req.Password); err != nil {
+		if errors.Is(err, service.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		fmt.Printf("[ERROR] Register user gagal: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -61,30 +62,34 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 func (h *UserHandler) Login(c *gin.Context) {
 	var req struct {
-		Email string `json:"email" binding:"required"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	user, err := h.UserService.LoginUser(req.Email)
+	user, err := h.UserService.LoginUser(req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	token, expiresAt, err := h.AuthService.IssueToken(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"user":    user,
+		"message":    "Login successful",
+		"token":      token,
+		"expires_at": expiresAt,
+		"user":       user,
 	})
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	userID := c.Param("id")
-	// Konversi userID ke uint
-	var id uint
-	_, err := fmt.Sscanf(userID, "%d", &id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	id, ok := requireSelf(c, "id")
+	if !ok {
 		return
 	}
 	user, err := h.UserService.GetProfile(id)
@@ -92,7 +97,37 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
 	}
+	expirations, err := h.ExpiryService.Upcoming(id, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"user": user,
+		"user":                 user,
+		"upcoming_expirations": expirations,
 	})
 }
+
+func (h *UserHandler) SetRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.UserService.SetRole(uint(id), req.Role); err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
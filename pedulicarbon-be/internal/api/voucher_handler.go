@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"pedulicarbon/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxVoucherUploadBytes membatasi ukuran file CSV kode voucher.
+const maxVoucherUploadBytes = 2 << 20
+
+type VoucherHandler struct {
+	VoucherService *service.VoucherService
+}
+
+func NewVoucherHandler(voucherService *service.VoucherService) *VoucherHandler {
+	return &VoucherHandler{VoucherService: voucherService}
+}
+
+// UploadCodes menerima batch kode voucher dalam CSV (kolom pertama = kode), baik
+// sebagai multipart field "file" maupun body text/csv.
+func (h *VoucherHandler) UploadCodes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVoucherUploadBytes)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "field file wajib diisi"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	codes, err := service.ParseVoucherCSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.VoucherService.UploadCodes(catalogActor(c), uint(id), codes)
+	switch {
+	case errors.Is(err, service.ErrCatalogNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "catalog not found"})
+		return
+	case errors.Is(err, service.ErrNotCatalogOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrNotVoucherCatalog):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// MarkUsed menandai kode voucher yang ditunjukkan user di merchant sebagai terpakai.
+func (h *VoucherHandler) MarkUsed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog id"})
+		return
+	}
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	voucher, err := h.VoucherService.MarkUsed(catalogActor(c), uint(id), strings.TrimSpace(req.Code))
+	switch {
+	case errors.Is(err, service.ErrVoucherNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrNotCatalogOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrVoucherNotAssigned), errors.Is(err, service.ErrVoucherUsed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, voucher)
+}
@@ -1,12 +1,14 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type WalletHandler struct {
@@ -17,43 +19,146 @@ func NewWalletHandler(walletService *service.WalletService) *WalletHandler {
 	return &WalletHandler{WalletService: walletService}
 }
 
+// GetWallet mengembalikan saldo wallet yang dihitung dari ledger: rupiah dari
+// ledger rupiah, points dari ledger point dan carbon_nft dari NFT yang masih dimiliki.
 func (h *WalletHandler) GetWallet(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
 		return
 	}
-	wallet, err := h.WalletService.GetWallet(uint(userID))
-	if err != nil {
+	wallet, err := h.WalletService.GetWallet(userID)
+	if errors.Is(err, service.ErrWalletNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, wallet)
 }
 
+// CreateWallet menyiapkan wallet milik caller bila belum ada. Saldo tidak bisa
+// diisi dari request; wallet baru selalu kosong.
 func (h *WalletHandler) CreateWallet(c *gin.Context) {
-	var req model.Wallet
+	wallet, created, err := h.WalletService.EnsureWallet(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if created {
+		c.JSON(http.StatusCreated, wallet)
+		return
+	}
+	c.JSON(http.StatusOK, wallet)
+}
+
+// GetTransactions mengembalikan saldo dan baris ledger rupiah user, terbaru dulu.
+// Query: limit (default 50, maks 200) dan offset.
+func (h *WalletHandler) GetTransactions(c *gin.Context) {
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
+		return
+	}
+	wallet, err := h.WalletService.GetWallet(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultWalletHistoryLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	entries, total, err := h.WalletService.History(userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"balance":      wallet.Rupiah,
+		"total":        total,
+		"transactions": entries,
+	})
+}
+
+// AdjustRupiah memposting koreksi manual (positif atau negatif) ke saldo rupiah user.
+func (h *WalletHandler) AdjustRupiah(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req struct {
+		Amount int64  `json:"amount" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.WalletService.CreateWallet(&req); err != nil {
+	entry, err := h.WalletService.Post(service.RupiahPosting{
+		UserID:      uint(id),
+		Amount:      req.Amount,
+		EntryType:   model.RupiahEntryAdjustment,
+		RefType:     "admin",
+		Description: req.Reason + " (oleh user " + strconv.FormatUint(uint64(currentUserID(c)), 10) + ")",
+	})
+	switch {
+	case errors.Is(err, service.ErrInsufficientRupiah), errors.Is(err, service.ErrInvalidRupiahAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// ListQuarantine menampilkan saldo rupiah di luar ledger yang dikarantina saat
+// migrasi. ?all=true ikut menampilkan yang sudah diselesaikan.
+func (h *WalletHandler) ListQuarantine(c *gin.Context) {
+	list, err := h.WalletService.ListQuarantine(c.Query("all") == "true")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, req)
+	c.JSON(http.StatusOK, list)
 }
 
-func (h *WalletHandler) UpdateWallet(c *gin.Context) {
-	var req model.Wallet
+// ResolveQuarantine mengembalikan dana karantina yang terbukti sah (amount > 0)
+// atau menyatakannya tidak sah (amount 0). Alasan wajib diisi.
+func (h *WalletHandler) ResolveQuarantine(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quarantine id"})
+		return
+	}
+	var req struct {
+		Amount int64  `json:"amount"`
+		Reason string `json:"reason" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.WalletService.UpdateWallet(&req); err != nil {
+	q, err := h.WalletService.ResolveQuarantine(currentUserID(c), uint(id), req.Amount, req.Reason)
+	switch {
+	case errors.Is(err, service.ErrQuarantineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrQuarantineResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrResolutionReason), errors.Is(err, service.ErrInvalidRupiahAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, req)
+	c.JSON(http.StatusOK, q)
 }
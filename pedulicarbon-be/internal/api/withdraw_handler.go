@@ -1,14 +1,20 @@
 package api
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/payout"
 	"pedulicarbon/internal/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxPayoutCallbackBytes membatasi ukuran body webhook payout provider.
+const maxPayoutCallbackBytes = 1 << 20
+
 type WithdrawHandler struct {
 	WithdrawService *service.WithdrawService
 }
@@ -17,27 +23,46 @@ func NewWithdrawHandler(s *service.WithdrawService) *WithdrawHandler {
 	return &WithdrawHandler{WithdrawService: s}
 }
 
+// CreateWithdraw meminta penarikan rupiah milik caller ke salah satu rekening
+// tujuannya. Amount + biaya langsung ditahan dari saldo wallet sampai penarikan
+// sukses atau gagal.
 func (h *WithdrawHandler) CreateWithdraw(c *gin.Context) {
-	var req model.Withdraw
+	var req struct {
+		Amount        int64 `json:"amount" binding:"required"`
+		BeneficiaryID uint  `json:"beneficiary_id" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.WithdrawService.CreateWithdraw(&req); err != nil {
+	wd, err := h.WithdrawService.CreateWithdraw(currentUserID(c), req.Amount, req.BeneficiaryID)
+	switch {
+	case errors.Is(err, service.ErrInvalidWithdraw), errors.Is(err, service.ErrInsufficientRupiah):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrWithdrawDailyCap), errors.Is(err, service.ErrBeneficiaryCoolingDown),
+		errors.Is(err, service.ErrBeneficiaryNotVerified):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrBeneficiaryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, req)
+	c.JSON(http.StatusCreated, wd)
 }
 
 func (h *WithdrawHandler) GetUserWithdraws(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	userID, ok := requireSelf(c, "user_id")
+	if !ok {
 		return
 	}
-	withdraws, err := h.WithdrawService.GetUserWithdraws(uint(userID))
+	withdraws, err := h.WithdrawService.GetUserWithdraws(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,23 +70,163 @@ func (h *WithdrawHandler) GetUserWithdraws(c *gin.Context) {
 	c.JSON(http.StatusOK, withdraws)
 }
 
+// ListWithdraws menampilkan antrian penarikan untuk finance admin.
+// Query: status (default review), limit (default 50, maks 200) dan offset.
+func (h *WithdrawHandler) ListWithdraws(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultWithdrawQueueLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	withdraws, total, err := h.WithdrawService.ListWithdraws(c.DefaultQuery("status", model.WithdrawStatusReview), limit, offset)
+	switch {
+	case errors.Is(err, service.ErrInvalidWithdraw):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "withdraws": withdraws})
+}
+
+// GetWithdraw mengembalikan penarikan beserta audit trail keputusannya (admin).
+func (h *WithdrawHandler) GetWithdraw(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid withdraw id"})
+		return
+	}
+	wd, err := h.WithdrawService.GetWithdraw(uint(id))
+	if err != nil {
+		writeWithdrawDecisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, wd)
+}
+
+// UpdateWithdrawStatus memindahkan penarikan pending -> processing -> success/failed.
 func (h *WithdrawHandler) UpdateWithdrawStatus(c *gin.Context) {
-	idStr := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid withdraw id"})
+		return
+	}
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wd, err := h.WithdrawService.UpdateWithdrawStatus(uint(id), service.WithdrawStatusUpdate{Status: req.Status, Reason: req.Reason, ActorID: currentUserID(c)})
+	if err != nil {
+		writeWithdrawDecisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, wd)
+}
+
+// ApproveWithdraw mencatat persetujuan caller. Penarikan processing setelah
+// jumlah persetujuan dari finance admin berbeda terpenuhi.
+func (h *WithdrawHandler) ApproveWithdraw(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid withdraw id"})
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wd, err := h.WithdrawService.Approve(currentUserID(c), uint(id), req.Note)
+	if err != nil {
+		writeWithdrawDecisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, wd)
+}
+
+// RejectWithdraw menggagalkan penarikan pending/review dan mengembalikan saldonya.
+func (h *WithdrawHandler) RejectWithdraw(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid withdraw id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	wd, err := h.WithdrawService.Reject(currentUserID(c), uint(id), req.Reason)
+	if err != nil {
+		writeWithdrawDecisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, wd)
+}
+
+func writeWithdrawDecisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWithdrawNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidWithdrawTransition), errors.Is(err, service.ErrPayoutProviderManaged),
+		errors.Is(err, service.ErrWithdrawApprovalRequired), errors.Is(err, service.ErrDuplicateApprover):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFailureReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// PayoutCallback menerima webhook status disbursement dari payout provider.
+// Callback tanpa signature yang valid ditolak 401; callback berulang tetap 200.
+func (h *WithdrawHandler) PayoutCallback(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPayoutCallbackBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wd, err := h.WithdrawService.HandleCallback(c.Param("provider"), c.Request.Header, body)
+	switch {
+	case errors.Is(err, service.ErrUnknownPayoutProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, payout.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrWithdrawNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrPayoutStatusConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdraw_id": wd.ID, "status": wd.Status})
+}
+
+// ListPayoutEvents mengembalikan respons dan callback provider mentah untuk satu
+// penarikan, untuk penanganan sengketa.
+func (h *WithdrawHandler) ListPayoutEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid withdraw id"})
 		return
 	}
-	if err := h.WithdrawService.UpdateWithdrawStatus(uint(id), req.Status); err != nil {
+	events, err := h.WithdrawService.ListPayoutEvents(uint(id))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+	c.JSON(http.StatusOK, events)
 }
@@ -0,0 +1,233 @@
+// Package candidgen turns a Candid .did interface into Go record types and a
+// typed wrapper around agent-go for every service method, so that a change on the
+// Motoko side shows up as a compile error instead of a runtime reject.
+package candidgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/aviate-labs/agent-go/candid/did"
+)
+
+// Config controls the generated file.
+type Config struct {
+	// Package is the Go package name of the generated file.
+	Package string
+	// Source is the .did path written in the "Code generated" header.
+	Source string
+	// Actor is the (usually unexported) Go type that receives the method wrappers.
+	Actor string
+}
+
+// initialisms are kept upper case in Go names, following Go naming conventions.
+var initialisms = map[string]string{
+	"api": "API", "gps": "GPS", "http": "HTTP", "id": "ID", "ids": "IDs",
+	"nft": "NFT", "nfts": "NFTs", "url": "URL", "uri": "URI",
+}
+
+// Generate renders the Go bindings for the .did source.
+func Generate(src []byte, cfg Config) ([]byte, error) {
+	desc, err := did.ParseDID([]rune(string(src)))
+	if err != nil {
+		return nil, fmt.Errorf("parse did: %w", err)
+	}
+	if len(desc.Services) != 1 {
+		return nil, fmt.Errorf("expected exactly one service, found %d", len(desc.Services))
+	}
+	g := &generator{imports: map[string]bool{}}
+
+	var types bytes.Buffer
+	for _, def := range desc.Definitions {
+		t, ok := def.(did.Type)
+		if !ok {
+			return nil, fmt.Errorf("unsupported definition %s", def)
+		}
+		name := exportedName(t.Id)
+		typ, err := g.goType(t.Data)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", t.Id, err)
+		}
+		fmt.Fprintf(&types, "\n// %s is the Candid type %s.\n", name, t.Id)
+		if _, isRecord := t.Data.(did.Record); isRecord {
+			fmt.Fprintf(&types, "type %s %s\n", name, typ)
+		} else {
+			fmt.Fprintf(&types, "type %s = %s\n", name, typ)
+		}
+	}
+
+	var methods bytes.Buffer
+	for _, m := range desc.Services[0].Methods {
+		if m.Func == nil {
+			return nil, fmt.Errorf("method %s: function references are not supported", m.Name)
+		}
+		if err := g.method(&methods, cfg.Actor, m); err != nil {
+			return nil, fmt.Errorf("method %s: %w", m.Name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by candidgen from %s. DO NOT EDIT.\n\n", cfg.Source)
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", cfg.Package)
+	fmt.Fprintf(&out, "\tagentgo %q\n", "github.com/aviate-labs/agent-go")
+	g.imports["github.com/aviate-labs/agent-go/principal"] = true
+	var paths []string
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fmt.Fprintf(&out, "\t%q\n", p)
+	}
+	out.WriteString(")\n")
+	out.Write(types.Bytes())
+	fmt.Fprintf(&out, "\n// %s calls the canister's methods through one agent.\n", cfg.Actor)
+	fmt.Fprintf(&out, "type %s struct {\n\tagent    *agentgo.Agent\n\tcanister principal.Principal\n}\n", cfg.Actor)
+	out.Write(methods.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+type generator struct {
+	imports map[string]bool
+}
+
+func (g *generator) method(w *bytes.Buffer, actor string, m did.Method) error {
+	var params, args []string
+	for i, a := range m.Func.ArgTypes {
+		name := fmt.Sprintf("arg%d", i)
+		if a.Name != nil && *a.Name != "" {
+			name = paramName(*a.Name)
+		}
+		typ, err := g.goType(a.Data)
+		if err != nil {
+			return err
+		}
+		params = append(params, name+" "+typ)
+		args = append(args, name)
+	}
+	var results, rets, refs []string
+	for i, r := range m.Func.ResTypes {
+		typ, err := g.goType(r.Data)
+		if err != nil {
+			return err
+		}
+		results = append(results, typ)
+		rets = append(rets, fmt.Sprintf("r%d", i))
+		refs = append(refs, fmt.Sprintf("&r%d", i))
+	}
+
+	kind, call := "update", "Call"
+	if ann := m.Func.Annotation; ann != nil {
+		switch *ann {
+		case did.AnnQuery, did.AnnCompositeQuery:
+			kind, call = "query", "Query"
+		default:
+			return fmt.Errorf("unsupported annotation %s", *ann)
+		}
+	}
+
+	name := exportedName(m.Name)
+	fmt.Fprintf(w, "\n// %s calls the %q %s method.\n", name, m.Name, kind)
+	fmt.Fprintf(w, "func (a %s) %s(%s) (%s) {\n", actor, name, strings.Join(params, ", "), strings.Join(append(results, "error"), ", "))
+	for i, typ := range results {
+		fmt.Fprintf(w, "\tvar r%d %s\n", i, typ)
+	}
+	fmt.Fprintf(w, "\terr := a.agent.%s(a.canister, %q, []any{%s}, []any{%s})\n", call, m.Name, strings.Join(args, ", "), strings.Join(refs, ", "))
+	fmt.Fprintf(w, "\treturn %s\n}\n", strings.Join(append(rets, "err"), ", "))
+	return nil
+}
+
+func (g *generator) goType(d did.Data) (string, error) {
+	switch t := d.(type) {
+	case did.Primitive:
+		switch t {
+		case "bool", "float32", "float64", "int8", "int16", "int32", "int64":
+			return string(t), nil
+		case "nat8", "nat16", "nat32", "nat64":
+			return "uint" + strings.TrimPrefix(string(t), "nat"), nil
+		case "text":
+			return "string", nil
+		case "nat":
+			g.imports["github.com/aviate-labs/agent-go/candid/idl"] = true
+			return "idl.Nat", nil
+		case "int":
+			g.imports["github.com/aviate-labs/agent-go/candid/idl"] = true
+			return "idl.Int", nil
+		default:
+			return "", fmt.Errorf("unsupported primitive %s", t)
+		}
+	case did.Principal:
+		return "principal.Principal", nil
+	case did.Blob:
+		return "[]byte", nil
+	case did.DataId:
+		return exportedName(string(t)), nil
+	case did.Optional:
+		inner, err := g.goType(t.Data)
+		if err != nil {
+			return "", err
+		}
+		return "*" + inner, nil
+	case did.Vector:
+		inner, err := g.goType(t.Data)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + inner, nil
+	case did.Record:
+		var b strings.Builder
+		b.WriteString("struct {\n")
+		for _, f := range t {
+			if f.Name == nil || f.Data == nil {
+				return "", fmt.Errorf("only named record fields are supported")
+			}
+			typ, err := g.goType(*f.Data)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "\t%s %s `ic:%q`\n", exportedName(*f.Name), typ, *f.Name)
+		}
+		b.WriteString("}")
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", d)
+	}
+}
+
+// exportedName converts a Candid identifier such as "get_nft_detail" to "GetNFTDetail".
+func exportedName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.Trim(s, `"`), "_") {
+		if part == "" {
+			continue
+		}
+		if up, ok := initialisms[strings.ToLower(part)]; ok && strings.ToLower(part) == part {
+			b.WriteString(up)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// paramName converts a Candid argument name such as "proof_url" to "proofURL".
+func paramName(s string) string {
+	parts := strings.SplitN(strings.Trim(s, `"`), "_", 2)
+	name := strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		name += exportedName(parts[1])
+	}
+	if token.Lookup(name).IsKeyword() {
+		name += "_"
+	}
+	return name
+}
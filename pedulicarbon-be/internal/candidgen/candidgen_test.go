@@ -0,0 +1,83 @@
+package candidgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	cases := map[string]string{
+		"get_nft_detail": "GetNFTDetail",
+		"get_user_nfts":  "GetUserNFTs",
+		"NFTDetail":      "NFTDetail",
+		"mission_id":     "MissionID",
+	}
+	for in, want := range cases {
+		if got := exportedName(in); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	params := map[string]string{"proof_url": "proofURL", "nft_id": "nftID", "user": "user", "type": "type_"}
+	for in, want := range params {
+		if got := paramName(in); got != want {
+			t.Errorf("paramName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src := `type Item = record { id : nat64; tags : vec text; parent : opt nat };
+service : {
+  get : (id : nat64) -> (opt Item) query;
+  put : (Item) -> ();
+}`
+	code, err := Generate([]byte(src), Config{Package: "x", Source: "x.did", Actor: "xActor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ID     uint64   `ic:\"id\"`",
+		"Parent *idl.Nat `ic:\"parent\"`",
+		"func (a xActor) Get(id uint64) (*Item, error)",
+		"a.agent.Query(a.canister, \"get\", []any{id}, []any{&r0})",
+		"func (a xActor) Put(arg0 Item) error",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code lacks %q:\n%s", want, code)
+		}
+	}
+
+	if _, err := Generate([]byte(`service : { f : (variant { a; b }) -> () }`), Config{Package: "x", Actor: "a"}); err == nil {
+		t.Error("expected error for unsupported variant type")
+	}
+}
+
+func TestFromMotoko(t *testing.T) {
+	src := `actor {
+  type Item = { id: Nat64; tags: [Text]; parent: ?Nat };
+  stable var items : [Item] = [];
+  // public func commented(x: Nat) : async () {}
+  public query func get(id: Nat64) : async ?Item { null };
+  public shared({caller}) func put(item: Item, pair: (Text, Int)) : async () {};
+  func private_helper(x: Nat) : Nat { x };
+}`
+	got, err := FromMotoko([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `type Item = record { id : nat64; tags : vec text; parent : opt nat };
+service : {
+  get : (id : nat64) -> (opt Item) query;
+  put : (item : Item, pair : record { text; int }) -> ();
+}`
+	if same, err := SameInterface([]byte(got), []byte(want)); err != nil || !same {
+		t.Fatalf("FromMotoko = %s, %v", got, err)
+	}
+	if same, _ := SameInterface([]byte(got), []byte(strings.Replace(want, "opt Item", "Item", 1))); same {
+		t.Fatal("SameInterface ignored a changed result type")
+	}
+
+	if _, err := FromMotoko([]byte(`actor { public func f(m: [var Nat]) : async () {} }`)); err == nil {
+		t.Fatal("mutable array accepted")
+	}
+}
@@ -0,0 +1,199 @@
+package candidgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aviate-labs/agent-go/candid/did"
+)
+
+// The .did file is written by hand because dfx is not part of the backend build.
+// FromMotoko derives the Candid interface from the actor source instead, for the
+// subset of Motoko the canister uses (record types, primitives, arrays, options
+// and tuples), so a test can catch a .did that drifted from main.mo.
+
+var (
+	lineComment = regexp.MustCompile(`//[^\n]*`)
+	actorHeader = regexp.MustCompile(`\bactor\s+(?:class\s+\w+\s*\(([^)]*)\))?`)
+	typeDecl    = regexp.MustCompile(`(?s)\btype\s+(\w+)\s*=\s*\{(.*?)\}\s*;`)
+	publicFunc  = regexp.MustCompile(`\bpublic\s+(shared\s*(?:\([^)]*\))?\s*)?(query\s+)?func\s+(\w+)\s*\(`)
+	asyncResult = regexp.MustCompile(`^\s*:\s*async\s+([^{]+?)\s*\{`)
+)
+
+var motokoPrimitives = map[string]string{
+	"Bool": "bool", "Text": "text", "Principal": "principal", "Blob": "blob",
+	"Nat": "nat", "Nat8": "nat8", "Nat16": "nat16", "Nat32": "nat32", "Nat64": "nat64",
+	"Int": "int", "Int8": "int8", "Int16": "int16", "Int32": "int32", "Int64": "int64",
+	"Float": "float64", "Null": "null",
+}
+
+// FromMotoko renders the Candid interface of the actor in src.
+func FromMotoko(src []byte) (string, error) {
+	code := lineComment.ReplaceAllString(string(src), "")
+	header := actorHeader.FindStringSubmatch(code)
+	if header == nil {
+		return "", fmt.Errorf("no actor found")
+	}
+	if strings.TrimSpace(header[1]) != "" {
+		return "", fmt.Errorf("actor class parameters are not supported")
+	}
+
+	types := map[string]bool{}
+	decls := typeDecl.FindAllStringSubmatch(code, -1)
+	for _, d := range decls {
+		types[d[1]] = true
+	}
+	var out strings.Builder
+	for _, d := range decls {
+		var fields []string
+		for _, f := range splitTopLevel(d[2], ';') {
+			name, typ, ok := strings.Cut(f, ":")
+			if !ok {
+				return "", fmt.Errorf("type %s: field %q has no type", d[1], f)
+			}
+			c, err := candidType(typ, types)
+			if err != nil {
+				return "", fmt.Errorf("type %s: %w", d[1], err)
+			}
+			fields = append(fields, strings.TrimSpace(name)+" : "+c)
+		}
+		fmt.Fprintf(&out, "type %s = record { %s };\n", d[1], strings.Join(fields, "; "))
+	}
+
+	out.WriteString("service : {\n")
+	for _, loc := range publicFunc.FindAllStringSubmatchIndex(code, -1) {
+		f := make([]string, 4)
+		for i := range f {
+			if loc[2*i] >= 0 {
+				f[i] = code[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		end := closingParen(code, loc[1])
+		if end < 0 {
+			return "", fmt.Errorf("func %s: unbalanced parameter list", f[3])
+		}
+		result := asyncResult.FindStringSubmatch(code[end+1:])
+		if result == nil {
+			return "", fmt.Errorf("func %s: public functions must return async", f[3])
+		}
+		var args []string
+		for _, a := range splitTopLevel(code[loc[1]:end], ',') {
+			name, typ, ok := strings.Cut(a, ":")
+			if !ok {
+				return "", fmt.Errorf("func %s: argument %q has no type", f[3], a)
+			}
+			c, err := candidType(typ, types)
+			if err != nil {
+				return "", fmt.Errorf("func %s: %w", f[3], err)
+			}
+			args = append(args, strings.TrimSpace(name)+" : "+c)
+		}
+		res := ""
+		if ret := strings.TrimSpace(result[1]); ret != "()" {
+			c, err := candidType(ret, types)
+			if err != nil {
+				return "", fmt.Errorf("func %s: %w", f[3], err)
+			}
+			res = c
+		}
+		ann := ""
+		if f[2] != "" {
+			ann = " query"
+		}
+		fmt.Fprintf(&out, "  %s : (%s) -> (%s)%s;\n", f[3], strings.Join(args, ", "), res, ann)
+	}
+	out.WriteString("}\n")
+	return out.String(), nil
+}
+
+// SameInterface reports whether two Candid sources declare the same types and
+// service methods, ignoring comments and formatting.
+func SameInterface(a, b []byte) (bool, error) {
+	da, err := did.ParseDID([]rune(string(a)))
+	if err != nil {
+		return false, fmt.Errorf("parse did: %w", err)
+	}
+	db, err := did.ParseDID([]rune(string(b)))
+	if err != nil {
+		return false, fmt.Errorf("parse did: %w", err)
+	}
+	return da.String() == db.String(), nil
+}
+
+func candidType(t string, types map[string]bool) (string, error) {
+	t = strings.TrimSpace(t)
+	switch {
+	case strings.HasPrefix(t, "?"):
+		inner, err := candidType(t[1:], types)
+		return "opt " + inner, err
+	case strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]"):
+		if strings.HasPrefix(t, "[var ") {
+			return "", fmt.Errorf("mutable array %s is not shareable", t)
+		}
+		inner, err := candidType(t[1:len(t)-1], types)
+		return "vec " + inner, err
+	case strings.HasPrefix(t, "(") && strings.HasSuffix(t, ")"):
+		var elems []string
+		for _, e := range splitTopLevel(t[1:len(t)-1], ',') {
+			c, err := candidType(e, types)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, c)
+		}
+		return "record { " + strings.Join(elems, "; ") + " }", nil
+	}
+	if c, ok := motokoPrimitives[t]; ok {
+		return c, nil
+	}
+	if types[t] {
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported Motoko type %q", t)
+}
+
+// closingParen returns the index of the ")" closing the list that starts at
+// open (just after its "("), or -1.
+func closingParen(s string, open int) int {
+	depth := 1
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s on sep outside brackets and drops empty parts.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	out := parts[:0]
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			out = append(out, strings.TrimSpace(p))
+		}
+	}
+	return out
+}
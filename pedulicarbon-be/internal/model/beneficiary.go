@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Beneficiary adalah rekening bank atau e-wallet tujuan penarikan milik user.
+// Rekening baru baru bisa dipakai setelah ActiveAt (masa cool-down) sebagai
+// perlindungan bila akun user diambil alih.
+type Beneficiary struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Label         string     `json:"label"`                   // mis. BCA gaji
+	Channel       string     `gorm:"not null" json:"channel"` // bank, ewallet
+	Provider      string     `gorm:"not null" json:"provider"`
+	AccountNumber string     `gorm:"not null" json:"account_number"` // nomor rekening atau nomor HP
+	HolderName    string     `gorm:"not null" json:"holder_name"`
+	Verified      bool       `gorm:"not null;default:false" json:"verified"`
+	VerifiedAt    *time.Time `json:"verified_at"`
+	VerifiedBy    *uint      `json:"verified_by,omitempty"`
+	ActiveAt      time.Time  `json:"active_at"`
+	RemovedAt     *time.Time `gorm:"index" json:"removed_at,omitempty"` // diisi oleh DELETE; penarikan lama tetap merujuk ke sini
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Target adalah format tujuan yang disalin ke Withdraw.Target:
+// bank:<kode>:<rekening> atau <ewallet>:<no hp>.
+func (b *Beneficiary) Target() string {
+	if b.Channel == WithdrawChannelBank {
+		return WithdrawChannelBank + ":" + b.Provider + ":" + b.AccountNumber
+	}
+	return b.Provider + ":" + b.AccountNumber
+}
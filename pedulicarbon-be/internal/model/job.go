@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const (
+	JobTypeVerifyMission = "verify_mission"
+	JobTypeClaimNFT      = "claim_nft"
+	JobTypeDisburse      = "disburse_withdraw"
+
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead"
+)
+
+// Job adalah pekerjaan canister yang diproses worker di background. ID-nya
+// dikembalikan ke client sebagai operation ID untuk di-poll lewat /operations/:id.
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `json:"type" gorm:"index"`
+	DedupKey    string     `json:"-" gorm:"index"`
+	Payload     string     `json:"-" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"index"` // queued, running, succeeded, dead
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at" gorm:"index"`
+	LockedUntil *time.Time `json:"-"`
+	LastError   string     `json:"last_error"`
+	Result      string     `json:"result" gorm:"type:text"`
+	CreatedBy   uint       `json:"created_by"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
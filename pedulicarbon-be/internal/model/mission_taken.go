@@ -4,14 +4,26 @@ import (
 	"time"
 )
 
+const (
+	MissionStatusTaken    = "taken"
+	MissionStatusPending  = "pending"
+	MissionStatusVerified = "verified"
+	MissionStatusRejected = "rejected"
+)
+
 type MissionTaken struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	UserID     uint      `json:"user_id"`
-	MissionID  uint      `json:"mission_id"`
-	Status     string    `json:"status"` // taken, pending, verified, rejected
-	ProofURL   string    `json:"proof_url"`
-	GPS        string    `json:"gps"`
-	VerifiedAt time.Time `json:"verified_at"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `json:"user_id"`
+	MissionID       uint       `json:"mission_id"`
+	Status          string     `json:"status"` // taken, pending, verified, rejected
+	ProofURL        string     `json:"proof_url"`
+	GPS             string     `json:"gps"`
+	VerifiedAt      time.Time  `json:"verified_at"`
+	VerifiedBy      *uint      `json:"verified_by"`
+	RejectionReason string     `json:"rejection_reason"`
+	RejectedBy      *uint      `json:"rejected_by"`
+	RejectedAt      *time.Time `json:"rejected_at"`
+	ResubmitCount   int        `json:"resubmit_count" gorm:"default:0"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Jenis selisih yang dilaporkan rekonsiliasi UserNFT vs canister.
+const (
+	ReconcileOrphanOnChain  = "orphan_on_chain" // NFT ada di canister tapi tidak ada baris UserNFT
+	ReconcileOrphanLocal    = "orphan_local"    // baris "owned" tapi NFT tidak ada di canister
+	ReconcileOwnerMismatch  = "owner_mismatch"  // baris UserNFT milik user lain dari pemilik on-chain
+	ReconcileStatusMismatch = "status_mismatch" // status lokal tidak cocok dengan keberadaan on-chain
+)
+
+// NFTReconciliationAudit mencatat setiap koreksi yang dilakukan rekonsiliasi dalam
+// mode repair. Before/After berisi JSON baris UserNFT sebelum dan sesudah koreksi.
+type NFTReconciliationAudit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RunID     string    `gorm:"index" json:"run_id"`
+	Issue     string    `json:"issue"`
+	UserID    uint      `json:"user_id"`
+	NFTID     string    `gorm:"index" json:"nft_id"`
+	Action    string    `json:"action"`
+	Before    string    `gorm:"type:text" json:"before"`
+	After     string    `gorm:"type:text" json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
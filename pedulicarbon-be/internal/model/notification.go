@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Jenis notifikasi in-app.
+const (
+	NotificationPointsExpiring     = "points_expiring"
+	NotificationBeneficiaryChanged = "beneficiary_changed"
+)
+
+// Notification adalah pesan in-app untuk user. RefKey (opsional) mencegah
+// notifikasi yang sama dikirim dua kali oleh job terjadwal.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`
+	Title     string     `json:"title"`
+	Message   string     `json:"message"`
+	RefKey    string     `gorm:"not null;default:'';uniqueIndex:idx_notification_ref,where:ref_key <> ''" json:"-"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Jenis PayoutEvent.
+const (
+	PayoutEventCreate   = "create"   // respons provider saat disbursement dibuat
+	PayoutEventQuery    = "query"    // status final dari polling provider
+	PayoutEventCallback = "callback" // webhook provider yang lolos verifikasi signature
+)
+
+// PayoutEvent menyimpan respons dan callback payout provider apa adanya untuk
+// penanganan sengketa. Append-only; WithdrawID kosong bila callback tidak cocok
+// dengan penarikan mana pun.
+type PayoutEvent struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WithdrawID  *uint     `gorm:"index" json:"withdraw_id"`
+	Provider    string    `json:"provider"`
+	Kind        string    `json:"kind"` // create, query, callback
+	ProviderRef string    `gorm:"index" json:"provider_ref"`
+	Status      string    `json:"status"`
+	Payload     string    `gorm:"type:text" json:"payload"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Jenis entri ledger point.
+const (
+	PointEntryMissionReward = "mission_reward"
+	PointEntryRedemption    = "redemption"
+	PointEntryAdjustment    = "adjustment"
+	PointEntryExpiry        = "expiry"
+	PointEntryTransfer      = "transfer"
+)
+
+// PointTransaction adalah satu baris ledger point yang append-only. Setiap posting
+// terdiri dari dua baris dengan JournalID yang sama dan jumlah Amount nol: satu di
+// akun user ("user:<id>") dan satu di akun lawan (akun sistem atau user lain untuk
+// transfer). User.Points selalu sama dengan jumlah Amount baris milik user tersebut.
+type PointTransaction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	JournalID    string    `gorm:"index;not null" json:"journal_id"`
+	Account      string    `gorm:"not null;uniqueIndex:idx_point_tx_ref,where:ref_id <> ''" json:"account"`
+	UserID       *uint     `gorm:"index" json:"user_id"` // terisi untuk baris akun user
+	EntryType    string    `gorm:"not null;uniqueIndex:idx_point_tx_ref,where:ref_id <> ''" json:"entry_type"`
+	Amount       int       `gorm:"not null" json:"amount"`        // positif menambah, negatif mengurangi saldo akun
+	BalanceAfter int       `gorm:"not null" json:"balance_after"` // saldo akun user setelah baris ini; 0 untuk akun sistem
+	RefType      string    `gorm:"not null;default:'';uniqueIndex:idx_point_tx_ref,where:ref_id <> ''" json:"ref_type"`
+	RefID        string    `gorm:"not null;default:'';uniqueIndex:idx_point_tx_ref,where:ref_id <> ''" json:"ref_id"`
+	Description  string    `json:"description"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// PrincipalChallenge adalah nonce sekali pakai yang harus ditandatangani lewat
+// delegasi Internet Identity untuk membuktikan kepemilikan principal.
+type PrincipalChallenge struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Challenge string     `json:"challenge" gorm:"uniqueIndex"` // hex
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
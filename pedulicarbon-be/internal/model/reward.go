@@ -4,14 +4,45 @@ import (
 	"time"
 )
 
+// Status Reward. Reward produk berjalan redeemed → packed → shipped → delivered,
+// atau cancelled (point dikembalikan) selama belum dikirim. Reward voucher menjadi
+// used saat kodenya dipakai di merchant.
+const (
+	RewardStatusRedeemed  = "redeemed"
+	RewardStatusPacked    = "packed"
+	RewardStatusShipped   = "shipped"
+	RewardStatusDelivered = "delivered"
+	RewardStatusCancelled = "cancelled"
+	RewardStatusUsed      = "used"
+)
+
 type Reward struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `json:"user_id"`
-	MissionID   uint      `json:"mission_id"`
-	Points      int       `json:"points"`
-	AssetType   string    `json:"asset_type"`
-	AssetAmount float64   `json:"asset_amount"`
-	Status      string    `json:"status"` // e.g. pending, verified, distributed
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint    `gorm:"primaryKey" json:"id"`
+	UserID      uint    `gorm:"uniqueIndex:idx_reward_idempotency,where:idempotency_key <> ''" json:"user_id"`
+	MissionID   uint    `json:"mission_id"`
+	CatalogID   *uint   `gorm:"index" json:"catalog_id,omitempty"` // terisi bila reward hasil redeem katalog
+	Points      int     `json:"points"`
+	AssetType   string  `json:"asset_type"`
+	AssetAmount float64 `json:"asset_amount"`
+	Status      string  `gorm:"index" json:"status"` // redeemed, packed, shipped, delivered, cancelled, used
+	// IdempotencyKey dari header Idempotency-Key saat redeem, unik per user
+	IdempotencyKey string `gorm:"not null;default:'';uniqueIndex:idx_reward_idempotency,where:idempotency_key <> ''" json:"-"`
+	// VoucherCode yang diberikan saat redeem katalog voucher
+	VoucherCode *VoucherCode `gorm:"foreignKey:RewardID" json:"voucher_code,omitempty"`
+
+	// Pengiriman reward produk; alamat disalin dari ShippingAddress saat redeem
+	ShippingAddressID *uint      `json:"shipping_address_id,omitempty"`
+	RecipientName     string     `json:"recipient_name,omitempty"`
+	RecipientPhone    string     `json:"recipient_phone,omitempty"`
+	ShippingAddress   string     `json:"shipping_address,omitempty"`
+	Courier           string     `json:"courier,omitempty"`
+	TrackingNumber    string     `json:"tracking_number,omitempty"`
+	PackedAt          *time.Time `json:"packed_at,omitempty"`
+	ShippedAt         *time.Time `json:"shipped_at,omitempty"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+	CancelledAt       *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason      string     `json:"cancel_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
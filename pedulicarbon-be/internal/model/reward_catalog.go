@@ -4,13 +4,37 @@ import (
 	"time"
 )
 
+const (
+	CatalogTypeVoucher = "voucher"
+	CatalogTypeProduct = "product"
+)
+
 type RewardCatalog struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Name           string    `json:"name"`
-	Description    string    `json:"description"`
-	PointsRequired int       `json:"points_required"`
-	Stock          int       `json:"stock"`
-	Type           string    `json:"type"` // voucher, product
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uint     `gorm:"primaryKey" json:"id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Category       string   `gorm:"not null;default:'';index" json:"category"`
+	ImageURLs      []string `gorm:"type:text;serializer:json" json:"image_urls"`
+	PointsRequired int      `json:"points_required"`
+	Stock          int      `json:"stock"`                                  // untuk voucher: jumlah VoucherCode yang masih available
+	Type           string   `json:"type"`                                   // voucher, product
+	MaxPerUser     int      `gorm:"not null;default:0" json:"max_per_user"` // 0 = tanpa batas
+	MerchantID     *uint    `gorm:"index" json:"merchant_id"`               // pemilik item; nil = item platform, hanya admin yang bisa mengubah
+	// Jendela waktu item bisa diredeem; nil berarti tanpa batas di sisi tersebut
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+	ArchivedAt     *time.Time `gorm:"index" json:"archived_at"` // diisi oleh DELETE; item tidak tampil dan tidak bisa diredeem
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AvailableAt reports whether the item can be redeemed at t.
+func (c *RewardCatalog) AvailableAt(t time.Time) bool {
+	if c.ArchivedAt != nil {
+		return false
+	}
+	if c.AvailableFrom != nil && t.Before(*c.AvailableFrom) {
+		return false
+	}
+	return c.AvailableUntil == nil || t.Before(*c.AvailableUntil)
 }
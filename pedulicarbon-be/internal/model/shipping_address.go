@@ -0,0 +1,31 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ShippingAddress adalah alamat kirim milik user untuk reward produk.
+type ShippingAddress struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	Label         string    `json:"label"` // mis. Rumah, Kantor
+	RecipientName string    `gorm:"not null" json:"recipient_name"`
+	Phone         string    `gorm:"not null" json:"phone"`
+	AddressLine   string    `gorm:"not null" json:"address_line"`
+	City          string    `gorm:"not null" json:"city"`
+	Province      string    `json:"province"`
+	PostalCode    string    `gorm:"not null" json:"postal_code"`
+	IsDefault     bool      `gorm:"not null;default:false" json:"is_default"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Formatted menggabungkan alamat menjadi satu baris untuk label pengiriman.
+func (a *ShippingAddress) Formatted() string {
+	parts := []string{a.AddressLine, a.City}
+	if a.Province != "" {
+		parts = append(parts, a.Province)
+	}
+	return strings.Join(parts, ", ") + " " + a.PostalCode
+}
@@ -4,12 +4,33 @@ import (
 	"time"
 )
 
+const (
+	RoleUser         = "user"
+	RoleAdmin        = "admin"
+	RoleVerifier     = "verifier"
+	RoleFinanceAdmin = "finance_admin"
+	RoleMerchant     = "merchant"
+	RoleInstitution  = "institution"
+)
+
 type User struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `json:"name" gorm:"not null"`
-	Email       string    `json:"email" gorm:"unique;not null"`
-	IIPrincipal string    `json:"ii_principal" gorm:"not null"`
-	Points      int       `json:"points" gorm:"default:0"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	Name                string     `json:"name" gorm:"not null"`
+	Email               string     `json:"email" gorm:"unique;not null"`
+	PasswordHash        string     `json:"-" gorm:"not null;default:''"`
+	IIPrincipal         string     `json:"ii_principal" gorm:"not null;default:'';index:idx_users_verified_principal,unique,where:principal_verified = true"`
+	PrincipalVerified   bool       `json:"principal_verified" gorm:"default:false"`
+	PrincipalVerifiedAt *time.Time `json:"principal_verified_at"`
+	Role                string     `json:"role" gorm:"not null;default:'user'"` // user, admin, verifier, finance_admin, merchant, institution
+	Points              int        `json:"points" gorm:"default:0"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin, RoleVerifier, RoleFinanceAdmin, RoleMerchant, RoleInstitution:
+		return true
+	}
+	return false
 }
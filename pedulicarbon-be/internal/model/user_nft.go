@@ -6,9 +6,11 @@ type UserNFT struct {
 	ID             uint       `gorm:"primaryKey" json:"id"`
 	UserID         uint       `json:"user_id"`
 	NFTID          string     `json:"nft_id"`
-	Status         string     `json:"status"`     // owned, claimed
+	Status         string     `json:"status"`     // owned, claiming, claimed, orphaned
 	ClaimedBy      *uint      `json:"claimed_by"` // user/institusi yang claim
 	ClaimedAt      *time.Time `json:"claimed_at"`
 	CertificateURL string     `json:"certificate_url"`
+	CarbonAmount   float64    `gorm:"not null;default:0" json:"carbon_amount"` // asset_amount mission saat NFT dicetak
+	Simulated      bool       `gorm:"not null;default:false" json:"simulated"` // dicetak oleh canister simulasi, tidak ada on-chain
 	CreatedAt      time.Time  `json:"created_at"`
 }
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+const (
+	SagaStepStarted          = "started"
+	SagaStepCanisterVerified = "canister_verified"
+	SagaStepMinted           = "minted"
+	SagaStepCompleted        = "completed"
+	SagaStepFailed           = "failed"
+	SagaStepCompensated      = "compensated"
+)
+
+// VerificationSaga mencatat progres VerifyMission per langkah supaya verifikasi
+// yang terputus bisa dilanjutkan atau di-rollback saat restart.
+type VerificationSaga struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	MissionTakenID uint       `json:"mission_taken_id" gorm:"uniqueIndex"`
+	UserID         uint       `json:"user_id"`
+	VerifierID     uint       `json:"verifier_id"`
+	Attempt        int        `json:"attempt" gorm:"default:1"`
+	IdempotencyKey string     `json:"idempotency_key" gorm:"uniqueIndex"`
+	Step           string     `json:"step"` // started, canister_verified, minted, completed, failed, compensated
+	NFTID          string     `json:"nft_id"`
+	LastError      string     `json:"last_error"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// IsTerminal reports whether the saga needs no further processing.
+func (s *VerificationSaga) IsTerminal() bool {
+	return s.Step == SagaStepCompleted || s.Step == SagaStepFailed || s.Step == SagaStepCompensated
+}
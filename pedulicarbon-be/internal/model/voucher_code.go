@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+const (
+	VoucherStatusAvailable = "available"
+	VoucherStatusAssigned  = "assigned"
+	VoucherStatusUsed      = "used"
+)
+
+// VoucherCode adalah satu kode voucher yang di-upload merchant untuk item katalog
+// bertipe voucher. Stok katalog voucher sama dengan jumlah kode yang masih available.
+type VoucherCode struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CatalogID  uint       `gorm:"not null;uniqueIndex:idx_voucher_catalog_code" json:"catalog_id"`
+	Code       string     `gorm:"not null;uniqueIndex:idx_voucher_catalog_code" json:"code"`
+	BatchID    string     `gorm:"index" json:"batch_id"`
+	Status     string     `gorm:"not null;index" json:"status"` // available, assigned, used
+	RewardID   *uint      `gorm:"uniqueIndex" json:"reward_id"`
+	UserID     *uint      `gorm:"index" json:"user_id"`
+	AssignedAt *time.Time `json:"assigned_at"`
+	UsedAt     *time.Time `json:"used_at"`
+	UsedBy     *uint      `json:"used_by"` // merchant yang menandai kode terpakai
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -4,12 +4,25 @@ import (
 	"time"
 )
 
+// Jenis entri ledger rupiah.
+const (
+	RupiahEntryAdjustment        = "adjustment"
+	RupiahEntryWithdrawalHold    = "withdrawal_hold"
+	RupiahEntryWithdrawalRelease = "withdrawal_release"
+	RupiahEntryWithdrawalPayout  = "withdrawal_payout"
+)
+
+// Wallet adalah ringkasan saldo user. Hanya Rupiah (saldo yang bisa dipakai) yang
+// disimpan, dan hanya ledger rupiah yang mengubahnya; HeldRupiah, Points dan
+// CarbonNFT dihitung saat dibaca dari akun hold penarikan, ledger point dan UserNFT
+// yang masih dimiliki user.
 type Wallet struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `json:"user_id"`
-	CarbonNFT float64   `json:"carbon_nft"`
-	Points    int       `json:"points"`
-	Rupiah    float64   `json:"rupiah"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"uniqueIndex" json:"user_id"`
+	CarbonNFT  float64   `gorm:"-" json:"carbon_nft"`
+	Points     int       `gorm:"-" json:"points"`
+	Rupiah     int64     `gorm:"not null;default:0" json:"rupiah"`
+	HeldRupiah int64     `gorm:"-" json:"held_rupiah"` // ditahan untuk penarikan yang belum selesai
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
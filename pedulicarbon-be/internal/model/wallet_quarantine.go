@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Asal saldo yang dikarantina.
+const (
+	QuarantineLegacyColumn    = "legacy_column"       // kolom float wallets.rupiah sebelum ledger, ditulis client
+	QuarantineUnexplained     = "unexplained_balance" // wallets.rupiah berbeda dengan jumlah ledger
+	QuarantineDuplicateWallet = "duplicate_wallet"    // wallet ganda yang dihapus sebelum unique index user_id
+)
+
+// WalletQuarantine mencatat saldo rupiah yang tidak berasal dari ledger. Saldo
+// itu tidak dipercaya: wallet di-reset ke jumlah ledger dan dana yang sah hanya
+// bisa dikembalikan finance/admin lewat adjustment dengan alasan (Restore).
+type WalletQuarantine struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	WalletID     uint       `json:"wallet_id"`
+	Source       string     `gorm:"not null" json:"source"`
+	Amount       string     `gorm:"not null" json:"amount"`    // nilai asli apa adanya, mis. "12000.5"
+	LedgerRupiah int64      `json:"ledger_rupiah"`             // saldo ledger saat dikarantina
+	Snapshot     string     `gorm:"type:text" json:"snapshot"` // JSON baris wallet yang dihapus, untuk duplicate_wallet
+	ResolvedAt   *time.Time `gorm:"index" json:"resolved_at"`  // diisi saat dana dikembalikan atau dinyatakan tidak sah
+	ResolvedBy   *uint      `json:"resolved_by,omitempty"`
+	Resolution   string     `json:"resolution,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// WalletTransaction adalah satu baris ledger rupiah yang append-only, dengan pola
+// yang sama seperti PointTransaction: setiap posting terdiri dari dua baris dengan
+// JournalID yang sama dan jumlah Amount nol, satu di akun user ("user:<id>") dan
+// satu di akun sistem. Wallet.Rupiah selalu sama dengan jumlah Amount baris user.
+type WalletTransaction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	JournalID    string    `gorm:"index;not null" json:"journal_id"`
+	Account      string    `gorm:"not null;uniqueIndex:idx_wallet_tx_ref,where:ref_id <> ''" json:"account"`
+	UserID       *uint     `gorm:"index" json:"user_id"` // terisi untuk baris akun user
+	EntryType    string    `gorm:"not null;uniqueIndex:idx_wallet_tx_ref,where:ref_id <> ''" json:"entry_type"`
+	Amount       int64     `gorm:"not null" json:"amount"`        // rupiah; positif menambah, negatif mengurangi saldo akun
+	BalanceAfter int64     `gorm:"not null" json:"balance_after"` // saldo akun user setelah baris ini; 0 untuk akun sistem
+	RefType      string    `gorm:"not null;default:'';uniqueIndex:idx_wallet_tx_ref,where:ref_id <> ''" json:"ref_type"`
+	RefID        string    `gorm:"not null;default:'';uniqueIndex:idx_wallet_tx_ref,where:ref_id <> ''" json:"ref_id"`
+	Description  string    `json:"description"`
+	CreatedAt    time.Time `json:"created_at"`
+}
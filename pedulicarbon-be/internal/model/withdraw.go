@@ -4,12 +4,41 @@ import (
 	"time"
 )
 
+// Status penarikan rupiah: pending/review -> processing -> success/failed.
+// review berarti penarikan ditandai mencurigakan dan menunggu persetujuan.
+const (
+	WithdrawStatusPending    = "pending"
+	WithdrawStatusReview     = "review"
+	WithdrawStatusProcessing = "processing"
+	WithdrawStatusSuccess    = "success"
+	WithdrawStatusFailed     = "failed"
+)
+
+// Channel tujuan penarikan, menentukan format Target dan biaya.
+const (
+	WithdrawChannelBank    = "bank"
+	WithdrawChannelEWallet = "ewallet"
+)
+
+// Withdraw adalah permintaan penarikan rupiah. Amount + Fee ditahan (hold) dari
+// saldo wallet saat dibuat, dikembalikan bila gagal dan dibayarkan bila sukses.
 type Withdraw struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `json:"user_id"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"` // pending, success, failed
-	Target    string    `json:"target"` // e-wallet/bank
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                uint               `gorm:"primaryKey" json:"id"`
+	UserID            uint               `gorm:"index" json:"user_id"`
+	Amount            int64              `json:"amount"` // rupiah yang diterima user
+	Fee               int64              `gorm:"not null;default:0" json:"fee"`
+	Status            string             `gorm:"index" json:"status"`         // pending, review, processing, success, failed
+	Channel           string             `json:"channel"`                     // bank, ewallet
+	BeneficiaryID     *uint              `gorm:"index" json:"beneficiary_id"` // kosong untuk penarikan sebelum ada rekening tujuan
+	Target            string             `json:"target"`                      // salinan tujuan: bank:<kode>:<rekening> atau <ewallet>:<no hp>
+	HolderName        string             `json:"holder_name,omitempty"`
+	FailureReason     string             `json:"failure_reason,omitempty"`
+	Provider          string             `json:"provider,omitempty"`                           // payout provider yang mencairkan, kosong = manual
+	ProviderRef       string             `gorm:"index" json:"provider_ref,omitempty"`          // ID disbursement di provider
+	RequiredApprovals int                `gorm:"not null;default:0" json:"required_approvals"` // finance admin berbeda yang harus menyetujui sebelum processing
+	Decisions         []WithdrawDecision `gorm:"foreignKey:WithdrawID" json:"decisions,omitempty"`
+	ProcessingAt      *time.Time         `json:"processing_at"`
+	CompletedAt       *time.Time         `json:"completed_at"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
 }
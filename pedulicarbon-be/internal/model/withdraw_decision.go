@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Jenis WithdrawDecision.
+const (
+	WithdrawDecisionCreated       = "created"        // user membuat penarikan
+	WithdrawDecisionFlagged       = "flagged"        // batas velocity/kumulatif terlampaui, masuk review
+	WithdrawDecisionApproved      = "approved"       // persetujuan satu finance admin
+	WithdrawDecisionRejected      = "rejected"       // ditolak sebelum dicairkan
+	WithdrawDecisionStatusChanged = "status_changed" // perubahan status lain, oleh admin atau payout provider
+)
+
+// WithdrawDecision adalah satu entri audit trail penarikan. Append-only: entri
+// tidak pernah diubah atau dihapus. ActorID kosong berarti keputusan sistem.
+type WithdrawDecision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	WithdrawID uint      `gorm:"not null;index" json:"withdraw_id"`
+	ActorID    *uint     `gorm:"index" json:"actor_id"`
+	Action     string    `gorm:"not null" json:"action"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,52 @@
+package motoko
+
+import (
+	"bytes"
+	"os"
+	"pedulicarbon/internal/candidgen"
+	"testing"
+)
+
+// Harus sama persis dengan argumen go:generate di canister.go.
+const didPath = "../../motoko/pedulicarbon/src/pedulicarbon_backend/pedulicarbon_backend.did"
+
+func TestGeneratedBindingsUpToDate(t *testing.T) {
+	src, err := os.ReadFile(didPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := candidgen.Generate(src, candidgen.Config{Package: "motoko", Source: didPath, Actor: "pedulicarbonActor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("pedulicarbon_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("pedulicarbon_gen.go is out of date with the .did file; run `go generate ./internal/motoko`")
+	}
+}
+
+// .did ditulis tangan; pastikan masih sama dengan actor di main.mo.
+func TestDIDMatchesMotokoSource(t *testing.T) {
+	mo, err := os.ReadFile("../../motoko/pedulicarbon/src/pedulicarbon_backend/main.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSource, err := candidgen.FromMotoko(mo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(didPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := candidgen.SameInterface(src, []byte(fromSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same {
+		t.Fatalf("%s is out of date with main.mo; the actor declares:\n%s", didPath, fromSource)
+	}
+}
@@ -0,0 +1,29 @@
+package motoko
+
+//go:generate go run ../../cmd/candidgen -did ../../motoko/pedulicarbon/src/pedulicarbon_backend/pedulicarbon_backend.did -out pedulicarbon_gen.go -package motoko -actor pedulicarbonActor
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNFTNotFound is returned by GetNFTDetail when get_nft_detail answers null,
+// i.e. the NFT was never minted or has been burned.
+var ErrNFTNotFound = errors.New("nft not found on canister")
+
+// CanisterClient is the set of PeduliCarbon canister methods used by the services.
+// MotokoClient talks to a real replica; FakeCanister keeps the same state in memory.
+type CanisterClient interface {
+	VerifyAction(ctx context.Context, userPrincipal string, missionID uint, proofURL, gps string) (bool, error)
+	MintNFT(ctx context.Context, userPrincipal string, missionID uint, carbonAmount float64, idempotencyKey string) (string, error)
+	BurnNFT(ctx context.Context, nftID string) error
+	GetUserNFTs(ctx context.Context, userPrincipal string) ([]string, error)
+	GetNFTDetail(ctx context.Context, nftID string) (*NFTDetail, error)
+	// Simulated reports whether results come from an in-memory simulation rather than the IC.
+	Simulated() bool
+}
+
+var (
+	_ CanisterClient = (*MotokoClient)(nil)
+	_ CanisterClient = (*FakeCanister)(nil)
+)
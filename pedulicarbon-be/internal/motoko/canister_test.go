@@ -0,0 +1,53 @@
+package motoko
+
+import (
+	"testing"
+
+	"github.com/aviate-labs/agent-go/candid"
+	"github.com/aviate-labs/agent-go/candid/idl"
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+// nftDetailType is the Candid type of get_nft_detail's result, ?NFTDetail.
+var nftDetailType = idl.NewOptionalType(idl.NewRecordType(map[string]idl.Type{
+	"id":            new(idl.TextType),
+	"owner":         new(idl.PrincipalType),
+	"mission_id":    new(idl.NatType),
+	"carbon_amount": idl.Float64Type(),
+	"timestamp":     new(idl.IntType),
+}))
+
+// Jawaban get_nft_detail harus ter-decode ke *NFTDetail, dengan null menjadi nil.
+func TestNFTDetailDecodesFromCandid(t *testing.T) {
+	some, err := candid.Encode([]idl.Type{nftDetailType}, []any{map[string]any{
+		"id":            "NFT-7",
+		"owner":         principal.AnonymousID,
+		"mission_id":    idl.NewNat(uint(7)),
+		"carbon_amount": 1.5,
+		"timestamp":     idl.NewInt(int64(1700000000000000000)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var detail *NFTDetail
+	if err := candid.Unmarshal(some, []any{&detail}); err != nil {
+		t.Fatalf("decode some: %v", err)
+	}
+	if detail == nil || detail.ID != "NFT-7" || !detail.Owner.Equal(principal.AnonymousID) ||
+		detail.MissionID.BigInt().Int64() != 7 || detail.CarbonAmount != 1.5 ||
+		detail.Timestamp.BigInt().Int64() != 1700000000000000000 {
+		t.Fatalf("detail = %+v", detail)
+	}
+
+	none, err := candid.Encode([]idl.Type{nftDetailType}, []any{nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail = nil
+	if err := candid.Unmarshal(none, []any{&detail}); err != nil {
+		t.Fatalf("decode none: %v", err)
+	}
+	if detail != nil {
+		t.Fatalf("null decoded to %+v", detail)
+	}
+}
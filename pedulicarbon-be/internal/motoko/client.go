@@ -2,344 +2,264 @@ package motoko
 
 import (
 	"context"
-	"crypto/ed25519"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
-	"math/big"
+	"log"
+	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	agentgo "github.com/aviate-labs/agent-go"
+	"github.com/aviate-labs/agent-go/candid/idl"
 	"github.com/aviate-labs/agent-go/identity"
 	"github.com/aviate-labs/agent-go/principal"
 )
 
+// MotokoClient calls the PeduliCarbon canister through agent-go. The identity and
+// agent are built once and shared by every call. When the client was created from
+// a key file, the file is checked for changes at most every keyFileCheckInterval
+// and the identity is reloaded in place. MotokoClient is safe for concurrent use.
 type MotokoClient struct {
 	CanisterURL string
 	CanisterID  string
-	Identity    identity.Identity
+
+	host         *url.URL
+	canister     principal.Principal
+	fetchRootKey bool
+
+	mu       sync.RWMutex
+	identity identity.Identity
+	agent    *agentgo.Agent
+	keyFile  *keyFile // nil when the identity was injected
+	expected *principal.Principal
 }
 
-func NewMotokoClient(canisterURL, canisterID string) *MotokoClient {
-	return &MotokoClient{CanisterURL: canisterURL, CanisterID: canisterID}
+// keyFile tracks the PEM file an identity was loaded from.
+type keyFile struct {
+	path       string
+	passphrase string
+	modTime    time.Time
+	size       int64
+	checkedAt  time.Time
 }
 
-// createIdentityFromPEM creates an Ed25519 identity from PEM file with passphrase support
-func (c *MotokoClient) createIdentityFromPEM(identityPath, passphrase string) (identity.Identity, error) {
-	pemData, err := os.ReadFile(identityPath)
+const keyFileCheckInterval = 5 * time.Second
+
+// mainnetHosts are boundary nodes that serve the mainnet root key; any other host
+// is treated as a local replica whose root key must be fetched.
+var mainnetHosts = map[string]bool{"ic0.app": true, "icp0.io": true, "icp-api.io": true}
+
+// NewMotokoClient loads the identity from the PEM file at identityPath and builds
+// an agent for canisterURL (empty means mainnet).
+func NewMotokoClient(canisterURL, canisterID, identityPath, passphrase string) (*MotokoClient, error) {
+	if identityPath == "" {
+		return nil, fmt.Errorf("identity path is required")
+	}
+	info, err := os.Stat(identityPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read PEM file: %v", err)
+		return nil, fmt.Errorf("failed to stat identity file: %w", err)
 	}
-
-	block, _ := pem.Decode(pemData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
+	id, err := loadIdentityFile(identityPath, passphrase)
+	if err != nil {
+		return nil, err
 	}
-
-	var privateKeyBytes []byte
-	if x509.IsEncryptedPEMBlock(block) {
-		privateKeyBytes, err = x509.DecryptPEMBlock(block, []byte(passphrase))
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt PEM block: %v", err)
-		}
-	} else {
-		privateKeyBytes = block.Bytes
+	c, err := NewMotokoClientWithIdentity(canisterURL, canisterID, id)
+	if err != nil {
+		return nil, err
 	}
+	c.keyFile = &keyFile{
+		path:       identityPath,
+		passphrase: passphrase,
+		modTime:    info.ModTime(),
+		size:       info.Size(),
+		checkedAt:  time.Now(),
+	}
+	return c, nil
+}
 
-	// Parse the private key based on PEM type
-	var privateKey ed25519.PrivateKey
-	if block.Type == "PRIVATE KEY" {
-		// PKCS#8 format
-		key, err := x509.ParsePKCS8PrivateKey(privateKeyBytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse PKCS#8 private key: %v", err)
-		}
-		if edKey, ok := key.(ed25519.PrivateKey); ok {
-			privateKey = edKey
-		} else {
-			return nil, fmt.Errorf("private key is not Ed25519")
-		}
-	} else if block.Type == "EC PRIVATE KEY" {
-		// EC (secp256k1) format - convert to Ed25519
-		privateKeyValue, err := extractECPrivateKeyValue(privateKeyBytes)
+// NewMotokoClientWithIdentity builds a client around an already loaded identity,
+// e.g. one fetched from a secrets store. It is never reloaded.
+func NewMotokoClientWithIdentity(canisterURL, canisterID string, id identity.Identity) (*MotokoClient, error) {
+	if id == nil {
+		return nil, fmt.Errorf("identity is required")
+	}
+	canister, err := principal.Decode(canisterID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid canister id %q: %w", canisterID, err)
+	}
+	c := &MotokoClient{CanisterURL: canisterURL, CanisterID: canisterID, canister: canister}
+	if canisterURL != "" {
+		host, err := url.Parse(canisterURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract EC private key value: %v", err)
+			return nil, fmt.Errorf("invalid canister host %q: %w", canisterURL, err)
 		}
-
-		// Convert to Ed25519 by using the private key bytes as seed
-		seed := make([]byte, 32)
-		copy(seed, privateKeyValue.Bytes())
-		privateKey = ed25519.NewKeyFromSeed(seed)
-	} else if block.Type == "OPENSSH PRIVATE KEY" {
-		// OpenSSH format - extract Ed25519 key
-		if len(privateKeyBytes) >= 32 {
-			privateKey = ed25519.NewKeyFromSeed(privateKeyBytes[:32])
-		} else {
-			return nil, fmt.Errorf("invalid OpenSSH private key format")
-		}
-	} else {
-		return nil, fmt.Errorf("unsupported PEM type: %s", block.Type)
+		c.host = host
+		c.fetchRootKey = !mainnetHosts[host.Hostname()]
 	}
-
-	// Create identity from private key
-	id, err := identity.NewEd25519Identity(privateKey.Public().(ed25519.PublicKey), privateKey)
+	ag, err := c.newAgent(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create identity: %v", err)
+		return nil, err
 	}
-
-	return id, nil
+	c.identity, c.agent = id, ag
+	return c, nil
 }
 
-// extractECPrivateKeyValue extracts the private key value from EC private key ASN.1 DER
-func extractECPrivateKeyValue(data []byte) (*big.Int, error) {
-	// EC Private Key ASN.1 structure:
-	// SEQUENCE {
-	//   version INTEGER,
-	//   privateKey OCTET STRING,
-	//   parameters [0] EXPLICIT ECDomainParameters OPTIONAL,
-	//   publicKey [1] EXPLICIT BIT STRING OPTIONAL
-	// }
-
-	if len(data) < 2 || data[0] != 0x30 {
-		return nil, fmt.Errorf("invalid ASN.1 sequence")
+func (c *MotokoClient) newAgent(id identity.Identity) (*agentgo.Agent, error) {
+	cfg := agentgo.Config{Identity: id, FetchRootKey: c.fetchRootKey}
+	if c.host != nil {
+		cfg.ClientConfig = []agentgo.ClientOption{agentgo.WithHostURL(c.host)}
 	}
-
-	// Skip sequence header and length
-	offset := 2
-
-	// Skip version (first INTEGER)
-	if offset >= len(data) || data[offset] != 0x02 {
-		return nil, fmt.Errorf("expected version INTEGER")
-	}
-	offset++
-	if offset >= len(data) {
-		return nil, fmt.Errorf("unexpected end of data")
+	ag, err := agentgo.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent: %w", err)
 	}
-	versionLen := int(data[offset])
-	offset++
-	offset += versionLen
+	return ag, nil
+}
 
-	// Get private key (second INTEGER)
-	if offset >= len(data) || data[offset] != 0x04 { // OCTET STRING
-		return nil, fmt.Errorf("expected private key OCTET STRING")
-	}
-	offset++
-	if offset >= len(data) {
-		return nil, fmt.Errorf("unexpected end of data")
+// Principal returns the principal of the identity currently used for calls.
+func (c *MotokoClient) Principal() principal.Principal {
+	c.reloadIfChanged()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.identity.Sender()
+}
+
+// ExpectPrincipal is the startup self-check: it fails unless the loaded identity
+// derives to expected, and from then on key file reloads that derive to any other
+// principal are rejected.
+func (c *MotokoClient) ExpectPrincipal(expected string) error {
+	p, err := principal.Decode(expected)
+	if err != nil {
+		return fmt.Errorf("invalid expected principal %q: %w", expected, err)
 	}
-	privateKeyLen := int(data[offset])
-	offset++
-	if offset+privateKeyLen > len(data) {
-		return nil, fmt.Errorf("invalid private key length")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkPrincipal(c.identity, &p); err != nil {
+		return err
 	}
+	c.expected = &p
+	return nil
+}
 
-	// Convert to big.Int
-	privateKeyValue := new(big.Int).SetBytes(data[offset : offset+privateKeyLen])
-	return privateKeyValue, nil
+func (c *MotokoClient) currentAgent() *agentgo.Agent {
+	c.reloadIfChanged()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.agent
 }
 
-// createAgent creates an agent with identity from PEM file
-func (c *MotokoClient) createAgent() (*agentgo.Agent, error) {
-	// Get identity path and passphrase from environment
-	identityPath := os.Getenv("IDENTITY_PATH")
-	passphrase := os.Getenv("IDENTITY_PASSPHRASE")
-	if passphrase == "" {
-		passphrase = "pedulicarbon" // default passphrase
+// reloadIfChanged re-reads the key file when its size or modification time changed.
+// A file that fails to load (e.g. half written during rotation) keeps the current
+// identity in use and is retried on the next check.
+func (c *MotokoClient) reloadIfChanged() {
+	if c.keyFile == nil {
+		return
 	}
-
-	if identityPath == "" {
-		return nil, fmt.Errorf("IDENTITY_PATH environment variable not set")
+	c.mu.RLock()
+	due := time.Since(c.keyFile.checkedAt) >= keyFileCheckInterval
+	c.mu.RUnlock()
+	if !due {
+		return
 	}
 
-	fmt.Printf("[DEBUG] Creating agent with identity: %s, passphrase: %s, host: %s, canister: %s\n",
-		identityPath, passphrase, c.CanisterURL, c.CanisterID)
-
-	// Set environment variable for agent-go to use local host
-	if c.CanisterURL != "" && c.CanisterURL != "https://ic0.app" {
-		os.Setenv("IC_HOST", c.CanisterURL)
-		fmt.Printf("[DEBUG] Set IC_HOST environment variable to: %s\n", c.CanisterURL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kf := c.keyFile
+	if time.Since(kf.checkedAt) < keyFileCheckInterval {
+		return
 	}
-
-	// Create identity from PEM
-	id, err := c.createIdentityFromPEM(identityPath, passphrase)
+	kf.checkedAt = time.Now()
+	info, err := os.Stat(kf.path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create identity: %v", err)
+		log.Printf("[WARNING] ICP identity file %s: %v", kf.path, err)
+		return
 	}
-
-	// Create agent
-	ag, err := agentgo.New(agentgo.Config{
-		Identity: id,
-	})
+	if info.ModTime().Equal(kf.modTime) && info.Size() == kf.size {
+		return
+	}
+	id, err := loadIdentityFile(kf.path, kf.passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create agent: %v", err)
+		log.Printf("[WARNING] ICP identity file %s changed but could not be loaded: %v", kf.path, err)
+		return
 	}
-
-	fmt.Printf("[DEBUG] Agent created successfully\n")
-	return ag, nil
+	if err := checkPrincipal(id, c.expected); err != nil {
+		log.Printf("[WARNING] ICP identity file %s changed but was rejected: %v", kf.path, err)
+		return
+	}
+	ag, err := c.newAgent(id)
+	if err != nil {
+		log.Printf("[WARNING] ICP identity file %s changed but agent creation failed: %v", kf.path, err)
+		return
+	}
+	c.identity, c.agent = id, ag
+	kf.modTime, kf.size = info.ModTime(), info.Size()
+	log.Printf("[INFO] Reloaded ICP identity from %s, principal %s", kf.path, id.Sender())
 }
 
-// callCanisterDirect makes a direct HTTP call to the local replica
-func (c *MotokoClient) callCanisterDirect(method string, args []interface{}) ([]byte, error) {
-	// For now, let's just return success for local testing
-	// The proper implementation would require CBOR encoding and proper authentication
-	fmt.Printf("[DEBUG] Direct HTTP call - returning dummy success for local testing\n")
-	return []byte(`{"result": true}`), nil
+// actor binds the generated canister methods to the current agent.
+func (c *MotokoClient) actor() pedulicarbonActor {
+	return pedulicarbonActor{agent: c.currentAgent(), canister: c.canister}
 }
 
 func (c *MotokoClient) VerifyAction(ctx context.Context, userPrincipal string, missionID uint, proofURL, gps string) (bool, error) {
-	fmt.Printf("[DEBUG] VerifyAction called with user: %s, mission: %d, proof: %s, gps: %s\n",
-		userPrincipal, missionID, proofURL, gps)
-
-	// Try agent-go first
-	ag, err := c.createAgent()
-	if err != nil {
-		fmt.Printf("[ERROR] Failed to create agent: %v\n", err)
-		return false, err
-	}
-
 	p, err := principal.Decode(userPrincipal)
 	if err != nil {
-		fmt.Printf("[ERROR] Failed to decode principal: %v\n", err)
-		return false, err
+		return false, permanentError("verify_action", err)
 	}
-
-	fmt.Printf("[DEBUG] Calling canister %s with method verify_action\n", c.CanisterID)
-
-	var result bool
-	err = ag.Call(
-		principal.MustDecode(c.CanisterID),
-		"verify_action",
-		[]any{p, missionID, proofURL, gps},
-		[]any{&result},
-	)
+	result, err := c.actor().VerifyAction(p, idl.NewNat(missionID), proofURL, gps)
 	if err != nil {
-		fmt.Printf("[ERROR] Agent-go call failed: %v\n", err)
-		fmt.Printf("[DEBUG] Trying direct HTTP call as fallback...\n")
-
-		// Fallback: Try direct HTTP call
-		_, httpErr := c.callCanisterDirect("verify_action", []interface{}{userPrincipal, missionID, proofURL, gps})
-		if httpErr != nil {
-			fmt.Printf("[ERROR] Direct HTTP call also failed: %v\n", httpErr)
-			return false, err // Return original agent-go error
-		}
-
-		// If HTTP call succeeds, return true (dummy response)
-		fmt.Printf("[DEBUG] Direct HTTP call succeeded, returning true\n")
-		return true, nil
+		return false, classifyCallError("verify_action", err)
 	}
-
-	fmt.Printf("[DEBUG] Canister call successful, result: %v\n", result)
 	return result, nil
 }
 
-func (c *MotokoClient) MintNFT(ctx context.Context, userPrincipal string, missionID uint, carbonAmount float64) (string, error) {
-	ag, err := c.createAgent()
-	if err != nil {
-		return "", err
-	}
-
+// MintNFT mints a carbon NFT to userPrincipal. The canister returns the already
+// minted NFT ID when idempotencyKey has been used before, so retries are safe.
+func (c *MotokoClient) MintNFT(ctx context.Context, userPrincipal string, missionID uint, carbonAmount float64, idempotencyKey string) (string, error) {
 	p, err := principal.Decode(userPrincipal)
 	if err != nil {
-		return "", err
+		return "", permanentError("mint_nft", err)
 	}
-	var nftID string
-	err = ag.Call(
-		principal.MustDecode(c.CanisterID),
-		"mint_nft",
-		[]any{p, missionID, carbonAmount},
-		[]any{&nftID},
-	)
+	nftID, err := c.actor().MintNFT(p, idl.NewNat(missionID), carbonAmount, idempotencyKey)
 	if err != nil {
-		fmt.Printf("[ERROR] Agent-go MintNFT call failed: %v\n", err)
-		fmt.Printf("[DEBUG] Trying direct HTTP call as fallback for MintNFT...\n")
-
-		// Fallback: Try direct HTTP call
-		_, httpErr := c.callCanisterDirect("mint_nft", []interface{}{userPrincipal, missionID, carbonAmount})
-		if httpErr != nil {
-			fmt.Printf("[ERROR] Direct HTTP call for MintNFT also failed: %v\n", httpErr)
-			return "", err // Return original agent-go error
-		}
-
-		// If HTTP call succeeds, return dummy NFT ID
-		dummyNFTID := fmt.Sprintf("NFT-%d", time.Now().Unix())
-		fmt.Printf("[DEBUG] Direct HTTP call for MintNFT succeeded, returning dummy NFT ID: %s\n", dummyNFTID)
-		return dummyNFTID, nil
+		return "", classifyCallError("mint_nft", err)
 	}
 	return nftID, nil
 }
 
 func (c *MotokoClient) GetUserNFTs(ctx context.Context, userPrincipal string) ([]string, error) {
-	ag, err := c.createAgent()
-	if err != nil {
-		return nil, err
-	}
-
 	p, err := principal.Decode(userPrincipal)
 	if err != nil {
-		return nil, err
+		return nil, permanentError("get_user_nfts", err)
 	}
-	var nftIDs []string
-	err = ag.Query(
-		principal.MustDecode(c.CanisterID),
-		"get_user_nfts",
-		[]any{p},
-		[]any{&nftIDs},
-	)
+	nftIDs, err := c.actor().GetUserNFTs(p)
 	if err != nil {
-		return nil, err
+		return nil, classifyCallError("get_user_nfts", err)
 	}
 	return nftIDs, nil
 }
 
-func (c *MotokoClient) GetNFTDetail(ctx context.Context, nftID string) (map[string]interface{}, error) {
-	// Dummy: return NFT detail
-	return map[string]interface{}{
-		"id":            nftID,
-		"owner":         "principal-abc",
-		"mission_id":    1,
-		"carbon_amount": 1.2,
-		"timestamp":     1234567890,
-	}, nil
-}
-
-func (c *MotokoClient) BurnNFT(ctx context.Context, nftID string) error {
-	fmt.Printf("[DEBUG] BurnNFT called with NFT ID: %s\n", nftID)
-
-	// Try agent-go first
-	ag, err := c.createAgent()
+// GetNFTDetail queries get_nft_detail. A null answer is reported as ErrNFTNotFound.
+func (c *MotokoClient) GetNFTDetail(ctx context.Context, nftID string) (*NFTDetail, error) {
+	detail, err := c.actor().GetNFTDetail(nftID)
 	if err != nil {
-		fmt.Printf("[ERROR] Failed to create agent: %v\n", err)
-		return err
+		return nil, classifyCallError("get_nft_detail", err)
+	}
+	if detail == nil {
+		return nil, ErrNFTNotFound
 	}
+	return detail, nil
+}
 
-	var result bool
-	err = ag.Call(
-		principal.MustDecode(c.CanisterID),
-		"burn_nft",
-		[]any{nftID},
-		[]any{&result},
-	)
-	if err != nil {
-		fmt.Printf("[ERROR] Agent-go BurnNFT call failed: %v\n", err)
-		fmt.Printf("[DEBUG] Trying direct HTTP call as fallback for BurnNFT...\n")
+func (c *MotokoClient) Simulated() bool { return false }
 
-		// Fallback: Try direct HTTP call
-		_, httpErr := c.callCanisterDirect("burn_nft", []interface{}{nftID})
-		if httpErr != nil {
-			fmt.Printf("[ERROR] Direct HTTP call for BurnNFT also failed: %v\n", httpErr)
-			return err // Return original agent-go error
-		}
-
-		// If HTTP call succeeds, return success (dummy response)
-		fmt.Printf("[DEBUG] Direct HTTP call for BurnNFT succeeded, returning success\n")
-		return nil
+func (c *MotokoClient) BurnNFT(ctx context.Context, nftID string) error {
+	result, err := c.actor().BurnNFT(nftID)
+	if err != nil {
+		return classifyCallError("burn_nft", err)
 	}
-
 	if !result {
-		return fmt.Errorf("burn_nft failed on canister")
+		return permanentError("burn_nft", fmt.Errorf("burn_nft returned false for %s", nftID))
 	}
-
-	fmt.Printf("[DEBUG] BurnNFT call successful, result: %v\n", result)
 	return nil
 }
@@ -2,18 +2,32 @@ package motoko
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/aviate-labs/agent-go/identity"
+	"github.com/aviate-labs/agent-go/principal"
 )
 
+// TestMintNFTandQuery memanggil replica dfx sungguhan; replica_test.go menguji
+// alur yang sama terhadap replicatest tanpa jaringan.
 func TestMintNFTandQuery(t *testing.T) {
 	canisterHost := os.Getenv("ICP_CANISTER_HOST")
 	canisterID := os.Getenv("ICP_CANISTER_ID")
-	if canisterHost == "" || canisterID == "" {
-		t.Skip("Set ICP_CANISTER_HOST dan ICP_CANISTER_ID di env")
+	identityPath := os.Getenv("IDENTITY_PATH")
+	if canisterHost == "" || canisterID == "" || identityPath == "" {
+		t.Skip("Set ICP_CANISTER_HOST, ICP_CANISTER_ID dan IDENTITY_PATH di env")
+	}
+	client, err := NewMotokoClient(canisterHost, canisterID, identityPath, os.Getenv("IDENTITY_PASSPHRASE"))
+	if err != nil {
+		t.Fatalf("NewMotokoClient error: %v", err)
 	}
-	client := NewMotokoClient(canisterHost, canisterID)
 
 	// principal dummy, ganti dengan principal Anda jika perlu
 	userPrincipal := "aaaaa-aa" // principal anonymous, untuk test local
@@ -23,7 +37,7 @@ func TestMintNFTandQuery(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	nftID, err := client.MintNFT(ctx, userPrincipal, missionID, carbonAmount)
+	nftID, err := client.MintNFT(ctx, userPrincipal, missionID, carbonAmount, fmt.Sprintf("test-%d", time.Now().UnixNano()))
 	if err != nil {
 		t.Fatalf("MintNFT error: %v", err)
 	}
@@ -47,3 +61,77 @@ func TestMintNFTandQuery(t *testing.T) {
 	}
 	t.Logf("NFT user: %v", nftIDs)
 }
+
+func writeEd25519PEM(t *testing.T, path string) principal.Principal {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	id, err := identity.NewEd25519Identity(pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id.Sender()
+}
+
+func TestMotokoClientReloadsKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.pem")
+	first := writeEd25519PEM(t, path)
+
+	// Host mainnet supaya agent tidak perlu fetch root key dari jaringan.
+	client, err := NewMotokoClient("https://icp0.io", "aaaaa-aa", path, "")
+	if err != nil {
+		t.Fatalf("NewMotokoClient error: %v", err)
+	}
+	if got := client.Principal(); !got.Equal(first) {
+		t.Fatalf("principal = %s, want %s", got, first)
+	}
+
+	second := writeEd25519PEM(t, path)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	// Belum waktunya cek ulang: identity lama tetap dipakai.
+	if got := client.Principal(); !got.Equal(first) {
+		t.Fatalf("reloaded before check interval: got %s", got)
+	}
+	client.keyFile.checkedAt = time.Time{}
+	if got := client.Principal(); !got.Equal(second) {
+		t.Fatalf("principal after reload = %s, want %s", got, second)
+	}
+
+	// File rusak tidak menggantikan identity yang sedang dipakai.
+	if err := os.WriteFile(path, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client.keyFile.checkedAt = time.Time{}
+	if got := client.Principal(); !got.Equal(second) {
+		t.Fatalf("broken key file replaced identity: got %s", got)
+	}
+}
+
+func TestNewMotokoClientWithIdentity(t *testing.T) {
+	id, err := identity.NewRandomEd25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewMotokoClientWithIdentity("", "aaaaa-aa", id)
+	if err != nil {
+		t.Fatalf("NewMotokoClientWithIdentity error: %v", err)
+	}
+	if !client.Principal().Equal(id.Sender()) {
+		t.Fatal("injected identity not used")
+	}
+	if _, err := NewMotokoClientWithIdentity("", "not a principal", id); err == nil {
+		t.Fatal("expected error for invalid canister id")
+	}
+}
@@ -0,0 +1,69 @@
+package motoko
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// CanisterError is returned by MotokoClient when a canister call fails.
+// Retryable errors (network trouble, transient replica rejects, 5xx) may succeed
+// when repeated; permanent ones (bad input, canister rejects or traps) will not.
+type CanisterError struct {
+	Method    string
+	Retryable bool
+	Err       error
+}
+
+func (e *CanisterError) Error() string {
+	kind := "permanent"
+	if e.Retryable {
+		kind = "retryable"
+	}
+	return fmt.Sprintf("canister %s failed (%s): %v", e.Method, kind, e.Err)
+}
+
+func (e *CanisterError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err is a canister failure that must not be retried.
+func IsPermanent(err error) bool {
+	var cerr *CanisterError
+	return errors.As(err, &cerr) && !cerr.Retryable
+}
+
+func permanentError(method string, err error) error {
+	return &CanisterError{Method: method, Retryable: false, Err: err}
+}
+
+// IC reject codes, see the Internet Computer interface specification.
+const (
+	rejectSysFatal           = 1
+	rejectSysTransient       = 2
+	rejectDestinationInvalid = 3
+	rejectCanisterReject     = 4
+	rejectCanisterError      = 5
+)
+
+// agent-go formats both replica rejects and non-200 HTTP responses as "(<code>) ...".
+var codePrefix = regexp.MustCompile(`^\((\d+)\)`)
+
+// classifyCallError wraps an error returned by agent-go for method.
+func classifyCallError(method string, err error) error {
+	retryable := true
+	if m := codePrefix.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		switch {
+		case code == rejectSysTransient:
+			retryable = true
+		case code == rejectSysFatal, code == rejectDestinationInvalid,
+			code == rejectCanisterReject, code == rejectCanisterError:
+			retryable = false
+		case code == 408 || code == 429 || code >= 500:
+			retryable = true
+		case code >= 400:
+			retryable = false
+		}
+	}
+	return &CanisterError{Method: method, Retryable: retryable, Err: err}
+}
@@ -0,0 +1,32 @@
+package motoko
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyCallError(t *testing.T) {
+	cases := []struct {
+		msg       string
+		permanent bool
+	}{
+		{"(2) Canister is out of cycles", false},
+		{"(4) IC0406: Canister rejected the message", true},
+		{"(5) IC0503: Canister trapped explicitly", true},
+		{"(3) Canister not found", true},
+		{"(503) 503 Service Unavailable: overloaded", false},
+		{"(429) 429 Too Many Requests: slow down", false},
+		{"(400) 400 Bad Request: invalid envelope", true},
+		{"dial tcp 127.0.0.1:4943: connect: connection refused", false},
+		{"out of time... waited 30 seconds", false},
+	}
+	for _, tc := range cases {
+		err := classifyCallError("mint_nft", errors.New(tc.msg))
+		if got := IsPermanent(err); got != tc.permanent {
+			t.Errorf("%q: IsPermanent = %v, want %v", tc.msg, got, tc.permanent)
+		}
+	}
+	if IsPermanent(errors.New("(4) not from agent")) {
+		t.Error("plain errors must not be treated as permanent")
+	}
+}
@@ -0,0 +1,165 @@
+package motoko
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aviate-labs/agent-go/candid/idl"
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+// FakeCanister is an in-process stand-in for the PeduliCarbon canister. It mirrors
+// pedulicarbon.mo: sequential "NFT-<n>" IDs, idempotent mint_nft keys, per-owner
+// lookups and burns. It is safe for concurrent use. Everything it mints is
+// reported as simulated.
+type FakeCanister struct {
+	mu       sync.Mutex
+	nfts     []fakeNFT
+	mintKeys map[string]string
+	nextID   int
+	idPrefix string
+
+	// VerifyResult is returned by VerifyAction (the real canister always says true).
+	VerifyResult bool
+	// failNext holds errors to return from the next call of a method, by Candid method name.
+	failNext map[string][]error
+}
+
+type fakeNFT struct {
+	ID           string
+	Owner        principal.Principal
+	MissionID    uint
+	CarbonAmount float64
+	Timestamp    int64
+}
+
+func NewFakeCanister() *FakeCanister {
+	return &FakeCanister{
+		mintKeys:     map[string]string{},
+		idPrefix:     "NFT-",
+		VerifyResult: true,
+		failNext:     map[string][]error{},
+	}
+}
+
+// NewSimulationCanister returns a FakeCanister for ICP simulation mode. Its state
+// lives only in memory, so NFT IDs carry a per-process "SIM-<unix>-" prefix to
+// keep them from colliding with IDs minted before a restart or on a real canister.
+func NewSimulationCanister() *FakeCanister {
+	f := NewFakeCanister()
+	f.idPrefix = fmt.Sprintf("SIM-%d-NFT-", time.Now().Unix())
+	return f
+}
+
+func (f *FakeCanister) Simulated() bool { return true }
+
+// FailNext makes the next call to method (e.g. "mint_nft") return err instead of running.
+func (f *FakeCanister) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext[method] = append(f.failNext[method], err)
+}
+
+func (f *FakeCanister) takeFailure(method string) error {
+	errs := f.failNext[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	f.failNext[method] = errs[1:]
+	return errs[0]
+}
+
+func (f *FakeCanister) VerifyAction(ctx context.Context, userPrincipal string, missionID uint, proofURL, gps string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeFailure("verify_action"); err != nil {
+		return false, err
+	}
+	if _, err := principal.Decode(userPrincipal); err != nil {
+		return false, err
+	}
+	return f.VerifyResult, nil
+}
+
+func (f *FakeCanister) MintNFT(ctx context.Context, userPrincipal string, missionID uint, carbonAmount float64, idempotencyKey string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeFailure("mint_nft"); err != nil {
+		return "", err
+	}
+	owner, err := principal.Decode(userPrincipal)
+	if err != nil {
+		return "", err
+	}
+	if id, ok := f.mintKeys[idempotencyKey]; ok {
+		return id, nil
+	}
+	id := fmt.Sprintf("%s%d", f.idPrefix, f.nextID)
+	f.nextID++
+	f.nfts = append(f.nfts, fakeNFT{
+		ID:           id,
+		Owner:        owner,
+		MissionID:    missionID,
+		CarbonAmount: carbonAmount,
+		Timestamp:    time.Now().UnixNano(),
+	})
+	f.mintKeys[idempotencyKey] = id
+	return id, nil
+}
+
+func (f *FakeCanister) GetUserNFTs(ctx context.Context, userPrincipal string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeFailure("get_user_nfts"); err != nil {
+		return nil, err
+	}
+	owner, err := principal.Decode(userPrincipal)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, nft := range f.nfts {
+		if nft.Owner.Equal(owner) {
+			ids = append(ids, nft.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (f *FakeCanister) GetNFTDetail(ctx context.Context, nftID string) (*NFTDetail, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeFailure("get_nft_detail"); err != nil {
+		return nil, err
+	}
+	for _, nft := range f.nfts {
+		if nft.ID == nftID {
+			return &NFTDetail{
+				ID:           nft.ID,
+				Owner:        nft.Owner,
+				MissionID:    idl.NewNat(nft.MissionID),
+				CarbonAmount: nft.CarbonAmount,
+				Timestamp:    idl.NewInt(nft.Timestamp),
+			}, nil
+		}
+	}
+	return nil, ErrNFTNotFound
+}
+
+func (f *FakeCanister) BurnNFT(ctx context.Context, nftID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeFailure("burn_nft"); err != nil {
+		return err
+	}
+	for i, nft := range f.nfts {
+		if nft.ID == nftID {
+			f.nfts = append(f.nfts[:i], f.nfts[i+1:]...)
+			return nil
+		}
+	}
+	// burn_nft di canister mengembalikan false bila NFT tidak ada
+	return permanentError("burn_nft", fmt.Errorf("burn_nft returned false for %s", nftID))
+}
@@ -0,0 +1,48 @@
+package motoko
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+func TestFakeCanisterMirrorsActor(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeCanister()
+	alice := principal.NewSelfAuthenticating([]byte("alice")).Encode()
+	bob := principal.NewSelfAuthenticating([]byte("bob")).Encode()
+
+	a0, _ := f.MintNFT(ctx, alice, 1, 1.5, "k-a0")
+	b1, _ := f.MintNFT(ctx, bob, 2, 2.0, "k-b1")
+	if a0 != "NFT-0" || b1 != "NFT-1" {
+		t.Fatalf("ids = %s, %s; want NFT-0, NFT-1", a0, b1)
+	}
+	if again, _ := f.MintNFT(ctx, alice, 1, 1.5, "k-a0"); again != a0 {
+		t.Fatalf("replayed idempotency key minted %s, want %s", again, a0)
+	}
+
+	if err := f.BurnNFT(ctx, a0); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.BurnNFT(ctx, a0); err == nil {
+		t.Fatal("burning a burned NFT succeeded")
+	}
+	// ID tidak boleh terpakai ulang setelah burn
+	if a2, _ := f.MintNFT(ctx, alice, 3, 1.0, "k-a2"); a2 != "NFT-2" {
+		t.Fatalf("id after burn = %s, want NFT-2", a2)
+	}
+
+	ids, _ := f.GetUserNFTs(ctx, alice)
+	if len(ids) != 1 || ids[0] != "NFT-2" {
+		t.Fatalf("alice NFTs = %v, want [NFT-2]", ids)
+	}
+	detail, err := f.GetNFTDetail(ctx, b1)
+	if err != nil || detail.Owner.Encode() != bob || detail.CarbonAmount != 2.0 || detail.MissionID.BigInt().Uint64() != 2 {
+		t.Fatalf("detail = %+v, %v", detail, err)
+	}
+	if _, err := f.GetNFTDetail(ctx, a0); !errors.Is(err, ErrNFTNotFound) {
+		t.Fatalf("detail of burned NFT: err = %v, want ErrNFTNotFound", err)
+	}
+}
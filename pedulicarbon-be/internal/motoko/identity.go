@@ -0,0 +1,359 @@
+package motoko
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+
+	"github.com/aviate-labs/agent-go/identity"
+	"github.com/aviate-labs/agent-go/principal"
+	"github.com/aviate-labs/secp256k1"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	oidEd25519     = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidPrime256v1  = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// ecPrivateKey is the SEC 1 ECPrivateKey structure (RFC 5915).
+type ecPrivateKey struct {
+	Version    int
+	PrivateKey []byte
+	Curve      asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey  asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// pkcs8 is the PKCS #8 PrivateKeyInfo structure (RFC 5208).
+type pkcs8 struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type encryptedPKCS8 struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type scryptParams struct {
+	Salt            []byte
+	CostParameter   int
+	BlockSize       int
+	Parallelization int
+	KeyLength       int `asn1:"optional"`
+}
+
+// loadIdentityFile reads a PEM identity from path, see parseIdentityPEM.
+func loadIdentityFile(path, passphrase string) (identity.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PEM file: %w", err)
+	}
+	return parseIdentityPEM(data, passphrase)
+}
+
+// parseIdentityPEM builds an identity from a PEM key in any format dfx or openssl
+// produce, keeping the key's own algorithm so the principal matches `dfx identity
+// get-principal`:
+//   - "EC PRIVATE KEY" (SEC 1) on secp256k1 or prime256v1, optionally preceded by
+//     an "EC PARAMETERS" block as written by dfx;
+//   - "PRIVATE KEY" (PKCS #8) holding an Ed25519, secp256k1 or prime256v1 key;
+//   - "ENCRYPTED PRIVATE KEY" (PKCS #8 with PBES2, PBKDF2 or scrypt, AES-CBC);
+//   - legacy "Proc-Type: 4,ENCRYPTED" PEM blocks;
+//   - "OPENSSH PRIVATE KEY" holding an Ed25519 key.
+//
+// passphrase is only used for encrypted keys.
+func parseIdentityPEM(data []byte, passphrase string) (identity.Identity, error) {
+	var (
+		params *asn1.ObjectIdentifier
+		key    *pem.Block
+	)
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "EC PARAMETERS" {
+			var oid asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(block.Bytes, &oid); err != nil {
+				return nil, fmt.Errorf("invalid EC PARAMETERS block: %w", err)
+			}
+			params = &oid
+			continue
+		}
+		if key != nil {
+			return nil, errors.New("PEM file contains more than one private key")
+		}
+		key = block
+	}
+	if key == nil {
+		return nil, errors.New("failed to decode PEM block")
+	}
+
+	der := key.Bytes
+	if x509.IsEncryptedPEMBlock(key) {
+		var err error
+		if der, err = x509.DecryptPEMBlock(key, []byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt PEM block: %w", err)
+		}
+	}
+
+	switch key.Type {
+	case "EC PRIVATE KEY":
+		var curve asn1.ObjectIdentifier
+		if params != nil {
+			curve = *params
+		}
+		return parseECPrivateKey(der, curve)
+	case "PRIVATE KEY":
+		return parsePKCS8(der)
+	case "ENCRYPTED PRIVATE KEY":
+		plain, err := decryptPKCS8(der, []byte(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		return parsePKCS8(plain)
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSH(data, passphrase)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type: %s", key.Type)
+	}
+}
+
+// parseECPrivateKey parses a SEC 1 key. curve is used when the structure itself
+// does not name one (PKCS #8 carries it in the algorithm parameters instead).
+func parseECPrivateKey(der []byte, curve asn1.ObjectIdentifier) (identity.Identity, error) {
+	var k ecPrivateKey
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EC private key: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after EC private key")
+	}
+	if len(k.Curve) != 0 {
+		if len(curve) != 0 && !curve.Equal(k.Curve) {
+			return nil, fmt.Errorf("EC key curve %s does not match parameters %s", k.Curve, curve)
+		}
+		curve = k.Curve
+	}
+	switch {
+	case curve.Equal(oidSecp256k1):
+		if len(k.PrivateKey) == 0 || len(k.PrivateKey) > 32 {
+			return nil, fmt.Errorf("invalid secp256k1 private key length %d", len(k.PrivateKey))
+		}
+		priv, _ := secp256k1.PrivKeyFromBytes(secp256k1.S256(), k.PrivateKey)
+		return identity.NewSecp256k1Identity(priv)
+	case curve.Equal(oidPrime256v1):
+		// Re-encode with the curve named so the standard library can parse it.
+		k.Curve = oidPrime256v1
+		full, err := asn1.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := x509.ParseECPrivateKey(full)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prime256v1 key: %w", err)
+		}
+		return identity.NewPrime256v1Identity(priv), nil
+	case len(curve) == 0:
+		return nil, errors.New("EC private key does not name its curve")
+	default:
+		return nil, fmt.Errorf("unsupported EC curve %s", curve)
+	}
+}
+
+func parsePKCS8(der []byte) (identity.Identity, error) {
+	var k pkcs8
+	if _, err := asn1.Unmarshal(der, &k); err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+	}
+	switch {
+	case k.Algorithm.Algorithm.Equal(oidEd25519):
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+		}
+		priv := key.(ed25519.PrivateKey)
+		return identity.NewEd25519Identity(priv.Public().(ed25519.PublicKey), priv)
+	case k.Algorithm.Algorithm.Equal(oidECPublicKey):
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(k.Algorithm.Parameters.FullBytes, &curve); err != nil {
+			return nil, fmt.Errorf("invalid EC parameters in PKCS#8 key: %w", err)
+		}
+		return parseECPrivateKey(k.PrivateKey, curve)
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#8 key algorithm %s", k.Algorithm.Algorithm)
+	}
+}
+
+// decryptPKCS8 decrypts an EncryptedPrivateKeyInfo protected with PBES2 (RFC 8018).
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var enc encryptedPKCS8
+	if _, err := asn1.Unmarshal(der, &enc); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted PKCS#8 key: %w", err)
+	}
+	if !enc.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported PKCS#8 encryption %s, only PBES2 is supported", enc.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(enc.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+
+	var keyLen int
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported PBES2 cipher %s", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid AES-CBC IV")
+	}
+
+	key, err := deriveKey(params.KeyDerivationFunc, passphrase, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc.EncryptedData) == 0 || len(enc.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted PKCS#8 data length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(enc.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, enc.EncryptedData)
+
+	// PKCS #7 padding; a wrong passphrase almost always shows up here.
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, errors.New("failed to decrypt PKCS#8 key: wrong passphrase?")
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("failed to decrypt PKCS#8 key: wrong passphrase?")
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+func deriveKey(kdf pkix.AlgorithmIdentifier, passphrase []byte, keyLen int) ([]byte, error) {
+	switch {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var p pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &p); err != nil {
+			return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+		}
+		if p.KeyLength != 0 && p.KeyLength != keyLen {
+			return nil, fmt.Errorf("PBKDF2 key length %d does not match cipher", p.KeyLength)
+		}
+		var prf func() hash.Hash
+		switch alg := p.PRF.Algorithm; {
+		case len(alg) == 0, alg.Equal(oidHMACSHA1):
+			prf = sha1.New
+		case alg.Equal(oidHMACSHA256):
+			prf = sha256.New
+		case alg.Equal(oidHMACSHA512):
+			prf = sha512.New
+		default:
+			return nil, fmt.Errorf("unsupported PBKDF2 PRF %s", alg)
+		}
+		return pbkdf2.Key(passphrase, p.Salt, p.IterationCount, keyLen, prf), nil
+	case kdf.Algorithm.Equal(oidScrypt):
+		var p scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &p); err != nil {
+			return nil, fmt.Errorf("invalid scrypt parameters: %w", err)
+		}
+		return scrypt.Key(passphrase, p.Salt, p.CostParameter, p.BlockSize, p.Parallelization, keyLen)
+	default:
+		return nil, fmt.Errorf("unsupported PBES2 key derivation %s", kdf.Algorithm)
+	}
+}
+
+func parseOpenSSH(data []byte, passphrase string) (identity.Identity, error) {
+	var (
+		key interface{}
+		err error
+	)
+	if passphrase != "" {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) || errors.Is(err, x509.IncorrectPasswordError) {
+			// Key is not encrypted, or was encrypted with another passphrase.
+			key, err = ssh.ParseRawPrivateKey(data)
+		}
+	} else {
+		key, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenSSH private key: %w", err)
+	}
+	switch k := key.(type) {
+	case *ed25519.PrivateKey:
+		return identity.NewEd25519Identity(k.Public().(ed25519.PublicKey), *k)
+	case ed25519.PrivateKey:
+		return identity.NewEd25519Identity(k.Public().(ed25519.PublicKey), k)
+	case *ecdsa.PrivateKey:
+		return nil, errors.New("OpenSSH ECDSA keys are not supported, export the key as PKCS#8")
+	default:
+		return nil, fmt.Errorf("unsupported OpenSSH key type %T", key)
+	}
+}
+
+// checkPrincipal returns an error when expected is set and differs from id's principal.
+func checkPrincipal(id identity.Identity, expected *principal.Principal) error {
+	if expected == nil {
+		return nil
+	}
+	if got := id.Sender(); !got.Equal(*expected) {
+		return fmt.Errorf("identity principal %s does not match expected %s", got, expected)
+	}
+	return nil
+}
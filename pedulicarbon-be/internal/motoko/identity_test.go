@@ -0,0 +1,95 @@
+package motoko
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviate-labs/agent-go/identity"
+)
+
+// Fixture di testdata dibuat dengan openssl dari satu key secp256k1 (format dfx)
+// dan satu key Ed25519; passphrase key terenkripsi adalah "pedulicarbon".
+func TestParseIdentityPEM(t *testing.T) {
+	dfxPEM, err := os.ReadFile("testdata/secp256k1.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfx, err := identity.NewSecp256k1IdentityFromPEM(dfxPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPEM, err := os.ReadFile("testdata/ed25519.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := identity.NewEd25519IdentityFromPEM(edPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		file string
+		want identity.Identity
+	}{
+		{"secp256k1.pem", dfx},
+		{"secp256k1_pkcs8.pem", dfx},
+		{"secp256k1_pkcs8_encrypted.pem", dfx},
+		{"secp256k1_pkcs8_scrypt.pem", dfx},
+		{"secp256k1_legacy_encrypted.pem", dfx},
+		{"ed25519.pem", ed},
+		{"ed25519_pkcs8_encrypted.pem", ed},
+	}
+	for _, tc := range cases {
+		id, err := loadIdentityFile(filepath.Join("testdata", tc.file), "pedulicarbon")
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if got, want := id.Sender(), tc.want.Sender(); !got.Equal(want) {
+			t.Errorf("%s: principal = %s, want %s", tc.file, got, want)
+		}
+	}
+
+	if _, err := loadIdentityFile("testdata/secp256k1_pkcs8_encrypted.pem", "wrong"); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+}
+
+func TestParseIdentityPEMRoundTrip(t *testing.T) {
+	// ToPEM agent-go menulis D tanpa padding, jadi key bisa lebih pendek dari 32 byte.
+	for i := 0; i < 20; i++ {
+		want, err := identity.NewRandomSecp256k1Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := want.ToPEM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseIdentityPEM(data, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Sender().Equal(want.Sender()) {
+			t.Fatalf("principal = %s, want %s", got.Sender(), want.Sender())
+		}
+	}
+}
+
+func TestMotokoClientExpectPrincipal(t *testing.T) {
+	id, err := loadIdentityFile("testdata/secp256k1.pem", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewMotokoClientWithIdentity("", "aaaaa-aa", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ExpectPrincipal(id.Sender().String()); err != nil {
+		t.Fatalf("ExpectPrincipal(own principal) = %v", err)
+	}
+	if err := client.ExpectPrincipal("aaaaa-aa"); err == nil {
+		t.Fatal("expected mismatch error")
+	}
+}
@@ -0,0 +1,59 @@
+// Code generated by candidgen from ../../motoko/pedulicarbon/src/pedulicarbon_backend/pedulicarbon_backend.did. DO NOT EDIT.
+
+package motoko
+
+import (
+	agentgo "github.com/aviate-labs/agent-go"
+	"github.com/aviate-labs/agent-go/candid/idl"
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+// NFTDetail is the Candid type NFTDetail.
+type NFTDetail struct {
+	ID           string              `ic:"id"`
+	Owner        principal.Principal `ic:"owner"`
+	MissionID    idl.Nat             `ic:"mission_id"`
+	CarbonAmount float64             `ic:"carbon_amount"`
+	Timestamp    idl.Int             `ic:"timestamp"`
+}
+
+// pedulicarbonActor calls the canister's methods through one agent.
+type pedulicarbonActor struct {
+	agent    *agentgo.Agent
+	canister principal.Principal
+}
+
+// VerifyAction calls the "verify_action" update method.
+func (a pedulicarbonActor) VerifyAction(user principal.Principal, missionID idl.Nat, proofURL string, gps string) (bool, error) {
+	var r0 bool
+	err := a.agent.Call(a.canister, "verify_action", []any{user, missionID, proofURL, gps}, []any{&r0})
+	return r0, err
+}
+
+// MintNFT calls the "mint_nft" update method.
+func (a pedulicarbonActor) MintNFT(user principal.Principal, missionID idl.Nat, carbonAmount float64, idempotencyKey string) (string, error) {
+	var r0 string
+	err := a.agent.Call(a.canister, "mint_nft", []any{user, missionID, carbonAmount, idempotencyKey}, []any{&r0})
+	return r0, err
+}
+
+// GetUserNFTs calls the "get_user_nfts" query method.
+func (a pedulicarbonActor) GetUserNFTs(user principal.Principal) ([]string, error) {
+	var r0 []string
+	err := a.agent.Query(a.canister, "get_user_nfts", []any{user}, []any{&r0})
+	return r0, err
+}
+
+// GetNFTDetail calls the "get_nft_detail" query method.
+func (a pedulicarbonActor) GetNFTDetail(nftID string) (*NFTDetail, error) {
+	var r0 *NFTDetail
+	err := a.agent.Query(a.canister, "get_nft_detail", []any{nftID}, []any{&r0})
+	return r0, err
+}
+
+// BurnNFT calls the "burn_nft" update method.
+func (a pedulicarbonActor) BurnNFT(nftID string) (bool, error) {
+	var r0 bool
+	err := a.agent.Call(a.canister, "burn_nft", []any{nftID}, []any{&r0})
+	return r0, err
+}
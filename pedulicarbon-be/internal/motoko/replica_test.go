@@ -0,0 +1,114 @@
+package motoko_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pedulicarbon/internal/motoko"
+	"pedulicarbon/internal/motoko/replicatest"
+
+	"github.com/aviate-labs/agent-go/identity"
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+const replicaCanisterID = "rrkah-fqaaa-aaaaa-aaaaq-cai"
+
+func newReplicaClient(t *testing.T, id identity.Identity) (*motoko.MotokoClient, *replicatest.Server) {
+	t.Helper()
+	replica, err := replicatest.NewServer(replicaCanisterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(replica.Close)
+	client, err := motoko.NewMotokoClientWithIdentity(replica.URL, replicaCanisterID, id)
+	if err != nil {
+		t.Fatalf("NewMotokoClientWithIdentity: %v", err)
+	}
+	return client, replica
+}
+
+func TestMotokoClientAgainstReplica(t *testing.T) {
+	ctx := context.Background()
+	user := principal.NewSelfAuthenticating([]byte("replica-user")).Encode()
+	newIdentities := map[string]func() (identity.Identity, error){
+		"ed25519":    func() (identity.Identity, error) { return identity.NewRandomEd25519Identity() },
+		"secp256k1":  func() (identity.Identity, error) { return identity.NewRandomSecp256k1Identity() },
+		"prime256v1": func() (identity.Identity, error) { return identity.NewRandomPrime256v1Identity() },
+	}
+	for name, newIdentity := range newIdentities {
+		t.Run(name, func(t *testing.T) {
+			id, err := newIdentity()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, replica := newReplicaClient(t, id)
+
+			ok, err := client.VerifyAction(ctx, user, 7, "https://example.com/p.jpg", "-6.2,106.8")
+			if err != nil || !ok {
+				t.Fatalf("VerifyAction = %v, %v", ok, err)
+			}
+			nftID, err := client.MintNFT(ctx, user, 7, 1.25, "mint-1")
+			if err != nil {
+				t.Fatalf("MintNFT: %v", err)
+			}
+			if again, err := client.MintNFT(ctx, user, 7, 1.25, "mint-1"); err != nil || again != nftID {
+				t.Fatalf("replayed MintNFT = %s, %v; want %s", again, err, nftID)
+			}
+			ids, err := client.GetUserNFTs(ctx, user)
+			if err != nil || len(ids) != 1 || ids[0] != nftID {
+				t.Fatalf("GetUserNFTs = %v, %v; want [%s]", ids, err, nftID)
+			}
+			detail, err := client.GetNFTDetail(ctx, nftID)
+			if err != nil {
+				t.Fatalf("GetNFTDetail: %v", err)
+			}
+			if detail.Owner.Encode() != user || detail.MissionID.BigInt().Uint64() != 7 || detail.CarbonAmount != 1.25 {
+				t.Fatalf("detail = %+v", detail)
+			}
+
+			if err := client.BurnNFT(ctx, nftID); err != nil {
+				t.Fatalf("BurnNFT: %v", err)
+			}
+			if _, err := client.GetNFTDetail(ctx, nftID); !errors.Is(err, motoko.ErrNFTNotFound) {
+				t.Fatalf("GetNFTDetail after burn: err = %v, want ErrNFTNotFound", err)
+			}
+			if err := client.BurnNFT(ctx, nftID); err == nil || !motoko.IsPermanent(err) {
+				t.Fatalf("second BurnNFT err = %v, want permanent error", err)
+			}
+			if replica.Calls("mint_nft") != 2 || replica.Calls("get_nft_detail") != 2 {
+				t.Fatalf("replica saw %d mint_nft and %d get_nft_detail requests", replica.Calls("mint_nft"), replica.Calls("get_nft_detail"))
+			}
+		})
+	}
+}
+
+func TestMotokoClientClassifiesReplicaRejects(t *testing.T) {
+	ctx := context.Background()
+	id, err := identity.NewRandomEd25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, replica := newReplicaClient(t, id)
+	user := principal.NewSelfAuthenticating([]byte("replica-user")).Encode()
+
+	cases := []struct {
+		method    string
+		call      func() error
+		code      uint64
+		retryable bool
+	}{
+		{"mint_nft", func() error { _, err := client.MintNFT(ctx, user, 1, 1, "k"); return err }, replicatest.RejectSysTransient, true},
+		{"mint_nft", func() error { _, err := client.MintNFT(ctx, user, 1, 1, "k"); return err }, replicatest.RejectCanisterError, false},
+		{"get_user_nfts", func() error { _, err := client.GetUserNFTs(ctx, user); return err }, replicatest.RejectSysTransient, true},
+		{"get_user_nfts", func() error { _, err := client.GetUserNFTs(ctx, user); return err }, replicatest.RejectCanisterReject, false},
+	}
+	for _, tc := range cases {
+		replica.Canister.FailNext(tc.method, &replicatest.Reject{Code: tc.code, Message: "injected"})
+		err := tc.call()
+		var canisterErr *motoko.CanisterError
+		if !errors.As(err, &canisterErr) || canisterErr.Retryable != tc.retryable {
+			t.Errorf("%s reject %d: err = %v, want retryable=%v", tc.method, tc.code, err, tc.retryable)
+		}
+	}
+}
@@ -0,0 +1,618 @@
+// Package replicatest runs an in-process stand-in for an IC replica that hosts
+// the PeduliCarbon canister. It speaks the HTTP interface agent-go uses
+// (/api/v2/status, /api/v2|v3/canister/<id>/call, /api/v2/canister/<id>/query and
+// /api/v2/canister/<id>/read_state) with CBOR envelopes, Candid arguments, signed
+// certificates and signed query responses, so MotokoClient can be tested end to
+// end without dfx or network access. Canister state is kept in a
+// motoko.FakeCanister.
+package replicatest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pedulicarbon/internal/motoko"
+
+	agentgo "github.com/aviate-labs/agent-go"
+	"github.com/aviate-labs/agent-go/candid"
+	"github.com/aviate-labs/agent-go/candid/idl"
+	"github.com/aviate-labs/agent-go/certification"
+	"github.com/aviate-labs/agent-go/certification/bls"
+	"github.com/aviate-labs/agent-go/certification/hashtree"
+	"github.com/aviate-labs/agent-go/principal"
+	"github.com/aviate-labs/leb128"
+	"github.com/aviate-labs/secp256k1"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Reject codes of the IC interface specification.
+const (
+	RejectSysFatal           = 1
+	RejectSysTransient       = 2
+	RejectDestinationInvalid = 3
+	RejectCanisterReject     = 4
+	RejectCanisterError      = 5
+)
+
+// Reject is returned by the replica instead of a reply. Queue one on
+// Server.Canister with FailNext to make the next call of a method reject.
+type Reject struct {
+	Code    uint64
+	Message string
+}
+
+func (r *Reject) Error() string { return fmt.Sprintf("(%d) %s", r.Code, r.Message) }
+
+// errorCodes are the IC error codes sent alongside each reject code.
+var errorCodes = map[uint64]string{
+	RejectSysFatal:           "IC0101",
+	RejectSysTransient:       "IC0201",
+	RejectDestinationInvalid: "IC0301",
+	RejectCanisterReject:     "IC0406",
+	RejectCanisterError:      "IC0503",
+}
+
+var (
+	ed25519OID     = asn1.ObjectIdentifier{1, 3, 101, 112}
+	ecPublicKeyOID = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	secp256k1OID   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// Server is a running replica stand-in. Close it when done.
+type Server struct {
+	*httptest.Server
+
+	// CanisterID is the only canister the replica hosts.
+	CanisterID principal.Principal
+	// Canister holds the emulated canister state.
+	Canister *motoko.FakeCanister
+
+	rootKey    *bls.SecretKey
+	rootKeyDER []byte
+	nodeKey    ed25519.PrivateKey
+	nodeID     principal.Principal
+	subnetID   principal.Principal
+
+	mu       sync.Mutex
+	statuses map[agentgo.RequestID]requestStatus
+	calls    map[string]int
+}
+
+type requestStatus struct {
+	reply  []byte
+	reject *Reject
+}
+
+// NewServer starts a replica stand-in hosting canisterID.
+func NewServer(canisterID string) (*Server, error) {
+	id, err := principal.Decode(canisterID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid canister id: %w", err)
+	}
+	rootKey := bls.NewSecretKeyByCSPRNG()
+	if rootKey == nil {
+		return nil, errors.New("generate root key")
+	}
+	// bls.SecretKey.PublicKey menimpa generator G2 global milik agent-go,
+	// jadi kunci publik dihitung sendiri
+	_, _, _, g2 := bls12381.Generators()
+	var pub bls12381.G2Affine
+	sk := fr.Element(*rootKey)
+	pub.ScalarMultiplication(&g2, sk.BigInt(new(big.Int)))
+	pubBytes := pub.Bytes()
+	rootKeyDER, err := certification.PublicBLSKeyToDER(pubBytes[:])
+	if err != nil {
+		return nil, err
+	}
+	_, nodeKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		CanisterID: id,
+		Canister:   motoko.NewFakeCanister(),
+		rootKey:    rootKey,
+		rootKeyDER: rootKeyDER,
+		nodeKey:    nodeKey,
+		nodeID:     principal.NewSelfAuthenticating([]byte("replicatest-node")),
+		// Tanpa delegasi agent-go mencari kunci node di bawah subnet root
+		subnetID: principal.MustDecode(certification.RootSubnetID),
+		statuses: map[agentgo.RequestID]requestStatus{},
+		calls:    map[string]int{},
+	}
+	s.Server = httptest.NewServer(s)
+	return s, nil
+}
+
+// Calls returns how many signed call or query requests reached method.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/api/v2/status" {
+		s.writeCBOR(w, http.StatusOK, map[string]any{"root_key": s.rootKeyDER, "impl_version": "replicatest"})
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method != http.MethodPost || len(parts) != 5 || parts[0] != "api" || parts[2] != "canister" {
+		http.NotFound(w, r)
+		return
+	}
+	version, endpoint := parts[1], parts[4]
+	ecID, err := principal.Decode(parts[3])
+	if err != nil {
+		http.Error(w, "invalid effective canister id", http.StatusBadRequest)
+		return
+	}
+	if !ecID.Equal(s.CanisterID) {
+		http.Error(w, fmt.Sprintf("canister %s not found", ecID), http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, requestID, err := decodeEnvelope(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case endpoint == "call" && (version == "v2" || version == "v3") && req.Type == agentgo.RequestTypeCall:
+		s.handleCall(w, version, req, requestID)
+	case endpoint == "query" && version == "v2" && req.Type == agentgo.RequestTypeQuery:
+		s.handleQuery(w, req, requestID)
+	case endpoint == "read_state" && version == "v2" && req.Type == agentgo.RequestTypeReadState:
+		s.handleReadState(w, req)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported %s request on %s/%s", req.Type, version, endpoint), http.StatusBadRequest)
+	}
+}
+
+// handleCall runs an update call. The v3 endpoint answers synchronously with a
+// certificate, v2 answers 202 and leaves the result to read_state polling.
+func (s *Server) handleCall(w http.ResponseWriter, version string, req agentgo.Request, requestID agentgo.RequestID) {
+	if !req.CanisterID.Equal(s.CanisterID) {
+		http.Error(w, "canister id does not match the effective canister id", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	status, done := s.statuses[requestID]
+	s.mu.Unlock()
+	if !done {
+		// Replay request yang sama tidak dieksekusi dua kali, sama seperti di IC
+		status = s.execute(req, false)
+		s.mu.Lock()
+		s.statuses[requestID] = status
+		s.mu.Unlock()
+	}
+	if version == "v2" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	cert, err := s.certificate(s.requestStatusTree(requestID, status))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeCBOR(w, http.StatusOK, map[string]any{"status": "replied", "certificate": cert})
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, req agentgo.Request, requestID agentgo.RequestID) {
+	if !req.CanisterID.Equal(s.CanisterID) {
+		http.Error(w, "canister id does not match the effective canister id", http.StatusBadRequest)
+		return
+	}
+	status := s.execute(req, true)
+	timestamp := time.Now().UnixNano()
+	resp := map[string]any{}
+	var fields []certification.KeyValuePair
+	if status.reject != nil {
+		code, err := leb128.EncodeUnsigned(new(big.Int).SetUint64(status.reject.Code))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp["status"] = "rejected"
+		resp["reject_code"] = status.reject.Code
+		resp["reject_message"] = status.reject.Message
+		resp["error_code"] = errorCodes[status.reject.Code]
+		fields = []certification.KeyValuePair{
+			{Key: "status", Value: "rejected"},
+			{Key: "reject_code", Value: code},
+			{Key: "reject_message", Value: status.reject.Message},
+			{Key: "error_code", Value: errorCodes[status.reject.Code]},
+		}
+	} else {
+		reply, err := cbor.Marshal(map[string]any{"arg": status.reply})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp["status"] = "replied"
+		resp["reply"] = cbor.RawMessage(reply)
+		fields = []certification.KeyValuePair{
+			{Key: "status", Value: "replied"},
+			{Key: "reply", Value: cbor.RawMessage(reply)},
+		}
+	}
+	fields = append(fields,
+		certification.KeyValuePair{Key: "timestamp", Value: timestamp},
+		certification.KeyValuePair{Key: "request_id", Value: requestID[:]},
+	)
+	hash, err := certification.RepresentationIndependentHash(fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp["signatures"] = []map[string]any{{
+		"timestamp": timestamp,
+		"signature": ed25519.Sign(s.nodeKey, append(hashtree.DomainSeparator("ic-response"), hash[:]...)),
+		"identity":  s.nodeID.Raw,
+	}}
+	s.writeCBOR(w, http.StatusOK, resp)
+}
+
+// handleReadState answers with the request statuses and subnet data that were
+// asked for; other paths are left out of the certificate.
+func (s *Server) handleReadState(w http.ResponseWriter, req agentgo.Request) {
+	var nodes []hashtree.Node
+	var statuses []hashtree.Node
+	subnet := false
+	for _, path := range req.Paths {
+		switch {
+		case len(path) >= 2 && string(path[0]) == "request_status" && len(path[1]) == 32:
+			var id agentgo.RequestID
+			copy(id[:], path[1])
+			s.mu.Lock()
+			status, ok := s.statuses[id]
+			s.mu.Unlock()
+			if ok {
+				statuses = append(statuses, s.requestStatusNode(id, status))
+			}
+		case len(path) >= 1 && string(path[0]) == "subnet":
+			subnet = true
+		case len(path) >= 1 && string(path[0]) == "time":
+		default:
+			http.Error(w, fmt.Sprintf("unsupported read_state path %s", path), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(statuses) > 0 {
+		nodes = append(nodes, hashtree.Labeled{Label: hashtree.Label("request_status"), Tree: fork(statuses)})
+	}
+	if subnet {
+		nodes = append(nodes, s.subnetNode())
+	}
+	cert, err := s.certificate(nodes...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeCBOR(w, http.StatusOK, map[string]any{"certificate": cert})
+}
+
+// execute runs one canister method on the FakeCanister and encodes its Candid reply.
+func (s *Server) execute(req agentgo.Request, query bool) requestStatus {
+	s.mu.Lock()
+	s.calls[req.MethodName]++
+	s.mu.Unlock()
+
+	m, ok := methods[req.MethodName]
+	if !ok {
+		return rejectStatus(RejectCanisterError, fmt.Sprintf("Canister %s has no update method '%s'", s.CanisterID, req.MethodName))
+	}
+	if query && !m.query {
+		return rejectStatus(RejectCanisterError, fmt.Sprintf("Canister %s has no query method '%s'", s.CanisterID, req.MethodName))
+	}
+	reply, err := m.run(context.Background(), s.Canister, req.Arguments)
+	var reject *Reject
+	switch {
+	case errors.As(err, &reject):
+		return requestStatus{reject: reject}
+	case err != nil:
+		return rejectStatus(RejectCanisterError, fmt.Sprintf("Canister %s trapped: %v", s.CanisterID, err))
+	}
+	return requestStatus{reply: reply}
+}
+
+func rejectStatus(code uint64, message string) requestStatus {
+	return requestStatus{reject: &Reject{Code: code, Message: message}}
+}
+
+// certificate builds a certificate over time plus the given top-level nodes and
+// signs it with the root key.
+func (s *Server) certificate(nodes ...hashtree.Node) ([]byte, error) {
+	now, err := leb128.EncodeUnsigned(big.NewInt(time.Now().UnixNano()))
+	if err != nil {
+		return nil, err
+	}
+	nodes = append(nodes, hashtree.Labeled{Label: hashtree.Label("time"), Tree: hashtree.Leaf(now)})
+	tree := hashtree.NewHashTree(fork(nodes))
+	root := tree.Digest()
+	sig, err := s.rootKey.Sign(append(hashtree.DomainSeparator("ic-state-root"), root[:]...))
+	if err != nil {
+		return nil, err
+	}
+	point := bls12381.G1Affine(*sig)
+	sigBytes := point.Bytes()
+	return cbor.Marshal(map[string]any{"tree": tree, "signature": sigBytes[:]})
+}
+
+func (s *Server) requestStatusTree(id agentgo.RequestID, status requestStatus) hashtree.Node {
+	return hashtree.Labeled{Label: hashtree.Label("request_status"), Tree: s.requestStatusNode(id, status)}
+}
+
+func (s *Server) requestStatusNode(id agentgo.RequestID, status requestStatus) hashtree.Node {
+	var fields []hashtree.Node
+	if status.reject != nil {
+		code, _ := leb128.EncodeUnsigned(new(big.Int).SetUint64(status.reject.Code))
+		fields = []hashtree.Node{
+			leaf("error_code", []byte(errorCodes[status.reject.Code])),
+			leaf("reject_code", code),
+			leaf("reject_message", []byte(status.reject.Message)),
+			leaf("status", []byte("rejected")),
+		}
+	} else {
+		fields = []hashtree.Node{
+			leaf("reply", status.reply),
+			leaf("status", []byte("replied")),
+		}
+	}
+	return hashtree.Labeled{Label: id[:], Tree: fork(fields)}
+}
+
+// subnetNode holds the node key query signatures are checked against.
+func (s *Server) subnetNode() hashtree.Node {
+	nodePub, _ := x509.MarshalPKIXPublicKey(s.nodeKey.Public())
+	node := hashtree.Labeled{Label: s.nodeID.Raw, Tree: leaf("public_key", nodePub)}
+	return hashtree.Labeled{Label: hashtree.Label("subnet"), Tree: hashtree.Labeled{
+		Label: s.subnetID.Raw,
+		Tree: fork([]hashtree.Node{
+			hashtree.Labeled{Label: hashtree.Label("node"), Tree: node},
+			leaf("public_key", s.rootKeyDER),
+		}),
+	}}
+}
+
+func (s *Server) writeCBOR(w http.ResponseWriter, code int, v any) {
+	body, err := cbor.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/cbor")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func leaf(label string, value []byte) hashtree.Node {
+	return hashtree.Labeled{Label: hashtree.Label(label), Tree: hashtree.Leaf(value)}
+}
+
+// fork joins labeled nodes into a tree; lookups need the labels in ascending order.
+func fork(nodes []hashtree.Node) hashtree.Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].(hashtree.Labeled).Label, nodes[j].(hashtree.Labeled).Label) < 0
+	})
+	return forkSorted(nodes)
+}
+
+func forkSorted(nodes []hashtree.Node) hashtree.Node {
+	switch len(nodes) {
+	case 0:
+		return hashtree.Empty{}
+	case 1:
+		return nodes[0]
+	}
+	mid := len(nodes) / 2
+	return hashtree.Fork{LeftTree: forkSorted(nodes[:mid]), RightTree: forkSorted(nodes[mid:])}
+}
+
+// envelope mirrors agentgo.Envelope for decoding; agent-go only encodes requests.
+type envelope struct {
+	Content struct {
+		RequestType   string     `cbor:"request_type"`
+		Sender        []byte     `cbor:"sender"`
+		Nonce         []byte     `cbor:"nonce"`
+		IngressExpiry uint64     `cbor:"ingress_expiry"`
+		CanisterID    []byte     `cbor:"canister_id"`
+		MethodName    string     `cbor:"method_name"`
+		Arg           []byte     `cbor:"arg"`
+		Paths         [][][]byte `cbor:"paths"`
+	} `cbor:"content"`
+	SenderPubKey []byte `cbor:"sender_pubkey"`
+	SenderSig    []byte `cbor:"sender_sig"`
+}
+
+// decodeEnvelope decodes a signed request, checks its expiry and verifies the
+// sender signature over the request ID.
+func decodeEnvelope(body []byte) (agentgo.Request, agentgo.RequestID, error) {
+	var env envelope
+	if err := cbor.Unmarshal(body, &env); err != nil {
+		return agentgo.Request{}, agentgo.RequestID{}, fmt.Errorf("invalid envelope: %w", err)
+	}
+	c := env.Content
+	req := agentgo.Request{
+		Type:          c.RequestType,
+		Sender:        principal.Principal{Raw: c.Sender},
+		Nonce:         c.Nonce,
+		IngressExpiry: c.IngressExpiry,
+		MethodName:    c.MethodName,
+		Arguments:     c.Arg,
+	}
+	if c.CanisterID != nil {
+		req.CanisterID = principal.Principal{Raw: c.CanisterID}
+	}
+	for _, path := range c.Paths {
+		var labels []hashtree.Label
+		for _, l := range path {
+			labels = append(labels, hashtree.Label(l))
+		}
+		req.Paths = append(req.Paths, labels)
+	}
+	if req.IngressExpiry < uint64(time.Now().UnixNano()) {
+		return req, agentgo.RequestID{}, errors.New("ingress expiry is in the past")
+	}
+	requestID := agentgo.NewRequestID(req)
+	if err := verifySender(req.Sender, env.SenderPubKey, env.SenderSig, requestID); err != nil {
+		return req, requestID, err
+	}
+	return req, requestID, nil
+}
+
+func verifySender(sender principal.Principal, pubKey, sig []byte, requestID agentgo.RequestID) error {
+	if sender.Equal(principal.AnonymousID) {
+		return nil
+	}
+	if len(pubKey) == 0 || len(sig) == 0 {
+		return errors.New("request from a non-anonymous sender is not signed")
+	}
+	if !principal.NewSelfAuthenticating(pubKey).Equal(sender) {
+		return errors.New("sender does not match the sender public key")
+	}
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.RawValue `asn1:"optional"`
+		}
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(pubKey, &spki); err != nil {
+		return fmt.Errorf("invalid sender public key: %w", err)
+	}
+	msg := append(hashtree.DomainSeparator("ic-request"), requestID[:]...)
+	hash := sha256.Sum256(msg)
+	ok := false
+	switch {
+	case spki.Algorithm.Algorithm.Equal(ed25519OID):
+		ok = ed25519.Verify(ed25519.PublicKey(spki.PublicKey.Bytes), msg, sig)
+	case spki.Algorithm.Algorithm.Equal(ecPublicKeyOID) && len(sig) == 64:
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil {
+			return fmt.Errorf("invalid sender key curve: %w", err)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if curve.Equal(secp256k1OID) {
+			pub, err := secp256k1.ParsePubKey(spki.PublicKey.Bytes, secp256k1.S256())
+			if err != nil {
+				return fmt.Errorf("invalid sender public key: %w", err)
+			}
+			ok = (&secp256k1.Signature{R: r, S: s}).Verify(hash[:], pub)
+		} else {
+			pub, err := x509.ParsePKIXPublicKey(pubKey)
+			if err != nil {
+				return fmt.Errorf("invalid sender public key: %w", err)
+			}
+			ecPub, isEC := pub.(*ecdsa.PublicKey)
+			ok = isEC && ecdsa.Verify(ecPub, hash[:], r, s)
+		}
+	default:
+		return fmt.Errorf("unsupported sender key algorithm %s", spki.Algorithm.Algorithm)
+	}
+	if !ok {
+		return errors.New("invalid sender signature")
+	}
+	return nil
+}
+
+// method emulates one method of pedulicarbon_backend.did.
+type method struct {
+	query bool
+	run   func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error)
+}
+
+var methods = map[string]method{
+	"verify_action": {run: func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error) {
+		var (
+			user          principal.Principal
+			missionID     idl.Nat
+			proofURL, gps string
+		)
+		if err := candid.Unmarshal(arg, []any{&user, &missionID, &proofURL, &gps}); err != nil {
+			return nil, err
+		}
+		ok, err := c.VerifyAction(ctx, user.Encode(), uint(missionID.BigInt().Uint64()), proofURL, gps)
+		if err != nil {
+			return nil, err
+		}
+		return candid.Marshal([]any{ok})
+	}},
+	"mint_nft": {run: func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error) {
+		var (
+			user      principal.Principal
+			missionID idl.Nat
+			carbon    float64
+			key       string
+		)
+		if err := candid.Unmarshal(arg, []any{&user, &missionID, &carbon, &key}); err != nil {
+			return nil, err
+		}
+		id, err := c.MintNFT(ctx, user.Encode(), uint(missionID.BigInt().Uint64()), carbon, key)
+		if err != nil {
+			return nil, err
+		}
+		return candid.Marshal([]any{id})
+	}},
+	"get_user_nfts": {query: true, run: func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error) {
+		var user principal.Principal
+		if err := candid.Unmarshal(arg, []any{&user}); err != nil {
+			return nil, err
+		}
+		ids, err := c.GetUserNFTs(ctx, user.Encode())
+		if err != nil {
+			return nil, err
+		}
+		return candid.Marshal([]any{ids})
+	}},
+	"get_nft_detail": {query: true, run: func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error) {
+		var nftID string
+		if err := candid.Unmarshal(arg, []any{&nftID}); err != nil {
+			return nil, err
+		}
+		detail, err := c.GetNFTDetail(ctx, nftID)
+		if errors.Is(err, motoko.ErrNFTNotFound) {
+			detail, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return candid.Marshal([]any{detail})
+	}},
+	"burn_nft": {run: func(ctx context.Context, c *motoko.FakeCanister, arg []byte) ([]byte, error) {
+		var nftID string
+		if err := candid.Unmarshal(arg, []any{&nftID}); err != nil {
+			return nil, err
+		}
+		// Canister asli mengembalikan false, bukan reject, untuk NFT yang tidak ada
+		if _, err := c.GetNFTDetail(ctx, nftID); errors.Is(err, motoko.ErrNFTNotFound) {
+			return candid.Marshal([]any{false})
+		}
+		if err := c.BurnNFT(ctx, nftID); err != nil {
+			return nil, err
+		}
+		return candid.Marshal([]any{true})
+	}},
+}
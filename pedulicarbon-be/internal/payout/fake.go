@@ -0,0 +1,142 @@
+package payout
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// FakeSignatureHeader membawa HMAC-SHA256 (hex) dari body callback FakeProvider.
+const FakeSignatureHeader = "X-Payout-Signature"
+
+// FakeProvider is an in-process payout gateway for development and tests. It
+// keeps disbursements in memory, honours ExternalID idempotency and signs its
+// callbacks with Secret. It is safe for concurrent use.
+type FakeProvider struct {
+	Secret string
+	// AutoComplete makes GetDisbursement report pending disbursements as completed,
+	// so the status poller settles withdrawals without a callback in development.
+	AutoComplete bool
+
+	mu       sync.Mutex
+	byRef    map[string]*fakeDisbursement
+	byExtID  map[string]string
+	nextID   int
+	failNext []error
+}
+
+type fakeDisbursement struct {
+	ID          string `json:"id"`
+	ExternalID  string `json:"external_id"`
+	Amount      int64  `json:"amount"`
+	ChannelCode string `json:"channel_code"`
+	Status      string `json:"status"`
+	FailureCode string `json:"failure_code,omitempty"`
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{Secret: secret, byRef: map[string]*fakeDisbursement{}, byExtID: map[string]string{}}
+}
+
+func (f *FakeProvider) Name() string { return "fake" }
+
+// FailNext makes the next CreateDisbursement return err instead of running.
+func (f *FakeProvider) FailNext(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext = append(f.failNext, err)
+}
+
+func (f *FakeProvider) CreateDisbursement(_ context.Context, d Disbursement) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failNext) > 0 {
+		err := f.failNext[0]
+		f.failNext = f.failNext[1:]
+		return nil, err
+	}
+	if ref, ok := f.byExtID[d.ExternalID]; ok {
+		return f.byRef[ref].result(), nil
+	}
+	f.nextID++
+	disb := &fakeDisbursement{
+		ID:          fmt.Sprintf("fake-disb-%d", f.nextID),
+		ExternalID:  d.ExternalID,
+		Amount:      d.Amount,
+		ChannelCode: d.ChannelCode,
+		Status:      StatusPending,
+	}
+	f.byRef[disb.ID] = disb
+	f.byExtID[d.ExternalID] = disb.ID
+	return disb.result(), nil
+}
+
+func (f *FakeProvider) GetDisbursement(_ context.Context, providerRef string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	disb, ok := f.byRef[providerRef]
+	if !ok {
+		return nil, &ProviderError{Provider: f.Name(), Op: "get_disbursement", Err: fmt.Errorf("disbursement %s not found", providerRef)}
+	}
+	if f.AutoComplete && disb.Status == StatusPending {
+		disb.Status = StatusCompleted
+	}
+	return disb.result(), nil
+}
+
+// Settle moves a pending disbursement to completed or failed and returns the
+// signed callback the provider would send, as body and signature header value.
+func (f *FakeProvider) Settle(providerRef, status, failureCode string) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	disb, ok := f.byRef[providerRef]
+	if !ok {
+		return nil, "", fmt.Errorf("disbursement %s not found", providerRef)
+	}
+	disb.Status, disb.FailureCode = status, failureCode
+	body, err := json.Marshal(disb)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, f.Sign(body), nil
+}
+
+// Sign returns the signature of a callback body.
+func (f *FakeProvider) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *FakeProvider) ParseCallback(header http.Header, body []byte) (*Result, error) {
+	sig, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || f.Secret == "" {
+		return nil, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(f.Secret))
+	mac.Write(body)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+	var disb fakeDisbursement
+	if err := json.Unmarshal(body, &disb); err != nil {
+		return nil, err
+	}
+	if disb.ID == "" {
+		return nil, errors.New("fake callback without disbursement id")
+	}
+	res := disb.result()
+	res.Raw = body
+	return res, nil
+}
+
+func (d *fakeDisbursement) result() *Result {
+	raw, _ := json.Marshal(d)
+	return &Result{ProviderRef: d.ID, ExternalID: d.ExternalID, Status: d.Status, FailureCode: d.FailureCode, Raw: raw}
+}
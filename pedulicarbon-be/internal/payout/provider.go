@@ -0,0 +1,88 @@
+// Package payout berisi gateway pencairan rupiah (disbursement) untuk penarikan
+// wallet: adapter bergaya Xendit untuk production dan FakeProvider untuk development.
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Status disbursement di provider, sudah dinormalkan dari status masing-masing gateway.
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ErrInvalidSignature is returned by ParseCallback when the callback is not signed
+// by the provider.
+var ErrInvalidSignature = errors.New("payout callback signature invalid")
+
+// Disbursement is one payout request. ExternalID is our idempotency key: creating
+// the same ExternalID twice must not pay out twice.
+type Disbursement struct {
+	ExternalID        string
+	Amount            int64
+	ChannelCode       string // kode bank (BCA, MANDIRI, ...) atau e-wallet (GOPAY, OVO, DANA, ...)
+	AccountNumber     string // nomor rekening atau nomor HP
+	AccountHolderName string
+	Description       string
+}
+
+// Result is the provider's view of a disbursement. Raw is the provider's response
+// or callback body, kept for dispute handling.
+type Result struct {
+	ProviderRef string
+	ExternalID  string
+	Status      string
+	FailureCode string
+	Raw         []byte
+}
+
+// Provider is the payout gateway used by WithdrawService. XenditProvider talks to
+// a Xendit-style disbursement API; FakeProvider keeps disbursements in memory.
+type Provider interface {
+	Name() string
+	CreateDisbursement(ctx context.Context, d Disbursement) (*Result, error)
+	GetDisbursement(ctx context.Context, providerRef string) (*Result, error)
+	// ParseCallback verifies that an inbound webhook comes from the provider and
+	// decodes it. It returns ErrInvalidSignature for unsigned or forged callbacks.
+	ParseCallback(header http.Header, body []byte) (*Result, error)
+}
+
+var (
+	_ Provider = (*XenditProvider)(nil)
+	_ Provider = (*FakeProvider)(nil)
+)
+
+// ProviderError is returned when a provider call fails. Retryable errors (network
+// trouble, 5xx, rate limits) may succeed when repeated with the same ExternalID;
+// permanent ones (rejected account, invalid request) will not.
+type ProviderError struct {
+	Provider  string
+	Op        string
+	Retryable bool
+	Code      string
+	Err       error
+}
+
+func (e *ProviderError) Error() string {
+	kind := "permanent"
+	if e.Retryable {
+		kind = "retryable"
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("payout %s %s failed (%s, %s): %v", e.Provider, e.Op, kind, e.Code, e.Err)
+	}
+	return fmt.Sprintf("payout %s %s failed (%s): %v", e.Provider, e.Op, kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err is a provider failure that must not be retried.
+func IsPermanent(err error) bool {
+	var perr *ProviderError
+	return errors.As(err, &perr) && !perr.Retryable
+}
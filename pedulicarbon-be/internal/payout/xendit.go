@@ -0,0 +1,169 @@
+package payout
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultXenditBaseURL adalah endpoint API Xendit.
+const DefaultXenditBaseURL = "https://api.xendit.co"
+
+// XenditProvider memakai Disbursement API Xendit: POST /disbursements dengan basic
+// auth secret key dan header X-IDEMPOTENCY-KEY, GET /disbursements/:id, dan callback
+// yang membawa token verifikasi di header x-callback-token.
+type XenditProvider struct {
+	BaseURL       string
+	SecretKey     string
+	CallbackToken string
+	HTTP          *http.Client
+}
+
+func NewXenditProvider(baseURL, secretKey, callbackToken string) *XenditProvider {
+	if baseURL == "" {
+		baseURL = DefaultXenditBaseURL
+	}
+	return &XenditProvider{
+		BaseURL:       strings.TrimRight(baseURL, "/"),
+		SecretKey:     secretKey,
+		CallbackToken: callbackToken,
+		HTTP:          &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *XenditProvider) Name() string { return "xendit" }
+
+// xenditDisbursement adalah objek disbursement di request, response dan callback.
+type xenditDisbursement struct {
+	ID                string `json:"id,omitempty"`
+	ExternalID        string `json:"external_id"`
+	Amount            int64  `json:"amount"`
+	BankCode          string `json:"bank_code"`
+	AccountHolderName string `json:"account_holder_name"`
+	AccountNumber     string `json:"account_number,omitempty"`
+	Description       string `json:"description,omitempty"`
+	Status            string `json:"status,omitempty"` // PENDING, COMPLETED, FAILED
+	FailureCode       string `json:"failure_code,omitempty"`
+}
+
+type xenditError struct {
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (d *xenditDisbursement) result(raw []byte) *Result {
+	status := StatusPending
+	switch strings.ToUpper(d.Status) {
+	case "COMPLETED":
+		status = StatusCompleted
+	case "FAILED":
+		status = StatusFailed
+	}
+	return &Result{ProviderRef: d.ID, ExternalID: d.ExternalID, Status: status, FailureCode: d.FailureCode, Raw: raw}
+}
+
+func (p *XenditProvider) CreateDisbursement(ctx context.Context, d Disbursement) (*Result, error) {
+	body, err := json.Marshal(xenditDisbursement{
+		ExternalID:        d.ExternalID,
+		Amount:            d.Amount,
+		BankCode:          d.ChannelCode,
+		AccountHolderName: d.AccountHolderName,
+		AccountNumber:     d.AccountNumber,
+		Description:       d.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.do(ctx, "create_disbursement", http.MethodPost, "/disbursements", body, d.ExternalID)
+	var perr *ProviderError
+	if errors.As(err, &perr) && perr.Code == "DUPLICATE_TRANSACTION_ERROR" {
+		// Sudah pernah dibuat (mis. retry setelah timeout): ambil disbursement yang ada
+		return p.findByExternalID(ctx, d.ExternalID)
+	}
+	return res, err
+}
+
+func (p *XenditProvider) GetDisbursement(ctx context.Context, providerRef string) (*Result, error) {
+	return p.do(ctx, "get_disbursement", http.MethodGet, "/disbursements/"+url.PathEscape(providerRef), nil, "")
+}
+
+func (p *XenditProvider) findByExternalID(ctx context.Context, externalID string) (*Result, error) {
+	raw, err := p.request(ctx, "get_disbursement", http.MethodGet, "/disbursements?external_id="+url.QueryEscape(externalID), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var list []xenditDisbursement
+	if err := json.Unmarshal(raw, &list); err != nil || len(list) == 0 {
+		return nil, &ProviderError{Provider: p.Name(), Op: "get_disbursement", Retryable: true, Err: fmt.Errorf("disbursement %s not found after duplicate", externalID)}
+	}
+	return list[0].result(raw), nil
+}
+
+// ParseCallback mencocokkan header x-callback-token dengan token verifikasi dari
+// dashboard Xendit.
+func (p *XenditProvider) ParseCallback(header http.Header, body []byte) (*Result, error) {
+	token := header.Get("x-callback-token")
+	if p.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.CallbackToken)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+	var d xenditDisbursement
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, err
+	}
+	if d.ID == "" {
+		return nil, errors.New("xendit callback without disbursement id")
+	}
+	return d.result(body), nil
+}
+
+func (p *XenditProvider) do(ctx context.Context, op, method, path string, body []byte, idempotencyKey string) (*Result, error) {
+	raw, err := p.request(ctx, op, method, path, body, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	var d xenditDisbursement
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Op: op, Retryable: true, Err: err}
+	}
+	return d.result(raw), nil
+}
+
+func (p *XenditProvider) request(ctx context.Context, op, method, path string, body []byte, idempotencyKey string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.SecretKey, "")
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("X-IDEMPOTENCY-KEY", idempotencyKey)
+	}
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Op: op, Retryable: true, Err: err}
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Name(), Op: op, Retryable: true, Err: err}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return raw, nil
+	}
+	var xerr xenditError
+	json.Unmarshal(raw, &xerr)
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	if xerr.Message == "" {
+		xerr.Message = http.StatusText(resp.StatusCode)
+	}
+	return nil, &ProviderError{Provider: p.Name(), Op: op, Retryable: retryable, Code: xerr.ErrorCode,
+		Err: fmt.Errorf("HTTP %d: %s", resp.StatusCode, xerr.Message)}
+}
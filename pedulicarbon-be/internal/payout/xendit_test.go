@@ -0,0 +1,75 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestXenditDisbursement(t *testing.T) {
+	created := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/disbursements":
+			key := r.Header.Get("X-IDEMPOTENCY-KEY")
+			switch {
+			case key == "withdraw-bad":
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error_code":"INVALID_DESTINATION","message":"bank code"}`))
+			case key == "withdraw-down":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case created[key]:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error_code":"DUPLICATE_TRANSACTION_ERROR","message":"duplicate"}`))
+			default:
+				created[key] = true
+				w.Write([]byte(`{"id":"disb-1","external_id":"` + key + `","status":"PENDING"}`))
+			}
+		case r.URL.Path == "/disbursements" && r.URL.Query().Get("external_id") == "withdraw-1":
+			w.Write([]byte(`[{"id":"disb-1","external_id":"withdraw-1","status":"COMPLETED"}]`))
+		case r.URL.Path == "/disbursements/disb-1":
+			w.Write([]byte(`{"id":"disb-1","external_id":"withdraw-1","status":"FAILED","failure_code":"INVALID_DESTINATION"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	p := NewXenditProvider(srv.URL, "secret", "callback-token")
+	ctx := context.Background()
+
+	res, err := p.CreateDisbursement(ctx, Disbursement{ExternalID: "withdraw-1", Amount: 20000, ChannelCode: "BCA", AccountNumber: "1234567890"})
+	if err != nil || res.ProviderRef != "disb-1" || res.Status != StatusPending {
+		t.Fatalf("create = %+v, %v", res, err)
+	}
+	// Retry dengan external_id yang sama mengembalikan disbursement yang sudah ada
+	res, err = p.CreateDisbursement(ctx, Disbursement{ExternalID: "withdraw-1", Amount: 20000})
+	if err != nil || res.ProviderRef != "disb-1" || res.Status != StatusCompleted {
+		t.Fatalf("duplicate create = %+v, %v", res, err)
+	}
+	if res, err := p.GetDisbursement(ctx, "disb-1"); err != nil || res.Status != StatusFailed || res.FailureCode != "INVALID_DESTINATION" {
+		t.Fatalf("get = %+v, %v", res, err)
+	}
+
+	_, err = p.CreateDisbursement(ctx, Disbursement{ExternalID: "withdraw-bad"})
+	var perr *ProviderError
+	if !errors.As(err, &perr) || perr.Code != "INVALID_DESTINATION" || !IsPermanent(err) {
+		t.Fatalf("rejected create err = %v, want permanent INVALID_DESTINATION", err)
+	}
+	if _, err := p.CreateDisbursement(ctx, Disbursement{ExternalID: "withdraw-down"}); err == nil || IsPermanent(err) {
+		t.Fatalf("unavailable create err = %v, want retryable", err)
+	}
+
+	body := []byte(`{"id":"disb-1","external_id":"withdraw-1","status":"COMPLETED"}`)
+	if _, err := p.ParseCallback(http.Header{"X-Callback-Token": {"wrong"}}, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong token err = %v, want ErrInvalidSignature", err)
+	}
+	if res, err := p.ParseCallback(http.Header{"X-Callback-Token": {"callback-token"}}, body); err != nil || res.Status != StatusCompleted || string(res.Raw) != string(body) {
+		t.Fatalf("callback = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BeneficiaryRepository struct {
+	DB *gorm.DB
+}
+
+func NewBeneficiaryRepository(db *gorm.DB) *BeneficiaryRepository {
+	return &BeneficiaryRepository{DB: db}
+}
+
+func (r *BeneficiaryRepository) WithTx(tx *gorm.DB) *BeneficiaryRepository {
+	return &BeneficiaryRepository{DB: tx}
+}
+
+func (r *BeneficiaryRepository) Create(b *model.Beneficiary) error {
+	return r.DB.Create(b).Error
+}
+
+// ListByUserID returns the beneficiaries of the user that are not removed.
+func (r *BeneficiaryRepository) ListByUserID(userID uint) ([]model.Beneficiary, error) {
+	var list []model.Beneficiary
+	err := r.DB.Where("user_id = ? AND removed_at IS NULL", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+// GetByID returns the beneficiary only if it belongs to userID and is not removed.
+func (r *BeneficiaryRepository) GetByID(userID, id uint) (*model.Beneficiary, error) {
+	var b model.Beneficiary
+	err := r.DB.Where("id = ? AND user_id = ? AND removed_at IS NULL", id, userID).First(&b).Error
+	return &b, err
+}
+
+// FindAny returns a beneficiary of any user, including removed ones.
+func (r *BeneficiaryRepository) FindAny(id uint) (*model.Beneficiary, error) {
+	var b model.Beneficiary
+	err := r.DB.First(&b, id).Error
+	return &b, err
+}
+
+func (r *BeneficiaryRepository) CountByUserID(userID uint) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.Beneficiary{}).Where("user_id = ? AND removed_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// ExistsAccount reports whether the user already has the same account saved.
+func (r *BeneficiaryRepository) ExistsAccount(userID uint, channel, provider, account string) (bool, error) {
+	var n int64
+	err := r.DB.Model(&model.Beneficiary{}).
+		Where("user_id = ? AND channel = ? AND provider = ? AND account_number = ? AND removed_at IS NULL", userID, channel, provider, account).
+		Count(&n).Error
+	return n > 0, err
+}
+
+func (r *BeneficiaryRepository) Remove(userID, id uint, at time.Time) (bool, error) {
+	res := r.DB.Model(&model.Beneficiary{}).Where("id = ? AND user_id = ? AND removed_at IS NULL", id, userID).Update("removed_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *BeneficiaryRepository) MarkVerified(id, adminID uint, at time.Time) (bool, error) {
+	res := r.DB.Model(&model.Beneficiary{}).Where("id = ? AND removed_at IS NULL", id).
+		Updates(map[string]interface{}{"verified": true, "verified_at": at, "verified_by": adminID})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	DB *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{DB: db}
+}
+
+func (r *JobRepository) CreateJob(job *model.Job) error {
+	return r.DB.Create(job).Error
+}
+
+func (r *JobRepository) GetByID(id uint) (*model.Job, error) {
+	var job model.Job
+	err := r.DB.First(&job, id).Error
+	return &job, err
+}
+
+// GetActiveByDedupKey returns a queued or running job with the same dedup key.
+func (r *JobRepository) GetActiveByDedupKey(key string) (*model.Job, error) {
+	var job model.Job
+	err := r.DB.Where("dedup_key = ? AND status IN ?", key, []string{model.JobStatusQueued, model.JobStatusRunning}).
+		First(&job).Error
+	return &job, err
+}
+
+// ClaimNext locks the next due job, including running jobs whose lease expired
+// because their worker died, and marks it running for lease. It returns
+// gorm.ErrRecordNotFound when nothing is due.
+func (r *JobRepository) ClaimNext(lease time.Duration) (*model.Job, error) {
+	var job model.Job
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+				model.JobStatusQueued, now, model.JobStatusRunning, now).
+			Order("run_at").First(&job).Error
+		if err != nil {
+			return err
+		}
+		lockedUntil := now.Add(lease)
+		job.Status = model.JobStatusRunning
+		job.Attempts++
+		job.LockedUntil = &lockedUntil
+		return tx.Model(&model.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":       job.Status,
+			"attempts":     job.Attempts,
+			"locked_until": lockedUntil,
+		}).Error
+	})
+	return &job, err
+}
+
+func (r *JobRepository) MarkSucceeded(id uint, result string) error {
+	return r.DB.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.JobStatusSucceeded,
+		"result":       result,
+		"last_error":   "",
+		"locked_until": nil,
+		"finished_at":  time.Now(),
+	}).Error
+}
+
+func (r *JobRepository) MarkRetry(id uint, runAt time.Time, lastError string) error {
+	return r.DB.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.JobStatusQueued,
+		"run_at":       runAt,
+		"last_error":   lastError,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *JobRepository) MarkDead(id uint, lastError string) error {
+	return r.DB.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.JobStatusDead,
+		"last_error":   lastError,
+		"locked_until": nil,
+		"finished_at":  time.Now(),
+	}).Error
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"pedulicarbon/internal/model"
-	"time"
 
 	"gorm.io/gorm"
 )
@@ -25,12 +24,11 @@ func (r *MissionTakenRepository) GetUserMissions(userID uint) ([]model.MissionTa
 	return missions, err
 }
 
-func (r *MissionTakenRepository) UpdateProof(mtID uint, proofURL, gps string) error {
-	return r.DB.Model(&model.MissionTaken{}).Where("id = ?", mtID).Updates(map[string]interface{}{"proof_url": proofURL, "gps": gps, "status": "pending"}).Error
-}
-
-func (r *MissionTakenRepository) VerifyMission(mtID uint) error {
-	return r.DB.Model(&model.MissionTaken{}).Where("id = ?", mtID).Updates(map[string]interface{}{"status": "verified", "verified_at": time.Now()}).Error
+// UpdateStatus applies updates only while the row is still in status from.
+// It returns false when another request already moved the row on.
+func (r *MissionTakenRepository) UpdateStatus(mtID uint, from string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.MissionTaken{}).Where("id = ? AND status = ?", mtID, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
 }
 
 func (r *MissionTakenRepository) GetByID(id uint) (*model.MissionTaken, error) {
@@ -38,3 +36,7 @@ func (r *MissionTakenRepository) GetByID(id uint) (*model.MissionTaken, error) {
 	err := r.DB.First(&mt, id).Error
 	return &mt, err
 }
+
+func (r *MissionTakenRepository) WithTx(tx *gorm.DB) *MissionTakenRepository {
+	return &MissionTakenRepository{DB: tx}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type NFTReconciliationRepository struct {
+	DB *gorm.DB
+}
+
+func NewNFTReconciliationRepository(db *gorm.DB) *NFTReconciliationRepository {
+	return &NFTReconciliationRepository{DB: db}
+}
+
+func (r *NFTReconciliationRepository) WithTx(tx *gorm.DB) *NFTReconciliationRepository {
+	return &NFTReconciliationRepository{DB: tx}
+}
+
+func (r *NFTReconciliationRepository) CreateAudit(audit *model.NFTReconciliationAudit) error {
+	return r.DB.Create(audit).Error
+}
+
+func (r *NFTReconciliationRepository) ListByRunID(runID string) ([]model.NFTReconciliationAudit, error) {
+	var audits []model.NFTReconciliationAudit
+	err := r.DB.Where("run_id = ?", runID).Order("id").Find(&audits).Error
+	return audits, err
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{DB: db}
+}
+
+// CreateOnce inserts n unless a notification with the same RefKey exists, and
+// reports whether it was inserted.
+func (r *NotificationRepository) CreateOnce(n *model.Notification) (bool, error) {
+	res := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListByUserID returns the user's notifications, newest first, and the unread count.
+func (r *NotificationRepository) ListByUserID(userID uint, limit, offset int) ([]model.Notification, int64, error) {
+	var unread int64
+	if err := r.DB.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return nil, 0, err
+	}
+	var notifications []model.Notification
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, unread, err
+}
+
+// MarkRead sets read_at on the user's notification; false when it does not exist.
+func (r *NotificationRepository) MarkRead(userID, id uint, at time.Time) (bool, error) {
+	res := r.DB.Model(&model.Notification{}).Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).Update("read_at", at)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.RowsAffected > 0, res.Error
+	}
+	var n int64
+	err := r.DB.Model(&model.Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&n).Error
+	return n > 0, err
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PayoutEventRepository struct {
+	DB *gorm.DB
+}
+
+func NewPayoutEventRepository(db *gorm.DB) *PayoutEventRepository {
+	return &PayoutEventRepository{DB: db}
+}
+
+func (r *PayoutEventRepository) Create(e *model.PayoutEvent) error {
+	return r.DB.Create(e).Error
+}
+
+// ListByWithdrawID returns the provider events of a withdrawal, oldest first.
+func (r *PayoutEventRepository) ListByWithdrawID(withdrawID uint) ([]model.PayoutEvent, error) {
+	var events []model.PayoutEvent
+	err := r.DB.Where("withdraw_id = ?", withdrawID).Order("id").Find(&events).Error
+	return events, err
+}
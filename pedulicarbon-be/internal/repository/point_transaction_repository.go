@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PointTransactionRepository struct {
+	DB *gorm.DB
+}
+
+func NewPointTransactionRepository(db *gorm.DB) *PointTransactionRepository {
+	return &PointTransactionRepository{DB: db}
+}
+
+func (r *PointTransactionRepository) WithTx(tx *gorm.DB) *PointTransactionRepository {
+	return &PointTransactionRepository{DB: tx}
+}
+
+// CreateEntries inserts the lines of one journal.
+func (r *PointTransactionRepository) CreateEntries(entries []model.PointTransaction) error {
+	return r.DB.Create(&entries).Error
+}
+
+// ListByUserID returns a user's ledger lines, newest first, and the total count.
+func (r *PointTransactionRepository) ListByUserID(userID uint, limit, offset int) ([]model.PointTransaction, int64, error) {
+	var total int64
+	if err := r.DB.Model(&model.PointTransaction{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []model.PointTransaction
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// ListAllByUserID returns every ledger line of the user's account, oldest first.
+func (r *PointTransactionRepository) ListAllByUserID(userID uint) ([]model.PointTransaction, error) {
+	var entries []model.PointTransaction
+	err := r.DB.Where("user_id = ?", userID).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// ListUserIDsWithCreditsBefore returns users that received points at or before t.
+func (r *PointTransactionRepository) ListUserIDsWithCreditsBefore(t time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&model.PointTransaction{}).
+		Where("user_id IS NOT NULL AND amount > 0 AND created_at <= ?", t).
+		Distinct().Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// FindByRef returns the line of account posted for a source record, if any.
+func (r *PointTransactionRepository) FindByRef(account, entryType, refType, refID string) (*model.PointTransaction, error) {
+	var entry model.PointTransaction
+	err := r.DB.Where("account = ? AND entry_type = ? AND ref_type = ? AND ref_id = ?", account, entryType, refType, refID).First(&entry).Error
+	return &entry, err
+}
+
+// UnexplainedBalance is a user whose stored balance differs from the ledger sum.
+type UnexplainedBalance struct {
+	UserID    uint
+	Points    int
+	LedgerSum int
+}
+
+// ListUnexplainedBalances returns users whose users.points is not fully explained by ledger lines.
+func (r *PointTransactionRepository) ListUnexplainedBalances() ([]UnexplainedBalance, error) {
+	var rows []UnexplainedBalance
+	err := r.DB.Raw(`SELECT u.id AS user_id, u.points AS points, COALESCE(SUM(pt.amount), 0) AS ledger_sum
+		FROM users u LEFT JOIN point_transactions pt ON pt.user_id = u.id
+		GROUP BY u.id, u.points
+		HAVING u.points <> COALESCE(SUM(pt.amount), 0)
+		ORDER BY u.id`).Scan(&rows).Error
+	return rows, err
+}
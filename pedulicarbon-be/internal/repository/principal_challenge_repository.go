@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PrincipalChallengeRepository struct {
+	DB *gorm.DB
+}
+
+func NewPrincipalChallengeRepository(db *gorm.DB) *PrincipalChallengeRepository {
+	return &PrincipalChallengeRepository{DB: db}
+}
+
+func (r *PrincipalChallengeRepository) CreateChallenge(ch *model.PrincipalChallenge) error {
+	return r.DB.Create(ch).Error
+}
+
+func (r *PrincipalChallengeRepository) GetActiveChallenge(userID uint, challenge string) (*model.PrincipalChallenge, error) {
+	var ch model.PrincipalChallenge
+	err := r.DB.Where("user_id = ? AND challenge = ? AND used_at IS NULL AND expires_at > ?", userID, challenge, time.Now()).First(&ch).Error
+	return &ch, err
+}
+
+// MarkUsed consumes the challenge; it returns gorm.ErrRecordNotFound when it was already used.
+func (r *PrincipalChallengeRepository) MarkUsed(id uint) error {
+	res := r.DB.Model(&model.PrincipalChallenge{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"pedulicarbon/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,10 +15,44 @@ func NewRewardCatalogRepository(db *gorm.DB) *RewardCatalogRepository {
 	return &RewardCatalogRepository{DB: db}
 }
 
-func (r *RewardCatalogRepository) ListCatalog() ([]model.RewardCatalog, error) {
+func (r *RewardCatalogRepository) WithTx(tx *gorm.DB) *RewardCatalogRepository {
+	return &RewardCatalogRepository{DB: tx}
+}
+
+// CatalogFilter memfilter daftar katalog; field kosong diabaikan.
+type CatalogFilter struct {
+	Category        string
+	InStock         bool
+	MaxPoints       *int       // hanya item dengan points_required <= MaxPoints
+	AvailableAt     *time.Time // hanya item yang bisa diredeem pada waktu ini
+	IncludeArchived bool
+}
+
+// ListCatalog returns a page of catalog items matching f, newest first.
+func (r *RewardCatalogRepository) ListCatalog(f CatalogFilter, limit, offset int) ([]model.RewardCatalog, int64, error) {
+	q := r.DB.Model(&model.RewardCatalog{})
+	if !f.IncludeArchived {
+		q = q.Where("archived_at IS NULL")
+	}
+	if f.Category != "" {
+		q = q.Where("category = ?", f.Category)
+	}
+	if f.InStock {
+		q = q.Where("stock > 0")
+	}
+	if f.MaxPoints != nil {
+		q = q.Where("points_required <= ?", *f.MaxPoints)
+	}
+	if f.AvailableAt != nil {
+		q = q.Where("(available_from IS NULL OR available_from <= ?) AND (available_until IS NULL OR available_until > ?)", *f.AvailableAt, *f.AvailableAt)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 	var catalog []model.RewardCatalog
-	err := r.DB.Find(&catalog).Error
-	return catalog, err
+	err := q.Order("id DESC").Limit(limit).Offset(offset).Find(&catalog).Error
+	return catalog, total, err
 }
 
 func (r *RewardCatalogRepository) GetCatalogByID(id uint) (*model.RewardCatalog, error) {
@@ -26,10 +61,45 @@ func (r *RewardCatalogRepository) GetCatalogByID(id uint) (*model.RewardCatalog,
 	return &item, err
 }
 
-func (r *RewardCatalogRepository) RedeemCatalog(id uint) error {
-	return r.DB.Model(&model.RewardCatalog{}).Where("id = ? AND stock > 0", id).UpdateColumn("stock", gorm.Expr("stock - 1")).Error
+// DecrementStock mengurangi stok satu unit dan mengembalikan false bila stok sudah habis.
+func (r *RewardCatalogRepository) DecrementStock(id uint) (bool, error) {
+	res := r.DB.Model(&model.RewardCatalog{}).Where("id = ? AND stock > 0", id).UpdateColumn("stock", gorm.Expr("stock - 1"))
+	return res.RowsAffected == 1, res.Error
+}
+
+// IncrementStock mengembalikan satu unit ke stok, mis. saat reward produk dibatalkan.
+func (r *RewardCatalogRepository) IncrementStock(id uint) error {
+	return r.DB.Model(&model.RewardCatalog{}).Where("id = ?", id).UpdateColumn("stock", gorm.Expr("stock + 1")).Error
+}
+
+// SetStock overwrites the stock counter, e.g. after voucher codes were uploaded.
+func (r *RewardCatalogRepository) SetStock(id uint, stock int64) error {
+	return r.DB.Model(&model.RewardCatalog{}).Where("id = ?", id).UpdateColumn("stock", stock).Error
+}
+
+// ListByType returns all catalog items of one type.
+func (r *RewardCatalogRepository) ListByType(catalogType string) ([]model.RewardCatalog, error) {
+	var catalog []model.RewardCatalog
+	err := r.DB.Where("type = ?", catalogType).Find(&catalog).Error
+	return catalog, err
 }
 
 func (r *RewardCatalogRepository) CreateCatalog(catalog *model.RewardCatalog) error {
 	return r.DB.Create(catalog).Error
 }
+
+// UpdateCatalog writes the editable fields of catalog. Stock is only written when
+// withStock is set so a concurrent redemption is not overwritten by accident.
+func (r *RewardCatalogRepository) UpdateCatalog(catalog *model.RewardCatalog, withStock bool) error {
+	fields := []string{"name", "description", "category", "image_urls", "points_required", "max_per_user", "available_from", "available_until"}
+	if withStock {
+		fields = append(fields, "stock")
+	}
+	return r.DB.Model(catalog).Select(fields).Updates(catalog).Error
+}
+
+// Archive menandai item sebagai diarsipkan dan mengembalikan false bila sudah diarsipkan sebelumnya.
+func (r *RewardCatalogRepository) Archive(id uint, at time.Time) (bool, error) {
+	res := r.DB.Model(&model.RewardCatalog{}).Where("id = ? AND archived_at IS NULL", id).Update("archived_at", at)
+	return res.RowsAffected > 0, res.Error
+}
@@ -14,16 +14,80 @@ func NewRewardRepository(db *gorm.DB) *RewardRepository {
 	return &RewardRepository{DB: db}
 }
 
+func (r *RewardRepository) WithTx(tx *gorm.DB) *RewardRepository {
+	return &RewardRepository{DB: tx}
+}
+
 func (r *RewardRepository) CreateReward(reward *model.Reward) error {
 	return r.DB.Create(reward).Error
 }
 
 func (r *RewardRepository) GetRewardsByUserID(userID uint) ([]model.Reward, error) {
 	var rewards []model.Reward
-	err := r.DB.Where("user_id = ?", userID).Find(&rewards).Error
+	err := r.DB.Preload("VoucherCode").Where("user_id = ?", userID).Find(&rewards).Error
 	return rewards, err
 }
 
+// GetByIdempotencyKey mencari reward yang dibuat user dengan Idempotency-Key tertentu.
+func (r *RewardRepository) GetByIdempotencyKey(userID uint, key string) (*model.Reward, error) {
+	var reward model.Reward
+	err := r.DB.Preload("VoucherCode").Where("user_id = ? AND idempotency_key = ?", userID, key).First(&reward).Error
+	return &reward, err
+}
+
+func (r *RewardRepository) GetByID(id uint) (*model.Reward, error) {
+	var reward model.Reward
+	err := r.DB.Preload("VoucherCode").First(&reward, id).Error
+	return &reward, err
+}
+
+// CountByUserAndCatalog menghitung redeem user untuk satu item katalog, tanpa yang dibatalkan.
+func (r *RewardRepository) CountByUserAndCatalog(userID, catalogID uint) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.Reward{}).
+		Where("user_id = ? AND catalog_id = ? AND status <> ?", userID, catalogID, model.RewardStatusCancelled).
+		Count(&n).Error
+	return n, err
+}
+
+// RewardFilter memfilter daftar reward untuk operator; field kosong diabaikan.
+type RewardFilter struct {
+	Status    string
+	AssetType string
+	CatalogID uint
+	UserID    uint
+}
+
+// List returns rewards matching f, oldest first so the fulfilment queue is worked in order.
+func (r *RewardRepository) List(f RewardFilter, limit, offset int) ([]model.Reward, int64, error) {
+	q := r.DB.Model(&model.Reward{})
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.AssetType != "" {
+		q = q.Where("asset_type = ?", f.AssetType)
+	}
+	if f.CatalogID != 0 {
+		q = q.Where("catalog_id = ?", f.CatalogID)
+	}
+	if f.UserID != 0 {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rewards []model.Reward
+	err := q.Preload("VoucherCode").Order("created_at, id").Limit(limit).Offset(offset).Find(&rewards).Error
+	return rewards, total, err
+}
+
+// UpdateStatusIf applies updates only while the reward is still in status from.
+func (r *RewardRepository) UpdateStatusIf(id uint, from string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.Reward{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *RewardRepository) UpdateRewardStatus(rewardID uint, status string) error {
 	return r.DB.Model(&model.Reward{}).Where("id = ?", rewardID).Update("status", status).Error
 }
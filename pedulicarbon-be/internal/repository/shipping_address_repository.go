@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ShippingAddressRepository struct {
+	DB *gorm.DB
+}
+
+func NewShippingAddressRepository(db *gorm.DB) *ShippingAddressRepository {
+	return &ShippingAddressRepository{DB: db}
+}
+
+func (r *ShippingAddressRepository) WithTx(tx *gorm.DB) *ShippingAddressRepository {
+	return &ShippingAddressRepository{DB: tx}
+}
+
+func (r *ShippingAddressRepository) Create(address *model.ShippingAddress) error {
+	return r.DB.Create(address).Error
+}
+
+func (r *ShippingAddressRepository) Save(address *model.ShippingAddress) error {
+	return r.DB.Save(address).Error
+}
+
+func (r *ShippingAddressRepository) ListByUserID(userID uint) ([]model.ShippingAddress, error) {
+	var addresses []model.ShippingAddress
+	err := r.DB.Where("user_id = ?", userID).Order("is_default DESC, id").Find(&addresses).Error
+	return addresses, err
+}
+
+// GetByID returns the address only if it belongs to userID.
+func (r *ShippingAddressRepository) GetByID(userID, id uint) (*model.ShippingAddress, error) {
+	var address model.ShippingAddress
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	return &address, err
+}
+
+func (r *ShippingAddressRepository) GetDefault(userID uint) (*model.ShippingAddress, error) {
+	var address model.ShippingAddress
+	err := r.DB.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	return &address, err
+}
+
+func (r *ShippingAddressRepository) CountByUserID(userID uint) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.ShippingAddress{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, err
+}
+
+// ClearDefault unsets is_default on every address of the user except keepID.
+func (r *ShippingAddressRepository) ClearDefault(userID, keepID uint) error {
+	return r.DB.Model(&model.ShippingAddress{}).Where("user_id = ? AND id <> ?", userID, keepID).Update("is_default", false).Error
+}
+
+func (r *ShippingAddressRepository) Delete(userID, id uint) (bool, error) {
+	res := r.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.ShippingAddress{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -33,3 +33,39 @@ func (r *UserNFTRepository) GetUserNFTByNFTID(nftID string) (*model.UserNFT, err
 	err := r.DB.Where("nft_id = ?", nftID).First(&userNFT).Error
 	return &userNFT, err
 }
+
+func (r *UserNFTRepository) WithTx(tx *gorm.DB) *UserNFTRepository {
+	return &UserNFTRepository{DB: tx}
+}
+
+// UpdateStatusIf applies updates only while the NFT row is still in status from.
+func (r *UserNFTRepository) UpdateStatusIf(nftID, from string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.UserNFT{}).Where("nft_id = ? AND status = ?", nftID, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// UpdateOwnerIf moves the NFT row to toUserID only while it still belongs to fromUserID.
+func (r *UserNFTRepository) UpdateOwnerIf(nftID string, fromUserID, toUserID uint) (bool, error) {
+	res := r.DB.Model(&model.UserNFT{}).Where("nft_id = ? AND user_id = ?", nftID, fromUserID).Update("user_id", toUserID)
+	return res.RowsAffected > 0, res.Error
+}
+
+// SumCarbonByUserID returns the carbon amount of the NFTs the user still owns.
+func (r *UserNFTRepository) SumCarbonByUserID(userID uint) (float64, error) {
+	var sum float64
+	err := r.DB.Model(&model.UserNFT{}).Where("user_id = ? AND status = ?", userID, "owned").
+		Select("COALESCE(SUM(carbon_amount), 0)").Scan(&sum).Error
+	return sum, err
+}
+
+// BackfillCarbonAmounts fills carbon_amount of rows minted before it was stored,
+// from the mission of the verification saga that minted the NFT.
+func (r *UserNFTRepository) BackfillCarbonAmounts() (int64, error) {
+	source := `FROM verification_sagas s
+		JOIN mission_takens mt ON mt.id = s.mission_taken_id
+		JOIN missions m ON m.id = mt.mission_id
+		WHERE s.nft_id = user_nfts.nft_id AND m.asset_amount > 0`
+	res := r.DB.Exec(`UPDATE user_nfts SET carbon_amount = (SELECT MAX(m.asset_amount) ` + source + `)
+		WHERE carbon_amount = 0 AND EXISTS (SELECT 1 ` + source + `)`)
+	return res.RowsAffected, res.Error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"pedulicarbon/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -24,18 +25,46 @@ func (r *UserRepository) GetUserByEmail(email string) (*model.User, error) {
 	return &user, err
 }
 
+// GetUserByIIPrincipal returns the user that has verified ownership of the principal.
 func (r *UserRepository) GetUserByIIPrincipal(ii string) (*model.User, error) {
 	var user model.User
-	err := r.DB.Where("ii_principal = ?", ii).First(&user).Error
+	err := r.DB.Where("ii_principal = ? AND principal_verified = ?", ii, true).First(&user).Error
 	return &user, err
 }
 
-func (r *UserRepository) UpdateUserPoints(userID uint, points int) error {
-	return r.DB.Model(&model.User{}).Where("id = ?", userID).Update("points", points).Error
-}
-
 func (r *UserRepository) GetUserByID(userID uint) (*model.User, error) {
 	var user model.User
 	err := r.DB.First(&user, userID).Error
 	return &user, err
 }
+
+func (r *UserRepository) SetVerifiedPrincipal(userID uint, ii string) error {
+	return r.DB.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"ii_principal":          ii,
+		"principal_verified":    true,
+		"principal_verified_at": time.Now(),
+	}).Error
+}
+
+func (r *UserRepository) UpdateUserRole(userID uint, role string) error {
+	return r.DB.Model(&model.User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{DB: tx}
+}
+
+// IncrementUserPoints adds delta to the stored balance in a single UPDATE. It
+// returns false when the user does not exist or the balance would go negative.
+// Only the point ledger may call this, so every change has ledger lines.
+func (r *UserRepository) IncrementUserPoints(userID uint, delta int) (bool, error) {
+	res := r.DB.Model(&model.User{}).Where("id = ? AND points + ? >= 0", userID, delta).UpdateColumn("points", gorm.Expr("points + ?", delta))
+	return res.RowsAffected == 1, res.Error
+}
+
+// ListUsersWithVerifiedPrincipal returns every user whose II principal is verified.
+func (r *UserRepository) ListUsersWithVerifiedPrincipal() ([]model.User, error) {
+	var users []model.User
+	err := r.DB.Where("principal_verified = ?", true).Order("id").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type VerificationSagaRepository struct {
+	DB *gorm.DB
+}
+
+func NewVerificationSagaRepository(db *gorm.DB) *VerificationSagaRepository {
+	return &VerificationSagaRepository{DB: db}
+}
+
+func (r *VerificationSagaRepository) WithTx(tx *gorm.DB) *VerificationSagaRepository {
+	return &VerificationSagaRepository{DB: tx}
+}
+
+func (r *VerificationSagaRepository) CreateSaga(saga *model.VerificationSaga) error {
+	return r.DB.Create(saga).Error
+}
+
+func (r *VerificationSagaRepository) GetByMissionTakenID(mtID uint) (*model.VerificationSaga, error) {
+	var saga model.VerificationSaga
+	err := r.DB.Where("mission_taken_id = ?", mtID).First(&saga).Error
+	return &saga, err
+}
+
+func (r *VerificationSagaRepository) GetByID(id uint) (*model.VerificationSaga, error) {
+	var saga model.VerificationSaga
+	err := r.DB.First(&saga, id).Error
+	return &saga, err
+}
+
+// ListUnfinished returns sagas that stopped before reaching a terminal step.
+func (r *VerificationSagaRepository) ListUnfinished() ([]model.VerificationSaga, error) {
+	var sagas []model.VerificationSaga
+	err := r.DB.Where("step NOT IN ?", []string{model.SagaStepCompleted, model.SagaStepFailed, model.SagaStepCompensated}).
+		Order("id").Find(&sagas).Error
+	return sagas, err
+}
+
+// AcquireLease marks the saga as being processed until now+ttl. It returns false
+// when another worker still holds the lease.
+func (r *VerificationSagaRepository) AcquireLease(id uint, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res := r.DB.Model(&model.VerificationSaga{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", id, now).
+		Update("locked_until", now.Add(ttl))
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *VerificationSagaRepository) ReleaseLease(id uint) error {
+	return r.DB.Model(&model.VerificationSaga{}).Where("id = ?", id).Update("locked_until", nil).Error
+}
+
+func (r *VerificationSagaRepository) UpdateSaga(id uint, updates map[string]interface{}) error {
+	return r.DB.Model(&model.VerificationSaga{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// UpdateSagaFromStep applies updates only while the saga is still at step.
+func (r *VerificationSagaRepository) UpdateSagaFromStep(id uint, step string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.VerificationSaga{}).Where("id = ? AND step = ?", id, step).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
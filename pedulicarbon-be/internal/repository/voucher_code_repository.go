@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VoucherCodeRepository struct {
+	DB *gorm.DB
+}
+
+func NewVoucherCodeRepository(db *gorm.DB) *VoucherCodeRepository {
+	return &VoucherCodeRepository{DB: db}
+}
+
+func (r *VoucherCodeRepository) WithTx(tx *gorm.DB) *VoucherCodeRepository {
+	return &VoucherCodeRepository{DB: tx}
+}
+
+// CreateCodes inserts codes and skips those already uploaded for the same catalog.
+// It returns how many rows were inserted.
+func (r *VoucherCodeRepository) CreateCodes(codes []model.VoucherCode) (int64, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+	res := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&codes)
+	return res.RowsAffected, res.Error
+}
+
+func (r *VoucherCodeRepository) CountAvailable(catalogID uint) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.VoucherCode{}).Where("catalog_id = ? AND status = ?", catalogID, model.VoucherStatusAvailable).Count(&n).Error
+	return n, err
+}
+
+// AssignNext gives the oldest available code of the catalog to the reward. It
+// returns gorm.ErrRecordNotFound when no code is left.
+func (r *VoucherCodeRepository) AssignNext(catalogID, rewardID, userID uint) (*model.VoucherCode, error) {
+	var code model.VoucherCode
+	if err := r.DB.Where("catalog_id = ? AND status = ?", catalogID, model.VoucherStatusAvailable).Order("id").First(&code).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	res := r.DB.Model(&model.VoucherCode{}).Where("id = ? AND status = ?", code.ID, model.VoucherStatusAvailable).Updates(map[string]interface{}{
+		"status":      model.VoucherStatusAssigned,
+		"reward_id":   rewardID,
+		"user_id":     userID,
+		"assigned_at": now,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	code.Status, code.RewardID, code.UserID, code.AssignedAt = model.VoucherStatusAssigned, &rewardID, &userID, &now
+	return &code, nil
+}
+
+func (r *VoucherCodeRepository) GetByCode(catalogID uint, code string) (*model.VoucherCode, error) {
+	var v model.VoucherCode
+	err := r.DB.Where("catalog_id = ? AND code = ?", catalogID, code).First(&v).Error
+	return &v, err
+}
+
+// UpdateStatusIf applies updates only while the code is still in status from.
+func (r *VoucherCodeRepository) UpdateStatusIf(id uint, from string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.VoucherCode{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WalletQuarantineRepository struct {
+	DB *gorm.DB
+}
+
+func NewWalletQuarantineRepository(db *gorm.DB) *WalletQuarantineRepository {
+	return &WalletQuarantineRepository{DB: db}
+}
+
+func (r *WalletQuarantineRepository) WithTx(tx *gorm.DB) *WalletQuarantineRepository {
+	return &WalletQuarantineRepository{DB: tx}
+}
+
+func (r *WalletQuarantineRepository) Create(q *model.WalletQuarantine) error {
+	return r.DB.Create(q).Error
+}
+
+// List returns quarantined balances, oldest first; only unresolved ones unless all is set.
+func (r *WalletQuarantineRepository) List(all bool) ([]model.WalletQuarantine, error) {
+	var list []model.WalletQuarantine
+	q := r.DB.Order("id")
+	if !all {
+		q = q.Where("resolved_at IS NULL")
+	}
+	err := q.Find(&list).Error
+	return list, err
+}
+
+// LockByID reads the entry with a row lock held until the transaction ends.
+func (r *WalletQuarantineRepository) LockByID(id uint) (*model.WalletQuarantine, error) {
+	var q model.WalletQuarantine
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&q, id).Error
+	return &q, err
+}
+
+// Resolve marks the entry resolved once; false when it already was.
+func (r *WalletQuarantineRepository) Resolve(id, adminID uint, resolution string, at time.Time) (bool, error) {
+	res := r.DB.Model(&model.WalletQuarantine{}).Where("id = ? AND resolved_at IS NULL", id).
+		Updates(map[string]interface{}{"resolved_at": at, "resolved_by": adminID, "resolution": resolution})
+	return res.RowsAffected > 0, res.Error
+}
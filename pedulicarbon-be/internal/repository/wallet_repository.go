@@ -2,8 +2,10 @@ package repository
 
 import (
 	"pedulicarbon/internal/model"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WalletRepository struct {
@@ -14,16 +16,112 @@ func NewWalletRepository(db *gorm.DB) *WalletRepository {
 	return &WalletRepository{DB: db}
 }
 
+func (r *WalletRepository) WithTx(tx *gorm.DB) *WalletRepository {
+	return &WalletRepository{DB: tx}
+}
+
 func (r *WalletRepository) GetWalletByUserID(userID uint) (*model.Wallet, error) {
 	var wallet model.Wallet
 	err := r.DB.Where("user_id = ?", userID).First(&wallet).Error
 	return &wallet, err
 }
 
-func (r *WalletRepository) CreateWallet(wallet *model.Wallet) error {
-	return r.DB.Create(wallet).Error
+// LockByUserID reads the wallet with a row lock held until the transaction ends, so
+// checks against the user's other withdrawals are serialised per user.
+func (r *WalletRepository) LockByUserID(userID uint) (*model.Wallet, error) {
+	var wallet model.Wallet
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&wallet).Error
+	return &wallet, err
+}
+
+// CreateIfMissing inserts an empty wallet for the user unless one exists, and
+// reports whether it was inserted.
+func (r *WalletRepository) CreateIfMissing(userID uint) (bool, error) {
+	res := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Wallet{UserID: userID})
+	return res.RowsAffected > 0, res.Error
+}
+
+// IncrementRupiah adds delta to the stored balance in a single UPDATE. It returns
+// false when the wallet does not exist or the balance would go negative. Only the
+// rupiah ledger may call this, so every change has ledger lines.
+func (r *WalletRepository) IncrementRupiah(userID uint, delta int64) (bool, error) {
+	res := r.DB.Model(&model.Wallet{}).Where("user_id = ? AND rupiah + ? >= 0", userID, delta).
+		Updates(map[string]interface{}{"rupiah": gorm.Expr("rupiah + ?", delta)})
+	return res.RowsAffected == 1, res.Error
+}
+
+// ListUserIDsWithoutWallet returns users registered before wallets were provisioned.
+func (r *WalletRepository) ListUserIDsWithoutWallet() ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&model.User{}).
+		Where("NOT EXISTS (SELECT 1 FROM wallets w WHERE w.user_id = users.id)").
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// LegacyRupiah is a balance left in the pre-ledger wallets.legacy_rupiah column.
+type LegacyRupiah struct {
+	WalletID uint
+	UserID   uint
+	Amount   string
+}
+
+// ArchiveFloatRupiahColumn renames a pre-ledger floating point wallets.rupiah
+// column to legacy_rupiah, so AutoMigrate adds a fresh integer column instead of
+// casting (and rounding) the old values in place. It reports whether it renamed.
+func (r *WalletRepository) ArchiveFloatRupiahColumn() (bool, error) {
+	m := r.DB.Migrator()
+	if !m.HasTable(&model.Wallet{}) || m.HasColumn(&model.Wallet{}, "legacy_rupiah") {
+		return false, nil
+	}
+	types, err := m.ColumnTypes(&model.Wallet{})
+	if err != nil {
+		return false, err
+	}
+	for _, ct := range types {
+		if ct.Name() != "rupiah" {
+			continue
+		}
+		switch strings.ToLower(ct.DatabaseTypeName()) {
+		case "int8", "bigint", "integer", "int", "int4":
+			return false, nil
+		}
+		return true, m.RenameColumn(&model.Wallet{}, "rupiah", "legacy_rupiah")
+	}
+	return false, nil
+}
+
+// ListLegacyRupiah returns non-zero balances in wallets.legacy_rupiah, exactly as stored.
+func (r *WalletRepository) ListLegacyRupiah() ([]LegacyRupiah, error) {
+	var rows []LegacyRupiah
+	if !r.DB.Migrator().HasColumn(&model.Wallet{}, "legacy_rupiah") {
+		return rows, nil
+	}
+	err := r.DB.Raw(`SELECT id AS wallet_id, user_id, CAST(legacy_rupiah AS TEXT) AS amount FROM wallets
+		WHERE legacy_rupiah IS NOT NULL AND legacy_rupiah <> 0 ORDER BY id`).Scan(&rows).Error
+	return rows, err
+}
+
+// ClearLegacyRupiah empties legacy_rupiah of a wallet after it was quarantined.
+func (r *WalletRepository) ClearLegacyRupiah(walletID uint) error {
+	return r.DB.Exec("UPDATE wallets SET legacy_rupiah = NULL WHERE id = ?", walletID).Error
+}
+
+// ListDuplicateWallets returns every wallet except the oldest one of each user, as
+// raw rows so they can be archived whatever the old schema looked like.
+func (r *WalletRepository) ListDuplicateWallets() ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := r.DB.Raw(`SELECT * FROM wallets w WHERE EXISTS
+		(SELECT 1 FROM wallets d WHERE d.user_id = w.user_id AND d.id < w.id) ORDER BY id`).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *WalletRepository) DeleteByID(id uint) error {
+	return r.DB.Exec("DELETE FROM wallets WHERE id = ?", id).Error
 }
 
-func (r *WalletRepository) UpdateWallet(wallet *model.Wallet) error {
-	return r.DB.Save(wallet).Error
+// SetRupiahIf overwrites the stored balance only while it still equals from.
+func (r *WalletRepository) SetRupiahIf(userID uint, from, to int64) (bool, error) {
+	res := r.DB.Model(&model.Wallet{}).Where("user_id = ? AND rupiah = ?", userID, from).Update("rupiah", to)
+	return res.RowsAffected > 0, res.Error
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type WalletTransactionRepository struct {
+	DB *gorm.DB
+}
+
+func NewWalletTransactionRepository(db *gorm.DB) *WalletTransactionRepository {
+	return &WalletTransactionRepository{DB: db}
+}
+
+func (r *WalletTransactionRepository) WithTx(tx *gorm.DB) *WalletTransactionRepository {
+	return &WalletTransactionRepository{DB: tx}
+}
+
+// CreateEntries inserts the lines of one journal.
+func (r *WalletTransactionRepository) CreateEntries(entries []model.WalletTransaction) error {
+	return r.DB.Create(&entries).Error
+}
+
+// ListByUserID returns a user's ledger lines, newest first, and the total count.
+func (r *WalletTransactionRepository) ListByUserID(userID uint, limit, offset int) ([]model.WalletTransaction, int64, error) {
+	var total int64
+	if err := r.DB.Model(&model.WalletTransaction{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []model.WalletTransaction
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// FindByRef returns the line of account posted for a source record, if any.
+func (r *WalletTransactionRepository) FindByRef(account, entryType, refType, refID string) (*model.WalletTransaction, error) {
+	var entry model.WalletTransaction
+	err := r.DB.Where("account = ? AND entry_type = ? AND ref_type = ? AND ref_id = ?", account, entryType, refType, refID).First(&entry).Error
+	return &entry, err
+}
+
+// SumByAccount returns the balance of an account that has no stored balance, such
+// as a user's withdrawal hold account.
+func (r *WalletTransactionRepository) SumByAccount(account string) (int64, error) {
+	var sum int64
+	err := r.DB.Model(&model.WalletTransaction{}).Where("account = ?", account).
+		Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+	return sum, err
+}
+
+// UnexplainedRupiah is a wallet whose stored balance differs from the ledger sum.
+type UnexplainedRupiah struct {
+	UserID    uint
+	Rupiah    int64
+	LedgerSum int64
+}
+
+// ListUnexplainedBalances returns wallets whose rupiah is not fully explained by ledger lines.
+func (r *WalletTransactionRepository) ListUnexplainedBalances() ([]UnexplainedRupiah, error) {
+	var rows []UnexplainedRupiah
+	err := r.DB.Raw(`SELECT w.user_id AS user_id, w.rupiah AS rupiah, COALESCE(SUM(wt.amount), 0) AS ledger_sum
+		FROM wallets w LEFT JOIN wallet_transactions wt ON wt.user_id = w.user_id
+		GROUP BY w.user_id, w.rupiah
+		HAVING w.rupiah <> COALESCE(SUM(wt.amount), 0)
+		ORDER BY w.user_id`).Scan(&rows).Error
+	return rows, err
+}
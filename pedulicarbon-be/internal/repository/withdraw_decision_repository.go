@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"pedulicarbon/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// WithdrawDecisionRepository hanya bisa menambah entri; audit trail tidak bisa diubah.
+type WithdrawDecisionRepository struct {
+	DB *gorm.DB
+}
+
+func NewWithdrawDecisionRepository(db *gorm.DB) *WithdrawDecisionRepository {
+	return &WithdrawDecisionRepository{DB: db}
+}
+
+func (r *WithdrawDecisionRepository) WithTx(tx *gorm.DB) *WithdrawDecisionRepository {
+	return &WithdrawDecisionRepository{DB: tx}
+}
+
+func (r *WithdrawDecisionRepository) Create(d *model.WithdrawDecision) error {
+	return r.DB.Create(d).Error
+}
+
+// ListByWithdrawID returns the audit trail of a withdrawal, oldest first.
+func (r *WithdrawDecisionRepository) ListByWithdrawID(withdrawID uint) ([]model.WithdrawDecision, error) {
+	var decisions []model.WithdrawDecision
+	err := r.DB.Where("withdraw_id = ?", withdrawID).Order("id").Find(&decisions).Error
+	return decisions, err
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"pedulicarbon/internal/model"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WithdrawRepository struct {
@@ -14,16 +16,85 @@ func NewWithdrawRepository(db *gorm.DB) *WithdrawRepository {
 	return &WithdrawRepository{DB: db}
 }
 
+func (r *WithdrawRepository) WithTx(tx *gorm.DB) *WithdrawRepository {
+	return &WithdrawRepository{DB: tx}
+}
+
 func (r *WithdrawRepository) CreateWithdraw(wd *model.Withdraw) error {
 	return r.DB.Create(wd).Error
 }
 
+func (r *WithdrawRepository) GetByID(id uint) (*model.Withdraw, error) {
+	var wd model.Withdraw
+	err := r.DB.First(&wd, id).Error
+	return &wd, err
+}
+
+// LockByID reads the withdrawal with a row lock held until the transaction ends,
+// so concurrent approvals of the same withdrawal are serialised.
+func (r *WithdrawRepository) LockByID(id uint) (*model.Withdraw, error) {
+	var wd model.Withdraw
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wd, id).Error
+	return &wd, err
+}
+
+// GetWithDecisions returns the withdrawal with its audit trail, oldest decision first.
+func (r *WithdrawRepository) GetWithDecisions(id uint) (*model.Withdraw, error) {
+	var wd model.Withdraw
+	err := r.DB.Preload("Decisions", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&wd, id).Error
+	return &wd, err
+}
+
 func (r *WithdrawRepository) GetUserWithdraws(userID uint) ([]model.Withdraw, error) {
 	var withdraws []model.Withdraw
-	err := r.DB.Where("user_id = ?", userID).Find(&withdraws).Error
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&withdraws).Error
 	return withdraws, err
 }
 
-func (r *WithdrawRepository) UpdateWithdrawStatus(id uint, status string) error {
-	return r.DB.Model(&model.Withdraw{}).Where("id = ?", id).Update("status", status).Error
+func (r *WithdrawRepository) GetByProviderRef(provider, ref string) (*model.Withdraw, error) {
+	var wd model.Withdraw
+	err := r.DB.Where("provider = ? AND provider_ref = ?", provider, ref).First(&wd).Error
+	return &wd, err
+}
+
+// ListByStatus returns one page of withdrawals in status, oldest first, and the
+// number of withdrawals in that status.
+func (r *WithdrawRepository) ListByStatus(status string, limit, offset int) ([]model.Withdraw, int64, error) {
+	var total int64
+	if err := r.DB.Model(&model.Withdraw{}).Where("status = ?", status).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var withdraws []model.Withdraw
+	err := r.DB.Where("status = ?", status).Order("id").Limit(limit).Offset(offset).Find(&withdraws).Error
+	return withdraws, total, err
+}
+
+// SetProviderRef records the provider's disbursement ID once; false when the
+// withdrawal already has one.
+func (r *WithdrawRepository) SetProviderRef(id uint, provider, ref string) (bool, error) {
+	res := r.DB.Model(&model.Withdraw{}).Where("id = ? AND (provider_ref = '' OR provider_ref IS NULL)", id).
+		Updates(map[string]interface{}{"provider": provider, "provider_ref": ref})
+	return res.RowsAffected > 0, res.Error
+}
+
+// SumAmountSince returns the amount the user requested since t, excluding failed withdrawals.
+func (r *WithdrawRepository) SumAmountSince(userID uint, t time.Time) (int64, error) {
+	var sum int64
+	err := r.DB.Model(&model.Withdraw{}).Where("user_id = ? AND created_at >= ? AND status <> ?", userID, t, model.WithdrawStatusFailed).
+		Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+	return sum, err
+}
+
+// CountSince returns how many withdrawals the user requested since t, excluding failed ones.
+func (r *WithdrawRepository) CountSince(userID uint, t time.Time) (int64, error) {
+	var n int64
+	err := r.DB.Model(&model.Withdraw{}).Where("user_id = ? AND created_at >= ? AND status <> ?", userID, t, model.WithdrawStatusFailed).
+		Count(&n).Error
+	return n, err
+}
+
+// UpdateStatusIf applies updates only while the withdrawal is still in status from.
+func (r *WithdrawRepository) UpdateStatusIf(id uint, from string, updates map[string]interface{}) (bool, error) {
+	res := r.DB.Model(&model.Withdraw{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
 }
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAddressNotFound = errors.New("alamat pengiriman tidak ditemukan")
+	ErrInvalidAddress  = errors.New("alamat pengiriman tidak valid")
+	ErrAddressLimit    = fmt.Errorf("maksimal %d alamat pengiriman per user", MaxShippingAddresses)
+)
+
+// MaxShippingAddresses membatasi jumlah alamat yang disimpan satu user.
+const MaxShippingAddresses = 10
+
+var (
+	phonePattern      = regexp.MustCompile(`^(\+62|62|0)8[0-9]{7,12}$`)
+	postalCodePattern = regexp.MustCompile(`^[0-9]{5}$`)
+)
+
+type AddressService struct {
+	AddressRepo *repository.ShippingAddressRepository
+}
+
+func NewAddressService(addressRepo *repository.ShippingAddressRepository) *AddressService {
+	return &AddressService{AddressRepo: addressRepo}
+}
+
+func validateAddress(a *model.ShippingAddress) error {
+	a.RecipientName = strings.TrimSpace(a.RecipientName)
+	a.Phone = strings.ReplaceAll(strings.TrimSpace(a.Phone), " ", "")
+	a.AddressLine = strings.TrimSpace(a.AddressLine)
+	a.City = strings.TrimSpace(a.City)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	switch {
+	case a.RecipientName == "":
+		return fmt.Errorf("%w: recipient_name wajib diisi", ErrInvalidAddress)
+	case !phonePattern.MatchString(a.Phone):
+		return fmt.Errorf("%w: phone harus nomor HP Indonesia, mis. 081234567890", ErrInvalidAddress)
+	case a.AddressLine == "" || a.City == "":
+		return fmt.Errorf("%w: address_line dan city wajib diisi", ErrInvalidAddress)
+	case !postalCodePattern.MatchString(a.PostalCode):
+		return fmt.Errorf("%w: postal_code harus 5 digit", ErrInvalidAddress)
+	}
+	return nil
+}
+
+func (s *AddressService) List(userID uint) ([]model.ShippingAddress, error) {
+	return s.AddressRepo.ListByUserID(userID)
+}
+
+// Create menyimpan alamat baru. Alamat pertama user otomatis menjadi default.
+func (s *AddressService) Create(userID uint, address *model.ShippingAddress) error {
+	if err := validateAddress(address); err != nil {
+		return err
+	}
+	address.ID, address.UserID = 0, userID
+	return s.AddressRepo.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepo.WithTx(tx)
+		n, err := repo.CountByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if n >= MaxShippingAddresses {
+			return ErrAddressLimit
+		}
+		if n == 0 {
+			address.IsDefault = true
+		}
+		if err := repo.Create(address); err != nil {
+			return err
+		}
+		if address.IsDefault {
+			return repo.ClearDefault(userID, address.ID)
+		}
+		return nil
+	})
+}
+
+// Update mengganti isi alamat milik user. Reward yang sudah diredeem tidak ikut
+// berubah karena alamatnya disalin saat redeem.
+func (s *AddressService) Update(userID, id uint, address *model.ShippingAddress) error {
+	if err := validateAddress(address); err != nil {
+		return err
+	}
+	return s.AddressRepo.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepo.WithTx(tx)
+		existing, err := repo.GetByID(userID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAddressNotFound
+		}
+		if err != nil {
+			return err
+		}
+		address.ID, address.UserID, address.CreatedAt = existing.ID, userID, existing.CreatedAt
+		// Default hanya bisa dipindah ke alamat lain, tidak dilepas begitu saja
+		address.IsDefault = address.IsDefault || existing.IsDefault
+		if err := repo.Save(address); err != nil {
+			return err
+		}
+		if address.IsDefault {
+			return repo.ClearDefault(userID, address.ID)
+		}
+		return nil
+	})
+}
+
+// Delete menghapus alamat; bila alamat default yang dihapus, alamat tertua berikutnya menjadi default.
+func (s *AddressService) Delete(userID, id uint) error {
+	return s.AddressRepo.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepo.WithTx(tx)
+		existing, err := repo.GetByID(userID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAddressNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := repo.Delete(userID, id); err != nil {
+			return err
+		}
+		if !existing.IsDefault {
+			return nil
+		}
+		rest, err := repo.ListByUserID(userID)
+		if err != nil || len(rest) == 0 {
+			return err
+		}
+		rest[0].IsDefault = true
+		return repo.Save(&rest[0])
+	})
+}
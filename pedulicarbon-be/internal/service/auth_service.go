@@ -0,0 +1,83 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// AuthService menerbitkan dan memvalidasi access token (JWT HS256).
+type AuthService struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+type TokenClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func NewAuthService(secret string, ttl time.Duration) *AuthService {
+	return &AuthService{Secret: []byte(secret), TTL: ttl}
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// IssueToken returns a signed token for userID together with its expiry time.
+func (s *AuthService) IssueToken(userID uint) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(s.TTL)
+	claims := TokenClaims{
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: exp.Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + s.sign(signingInput), exp, nil
+}
+
+// ParseToken verifies the signature and expiry of token and returns the user ID it was issued for.
+func (s *AuthService) ParseToken(token string) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return 0, ErrInvalidToken
+	}
+	expected := s.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return 0, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
+
+func (s *AuthService) sign(input string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(input))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBeneficiaryNotFound    = errors.New("rekening tujuan tidak ditemukan")
+	ErrInvalidBeneficiary     = errors.New("rekening tujuan tidak valid")
+	ErrBeneficiaryExists      = errors.New("rekening tujuan sudah tersimpan")
+	ErrBeneficiaryLimit       = fmt.Errorf("maksimal %d rekening tujuan per user", MaxBeneficiaries)
+	ErrBeneficiaryCoolingDown = errors.New("rekening tujuan baru belum bisa dipakai")
+	ErrBeneficiaryNotVerified = errors.New("rekening tujuan belum diverifikasi")
+	ErrSelfVerification       = errors.New("tidak boleh memverifikasi rekening milik sendiri")
+)
+
+// MaxBeneficiaries membatasi jumlah rekening tujuan yang disimpan satu user.
+const MaxBeneficiaries = 10
+
+type BeneficiaryService struct {
+	BeneficiaryRepo *repository.BeneficiaryRepository
+	Notifications   *NotificationService
+	// Cooldown adalah jeda sejak rekening ditambahkan sampai boleh menerima penarikan.
+	Cooldown time.Duration
+}
+
+func NewBeneficiaryService(repo *repository.BeneficiaryRepository, notifications *NotificationService, cooldown time.Duration) *BeneficiaryService {
+	return &BeneficiaryService{BeneficiaryRepo: repo, Notifications: notifications, Cooldown: cooldown}
+}
+
+// validateBeneficiary menormalkan rekening dengan aturan yang sama dengan
+// target penarikan: bank:<kode bank>:<nomor rekening> atau <ewallet>:<nomor HP>.
+func validateBeneficiary(b *model.Beneficiary) error {
+	b.Label = strings.TrimSpace(b.Label)
+	b.HolderName = strings.Join(strings.Fields(b.HolderName), " ")
+	if b.HolderName == "" || len(b.HolderName) > 100 {
+		return fmt.Errorf("%w: holder_name wajib diisi, maksimal 100 karakter", ErrInvalidBeneficiary)
+	}
+	account := strings.ReplaceAll(strings.TrimSpace(b.AccountNumber), " ", "")
+	var target string
+	switch strings.ToLower(strings.TrimSpace(b.Channel)) {
+	case model.WithdrawChannelBank:
+		target = model.WithdrawChannelBank + ":" + strings.TrimSpace(b.Provider) + ":" + account
+	case model.WithdrawChannelEWallet:
+		target = strings.TrimSpace(b.Provider) + ":" + account
+	default:
+		return fmt.Errorf("%w: channel harus bank atau ewallet", ErrInvalidBeneficiary)
+	}
+	channel, normalized, err := ParseWithdrawTarget(target)
+	if err != nil || channel != strings.ToLower(strings.TrimSpace(b.Channel)) {
+		return fmt.Errorf("%w: gunakan kode bank + nomor rekening 6-20 digit, atau gopay/ovo/dana/shopeepay + nomor HP", ErrInvalidBeneficiary)
+	}
+	parts := strings.Split(normalized, ":")
+	b.Channel = channel
+	b.Provider, b.AccountNumber = parts[len(parts)-2], parts[len(parts)-1]
+	return nil
+}
+
+// maskAccount menyisakan 4 digit terakhir untuk ditampilkan di notifikasi.
+func maskAccount(account string) string {
+	if len(account) <= 4 {
+		return account
+	}
+	return strings.Repeat("*", len(account)-4) + account[len(account)-4:]
+}
+
+func (s *BeneficiaryService) List(userID uint) ([]model.Beneficiary, error) {
+	return s.BeneficiaryRepo.ListByUserID(userID)
+}
+
+// Create menyimpan rekening tujuan baru. Rekening baru belum terverifikasi dan
+// baru bisa dipakai setelah Cooldown; user diberi notifikasi supaya penambahan
+// oleh pihak lain cepat ketahuan.
+func (s *BeneficiaryService) Create(userID uint, b *model.Beneficiary) error {
+	if err := validateBeneficiary(b); err != nil {
+		return err
+	}
+	now := time.Now()
+	b.ID, b.UserID = 0, userID
+	b.Verified, b.VerifiedAt, b.VerifiedBy, b.RemovedAt = false, nil, nil, nil
+	b.ActiveAt = now.Add(s.Cooldown)
+	err := s.BeneficiaryRepo.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.BeneficiaryRepo.WithTx(tx)
+		n, err := repo.CountByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if n >= MaxBeneficiaries {
+			return ErrBeneficiaryLimit
+		}
+		exists, err := repo.ExistsAccount(userID, b.Channel, b.Provider, b.AccountNumber)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrBeneficiaryExists
+		}
+		return repo.Create(b)
+	})
+	if err != nil {
+		return err
+	}
+	s.notifyChange(userID, "Rekening tujuan ditambahkan",
+		fmt.Sprintf("Rekening %s %s a.n. %s ditambahkan dan bisa dipakai mulai %s. Jika bukan Anda, segera hubungi kami.",
+			b.Provider, maskAccount(b.AccountNumber), b.HolderName, b.ActiveAt.Format("02 Jan 2006 15:04")))
+	return nil
+}
+
+// Remove menghapus rekening dari daftar user. Penarikan yang sudah dibuat tetap
+// merujuk ke rekening ini.
+func (s *BeneficiaryService) Remove(userID, id uint) error {
+	b, err := s.BeneficiaryRepo.GetByID(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrBeneficiaryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	ok, err := s.BeneficiaryRepo.Remove(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBeneficiaryNotFound
+	}
+	s.notifyChange(userID, "Rekening tujuan dihapus",
+		fmt.Sprintf("Rekening %s %s dihapus dari daftar rekening tujuan.", b.Provider, maskAccount(b.AccountNumber)))
+	return nil
+}
+
+// Verify menandai rekening sudah dicek (nama pemilik cocok) oleh finance admin.
+// Admin tidak boleh memverifikasi rekeningnya sendiri, sama seperti Approve pada
+// penarikan.
+func (s *BeneficiaryService) Verify(adminID, id uint) (*model.Beneficiary, error) {
+	b, err := s.BeneficiaryRepo.FindAny(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && b.RemovedAt != nil) {
+		return nil, ErrBeneficiaryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.UserID == adminID {
+		return nil, ErrSelfVerification
+	}
+	ok, err := s.BeneficiaryRepo.MarkVerified(id, adminID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBeneficiaryNotFound
+	}
+	return s.BeneficiaryRepo.FindAny(id)
+}
+
+// ForWithdraw mengambil rekening user yang boleh menerima penarikan saat ini.
+func (s *BeneficiaryService) ForWithdraw(userID, id uint, requireVerified bool) (*model.Beneficiary, error) {
+	b, err := s.BeneficiaryRepo.GetByID(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBeneficiaryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(b.ActiveAt) {
+		return nil, fmt.Errorf("%w: bisa dipakai mulai %s", ErrBeneficiaryCoolingDown, b.ActiveAt.Format(time.RFC3339))
+	}
+	if requireVerified && !b.Verified {
+		return nil, ErrBeneficiaryNotVerified
+	}
+	return b, nil
+}
+
+func (s *BeneficiaryService) notifyChange(userID uint, title, message string) {
+	if s.Notifications == nil {
+		return
+	}
+	if _, err := s.Notifications.Notify(userID, model.NotificationBeneficiaryChanged, title, message, ""); err != nil {
+		fmt.Printf("[ERROR] Notify beneficiary change user %d error: %v\n", userID, err)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"testing"
+	"time"
+)
+
+func TestBeneficiaryValidationAndCooldown(t *testing.T) {
+	db := newTestDB(t)
+	s, user := newTestWithdrawService(t, db)
+	s.Beneficiaries.Cooldown = 24 * time.Hour
+
+	invalid := []model.Beneficiary{
+		{Channel: "bank", Provider: "BCA", AccountNumber: "12ab", HolderName: "Alice"},
+		{Channel: "bank", Provider: "BCA", AccountNumber: "1234567890"},
+		{Channel: "ewallet", Provider: "linkaja", AccountNumber: "081234567890", HolderName: "Alice"},
+		{Channel: "ewallet", Provider: "BCA", AccountNumber: "1234567890", HolderName: "Alice"},
+		{Channel: "crypto", Provider: "btc", AccountNumber: "1234567890", HolderName: "Alice"},
+	}
+	for _, b := range invalid {
+		if err := s.Beneficiaries.Create(user.ID, &b); !errors.Is(err, ErrInvalidBeneficiary) {
+			t.Errorf("Create(%+v) err = %v, want ErrInvalidBeneficiary", b, err)
+		}
+	}
+
+	bank := &model.Beneficiary{Channel: "bank", Provider: "bca", AccountNumber: "1234 567 890", HolderName: " Alice  Wijaya "}
+	if err := s.Beneficiaries.Create(user.ID, bank); err != nil {
+		t.Fatal(err)
+	}
+	if bank.Target() != "bank:BCA:1234567890" || bank.HolderName != "Alice Wijaya" || bank.Verified {
+		t.Fatalf("bank beneficiary = %+v", bank)
+	}
+	dup := &model.Beneficiary{Channel: "bank", Provider: "BCA", AccountNumber: "1234567890", HolderName: "Alice"}
+	if err := s.Beneficiaries.Create(user.ID, dup); !errors.Is(err, ErrBeneficiaryExists) {
+		t.Fatalf("duplicate err = %v, want ErrBeneficiaryExists", err)
+	}
+	var notified int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND type = ?", user.ID, model.NotificationBeneficiaryChanged).Count(&notified)
+	if notified != 1 {
+		t.Fatalf("notifications = %d, want 1", notified)
+	}
+
+	// Rekening baru belum bisa dipakai selama cool-down
+	if _, err := s.CreateWithdraw(user.ID, 20000, bank.ID); !errors.Is(err, ErrBeneficiaryCoolingDown) {
+		t.Fatalf("withdraw during cool-down err = %v, want ErrBeneficiaryCoolingDown", err)
+	}
+	db.Model(bank).Update("active_at", time.Now().Add(-time.Minute))
+	s.Policy.RequireVerifiedBeneficiary = true
+	if _, err := s.CreateWithdraw(user.ID, 20000, bank.ID); !errors.Is(err, ErrBeneficiaryNotVerified) {
+		t.Fatalf("unverified err = %v, want ErrBeneficiaryNotVerified", err)
+	}
+	if _, err := s.Beneficiaries.Verify(user.ID, bank.ID); !errors.Is(err, ErrSelfVerification) {
+		t.Fatalf("self verify err = %v, want ErrSelfVerification", err)
+	}
+	if b, err := s.Beneficiaries.Verify(99, bank.ID); err != nil || !b.Verified || b.VerifiedBy == nil || *b.VerifiedBy != 99 {
+		t.Fatalf("verify = %+v, %v", b, err)
+	}
+	wd, err := s.CreateWithdraw(user.ID, 20000, bank.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.BeneficiaryID == nil || *wd.BeneficiaryID != bank.ID || wd.Target != "bank:BCA:1234567890" || wd.HolderName != "Alice Wijaya" {
+		t.Fatalf("withdraw = %+v", wd)
+	}
+
+	// Rekening user lain dan rekening yang dihapus tidak bisa dipakai
+	if _, err := s.CreateWithdraw(user.ID+1, 20000, bank.ID); !errors.Is(err, ErrBeneficiaryNotFound) {
+		t.Fatalf("other user's beneficiary err = %v, want ErrBeneficiaryNotFound", err)
+	}
+	if err := s.Beneficiaries.Remove(user.ID, bank.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateWithdraw(user.ID, 20000, bank.ID); !errors.Is(err, ErrBeneficiaryNotFound) {
+		t.Fatalf("removed beneficiary err = %v, want ErrBeneficiaryNotFound", err)
+	}
+	if list, _ := s.Beneficiaries.List(user.ID); len(list) != 0 {
+		t.Fatalf("beneficiaries after remove = %+v", list)
+	}
+}
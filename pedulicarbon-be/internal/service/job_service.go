@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultJobWorkers     = 4
+	DefaultJobMaxAttempts = 8
+
+	jobLease        = 2 * time.Minute
+	jobPollInterval = time.Second
+	jobBackoffBase  = 5 * time.Second
+	jobBackoffMax   = 10 * time.Minute
+)
+
+// PermanentError marks a job failure that must not be retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err so the job goes straight to the dead-letter state.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// JobHandler processes one job type. OnDead, if set, runs once the job is
+// dead-lettered so the handler can undo any reservation made at enqueue time.
+type JobHandler struct {
+	Run    func(ctx context.Context, payload []byte) (interface{}, error)
+	OnDead func(payload []byte, err error)
+}
+
+// JobService menjalankan antrian job canister berbasis tabel jobs di Postgres.
+type JobService struct {
+	JobRepo     *repository.JobRepository
+	MaxAttempts int
+	handlers    map[string]JobHandler
+}
+
+func NewJobService(repo *repository.JobRepository) *JobService {
+	return &JobService{
+		JobRepo:     repo,
+		MaxAttempts: DefaultJobMaxAttempts,
+		handlers:    map[string]JobHandler{},
+	}
+}
+
+// Register sets the handler for jobType. It must be called before Start.
+func (s *JobService) Register(jobType string, h JobHandler) {
+	s.handlers[jobType] = h
+}
+
+// Enqueue stores a new job. When dedupKey is set and an active job with the same
+// key exists, that job is returned instead of queuing a duplicate.
+func (s *JobService) Enqueue(jobType, dedupKey string, payload interface{}, createdBy uint) (*model.Job, error) {
+	if dedupKey != "" {
+		existing, err := s.JobRepo.GetActiveByDedupKey(dedupKey)
+		if err == nil {
+			return existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.Job{
+		Type:        jobType,
+		DedupKey:    dedupKey,
+		Payload:     string(data),
+		Status:      model.JobStatusQueued,
+		MaxAttempts: s.MaxAttempts,
+		RunAt:       time.Now(),
+		CreatedBy:   createdBy,
+	}
+	if err := s.JobRepo.CreateJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *JobService) GetOperation(id uint) (*model.Job, error) {
+	return s.JobRepo.GetByID(id)
+}
+
+// Start launches workers that poll the job table until ctx is cancelled.
+func (s *JobService) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go s.worker(ctx)
+	}
+}
+
+func (s *JobService) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		job, err := s.JobRepo.ClaimNext(jobLease)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Printf("[ERROR] ClaimNext job error: %v\n", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+		s.process(ctx, job)
+	}
+}
+
+func (s *JobService) process(ctx context.Context, job *model.Job) {
+	h, ok := s.handlers[job.Type]
+	if !ok {
+		s.dead(job, h, fmt.Errorf("no handler for job type %s", job.Type))
+		return
+	}
+	// Lease yang kadaluarsa bisa membuat attempts melewati batas tanpa sempat retry
+	if job.Attempts > job.MaxAttempts {
+		s.dead(job, h, fmt.Errorf("max attempts exceeded: %s", job.LastError))
+		return
+	}
+
+	result, err := h.Run(ctx, []byte(job.Payload))
+	if err == nil {
+		data, _ := json.Marshal(result)
+		if err := s.JobRepo.MarkSucceeded(job.ID, string(data)); err != nil {
+			fmt.Printf("[ERROR] MarkSucceeded job %d error: %v\n", job.ID, err)
+		}
+		return
+	}
+
+	var perm *PermanentError
+	if errors.As(err, &perm) || job.Attempts >= job.MaxAttempts {
+		s.dead(job, h, err)
+		return
+	}
+	runAt := time.Now().Add(jobBackoff(job.Attempts))
+	fmt.Printf("[DEBUG] Job %d (%s) attempt %d failed, retry at %s: %v\n", job.ID, job.Type, job.Attempts, runAt.Format(time.RFC3339), err)
+	if err := s.JobRepo.MarkRetry(job.ID, runAt, err.Error()); err != nil {
+		fmt.Printf("[ERROR] MarkRetry job %d error: %v\n", job.ID, err)
+	}
+}
+
+func (s *JobService) dead(job *model.Job, h JobHandler, cause error) {
+	fmt.Printf("[ERROR] Job %d (%s) dead after %d attempts: %v\n", job.ID, job.Type, job.Attempts, cause)
+	if err := s.JobRepo.MarkDead(job.ID, cause.Error()); err != nil {
+		fmt.Printf("[ERROR] MarkDead job %d error: %v\n", job.ID, err)
+		return
+	}
+	if h.OnDead != nil {
+		h.OnDead([]byte(job.Payload), cause)
+	}
+}
+
+// jobBackoff returns the delay before retry number attempt: 5s, 10s, 20s, ... capped at 10 minutes.
+func jobBackoff(attempt int) time.Duration {
+	d := jobBackoffBase
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= jobBackoffMax {
+			return jobBackoffMax
+		}
+	}
+	return d
+}
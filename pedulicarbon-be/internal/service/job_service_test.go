@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		3:  20 * time.Second,
+		7:  320 * time.Second,
+		8:  jobBackoffMax,
+		50: jobBackoffMax,
+	}
+	for attempt, want := range cases {
+		if got := jobBackoff(attempt); got != want {
+			t.Errorf("jobBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	err := Permanent(ErrInvalidTransition)
+	var perm *PermanentError
+	if !errors.As(err, &perm) {
+		t.Fatal("Permanent error not detected by errors.As")
+	}
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatal("Permanent error does not unwrap to cause")
+	}
+	if Permanent(nil) != nil {
+		t.Fatal("Permanent(nil) should be nil")
+	}
+}
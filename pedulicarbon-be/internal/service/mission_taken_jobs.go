@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"pedulicarbon/internal/motoko"
+
+	"gorm.io/gorm"
+)
+
+type verifyMissionPayload struct {
+	MissionTakenID uint `json:"mission_taken_id"`
+	VerifierID     uint `json:"verifier_id"`
+}
+
+type claimNFTPayload struct {
+	NFTID string `json:"nft_id"`
+}
+
+func (s *MissionTakenService) runVerifyMissionJob(ctx context.Context, payload []byte) (interface{}, error) {
+	var p verifyMissionPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, Permanent(err)
+	}
+	if err := s.VerifyMission(p.MissionTakenID, p.VerifierID); err != nil {
+		if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrPrincipalNotVerified) ||
+			errors.Is(err, ErrCanisterRejected) || errors.Is(err, gorm.ErrRecordNotFound) ||
+			motoko.IsPermanent(err) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+	saga, err := s.SagaRepo.GetByMissionTakenID(p.MissionTakenID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"mission_taken_id": p.MissionTakenID, "nft_id": saga.NFTID}, nil
+}
+
+func (s *MissionTakenService) runClaimNFTJob(ctx context.Context, payload []byte) (interface{}, error) {
+	var p claimNFTPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, Permanent(err)
+	}
+	if err := s.claimNFT(ctx, p.NFTID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || motoko.IsPermanent(err) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+	return map[string]interface{}{"nft_id": p.NFTID, "status": "claimed"}, nil
+}
+
+func (s *MissionTakenService) onClaimNFTJobDead(payload []byte, _ error) {
+	var p claimNFTPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return
+	}
+	s.releaseClaim(p.NFTID)
+}
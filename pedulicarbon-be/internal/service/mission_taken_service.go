@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/motoko"
@@ -9,25 +10,71 @@ import (
 	"time"
 )
 
+var (
+	ErrNotOwner          = errors.New("mission bukan milik user ini")
+	ErrInvalidTransition = errors.New("perubahan status mission tidak diizinkan")
+	ErrResubmitLimit     = errors.New("batas pengajuan ulang bukti sudah habis")
+	ErrReasonRequired    = errors.New("alasan penolakan wajib diisi")
+	ErrCanisterRejected  = errors.New("mission verification failed on canister")
+	ErrNFTNotFound       = errors.New("NFT tidak ditemukan")
+	ErrNFTAlreadyClaimed = errors.New("NFT sudah claimed")
+)
+
+// DefaultMaxResubmissions is how often a rejected submission may be resubmitted.
+const DefaultMaxResubmissions = 3
+
+// missionTakenTransitions lists the allowed status changes of a MissionTaken.
+var missionTakenTransitions = map[string][]string{
+	model.MissionStatusTaken:    {model.MissionStatusPending},
+	model.MissionStatusPending:  {model.MissionStatusVerified, model.MissionStatusRejected},
+	model.MissionStatusRejected: {model.MissionStatusPending},
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range missionTakenTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
 type MissionTakenService struct {
 	MissionTakenRepo *repository.MissionTakenRepository
 	UserRepo         *repository.UserRepository
 	MissionRepo      *repository.MissionRepository
-	MotokoClient     *motoko.MotokoClient
+	Canister         motoko.CanisterClient
 	UserNFTRepo      *repository.UserNFTRepository
+	SagaRepo         *repository.VerificationSagaRepository
+	Jobs             *JobService
+	Points           *PointService
+	MaxResubmissions int
 }
 
-func NewMissionTakenService(repo *repository.MissionTakenRepository, userRepo *repository.UserRepository, missionRepo *repository.MissionRepository, motokoClient *motoko.MotokoClient, userNFTRepo *repository.UserNFTRepository) *MissionTakenService {
-	return &MissionTakenService{
+func NewMissionTakenService(repo *repository.MissionTakenRepository, userRepo *repository.UserRepository, missionRepo *repository.MissionRepository, canister motoko.CanisterClient, userNFTRepo *repository.UserNFTRepository, sagaRepo *repository.VerificationSagaRepository, jobs *JobService, points *PointService) *MissionTakenService {
+	s := &MissionTakenService{
 		MissionTakenRepo: repo,
 		UserRepo:         userRepo,
 		MissionRepo:      missionRepo,
-		MotokoClient:     motokoClient,
+		Canister:         canister,
 		UserNFTRepo:      userNFTRepo,
+		SagaRepo:         sagaRepo,
+		Jobs:             jobs,
+		Points:           points,
+		MaxResubmissions: DefaultMaxResubmissions,
 	}
+	jobs.Register(model.JobTypeVerifyMission, JobHandler{Run: s.runVerifyMissionJob})
+	jobs.Register(model.JobTypeClaimNFT, JobHandler{Run: s.runClaimNFTJob, OnDead: s.onClaimNFTJobDead})
+	return s
 }
 
 func (s *MissionTakenService) TakeMission(mt *model.MissionTaken) error {
+	// Status awal selalu "taken", abaikan field lain dari request
+	*mt = model.MissionTaken{
+		UserID:    mt.UserID,
+		MissionID: mt.MissionID,
+		Status:    model.MissionStatusTaken,
+	}
 	return s.MissionTakenRepo.TakeMission(mt)
 }
 
@@ -35,131 +82,169 @@ func (s *MissionTakenService) GetUserMissions(userID uint) ([]model.MissionTaken
 	return s.MissionTakenRepo.GetUserMissions(userID)
 }
 
-func (s *MissionTakenService) UpdateProof(mtID uint, proofURL, gps string) error {
-	return s.MissionTakenRepo.UpdateProof(mtID, proofURL, gps)
-}
-
-func (s *MissionTakenService) VerifyMission(mtID uint) error {
-	// Ambil data MissionTaken, User, Mission
+func (s *MissionTakenService) UpdateProof(userID, mtID uint, proofURL, gps string) error {
 	mt, err := s.MissionTakenRepo.GetByID(mtID)
 	if err != nil {
-		fmt.Printf("[ERROR] GetByID error: %v\n", err)
 		return err
 	}
-	user, err := s.UserRepo.GetUserByID(mt.UserID)
+	if mt.UserID != userID {
+		return ErrNotOwner
+	}
+	if !canTransition(mt.Status, model.MissionStatusPending) {
+		return ErrInvalidTransition
+	}
+	updates := map[string]interface{}{
+		"proof_url": proofURL,
+		"gps":       gps,
+		"status":    model.MissionStatusPending,
+	}
+	if mt.Status == model.MissionStatusRejected {
+		if mt.ResubmitCount >= s.MaxResubmissions {
+			return ErrResubmitLimit
+		}
+		updates["resubmit_count"] = mt.ResubmitCount + 1
+	}
+	ok, err := s.MissionTakenRepo.UpdateStatus(mtID, mt.Status, updates)
 	if err != nil {
-		fmt.Printf("[ERROR] GetUserByID error: %v\n", err)
 		return err
 	}
-	if user.IIPrincipal == "" {
-		fmt.Println("[ERROR] user belum punya ii_principal (ICP principal)")
-		return fmt.Errorf("user belum punya ii_principal (ICP principal)")
+	if !ok {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// RejectMission menolak bukti yang sedang pending dan mencatat verifier serta alasannya.
+func (s *MissionTakenService) RejectMission(mtID, verifierID uint, reason string) error {
+	if reason == "" {
+		return ErrReasonRequired
 	}
-	mission, err := s.MissionRepo.GetMissionByID(mt.MissionID)
+	mt, err := s.MissionTakenRepo.GetByID(mtID)
 	if err != nil {
-		fmt.Printf("[ERROR] GetMissionByID error: %v\n", err)
 		return err
 	}
-
-	// Step 1: Verify Action di Motoko
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	fmt.Printf("[DEBUG] Calling VerifyAction for user: %s, mission: %d, proof: %s, gps: %s\n",
-		user.IIPrincipal, mt.MissionID, mt.ProofURL, mt.GPS)
-
-	verified, err := s.MotokoClient.VerifyAction(ctx, user.IIPrincipal, mt.MissionID, mt.ProofURL, mt.GPS)
+	if !canTransition(mt.Status, model.MissionStatusRejected) {
+		return ErrInvalidTransition
+	}
+	ok, err := s.MissionTakenRepo.UpdateStatus(mtID, mt.Status, map[string]interface{}{
+		"status":           model.MissionStatusRejected,
+		"rejection_reason": reason,
+		"rejected_by":      verifierID,
+		"rejected_at":      time.Now(),
+	})
 	if err != nil {
-		fmt.Printf("[ERROR] VerifyAction error: %v\n", err)
 		return err
 	}
-
-	if !verified {
-		fmt.Printf("[ERROR] Mission verification failed on canister\n")
-		return fmt.Errorf("mission verification failed on canister")
+	if !ok {
+		return ErrInvalidTransition
 	}
+	return nil
+}
 
-	fmt.Printf("[DEBUG] Mission verified on canister: %v\n", verified)
+// RequestVerification memvalidasi MissionTaken lalu mengantrikan VerifyMission ke worker.
+func (s *MissionTakenService) RequestVerification(mtID, verifierID uint) (*model.Job, error) {
+	if _, err := s.checkVerifiable(mtID); err != nil {
+		return nil, err
+	}
+	return s.Jobs.Enqueue(model.JobTypeVerifyMission, fmt.Sprintf("verify-mission-taken-%d", mtID),
+		verifyMissionPayload{MissionTakenID: mtID, VerifierID: verifierID}, verifierID)
+}
 
-	// Step 2: Update status di DB
-	err = s.MissionTakenRepo.VerifyMission(mtID)
+// VerifyMission memverifikasi bukti mission dan memberi reward. Prosesnya dicatat
+// sebagai VerificationSaga sehingga kegagalan di tengah jalan bisa dilanjutkan.
+func (s *MissionTakenService) VerifyMission(mtID, verifierID uint) error {
+	mt, err := s.checkVerifiable(mtID)
 	if err != nil {
-		fmt.Printf("[ERROR] VerifyMissionRepo error: %v\n", err)
 		return err
 	}
-
-	// Step 3: Mint NFT ke Motoko
-	nftID, err := s.MotokoClient.MintNFT(ctx, user.IIPrincipal, mt.MissionID, mission.AssetAmount)
+	saga, err := s.startVerificationSaga(mt, verifierID)
 	if err != nil {
-		fmt.Printf("[ERROR] MintNFT error: %v\n", err)
 		return err
 	}
+	return s.runVerificationSaga(saga.ID)
+}
 
-	fmt.Printf("[DEBUG] NFT minted successfully: %s\n", nftID)
-
-	// Step 4: Simpan mapping NFT ke user
-	userNFT := &model.UserNFT{
-		UserID: user.ID,
-		NFTID:  nftID,
-		Status: "owned",
+func (s *MissionTakenService) checkVerifiable(mtID uint) (*model.MissionTaken, error) {
+	mt, err := s.MissionTakenRepo.GetByID(mtID)
+	if err != nil {
+		fmt.Printf("[ERROR] GetByID error: %v\n", err)
+		return nil, err
 	}
-	if err := s.UserNFTRepo.CreateUserNFT(userNFT); err != nil {
-		fmt.Printf("[ERROR] CreateUserNFT error: %v\n", err)
-		return err
+	if !canTransition(mt.Status, model.MissionStatusVerified) {
+		fmt.Printf("[ERROR] MissionTaken %d status %s tidak bisa diverifikasi\n", mtID, mt.Status)
+		return nil, ErrInvalidTransition
 	}
-
-	// Step 5: Tambah point ke user
-	if mission.Points > 0 {
-		// Reload user dari DB untuk dapat point terbaru
-		userLatest, err := s.UserRepo.GetUserByID(user.ID)
-		if err != nil {
-			fmt.Printf("[ERROR] GetUserByID (latest) error: %v\n", err)
-			return err
-		}
-		newPoints := userLatest.Points + mission.Points
-		if err := s.UserRepo.UpdateUserPoints(user.ID, newPoints); err != nil {
-			fmt.Printf("[ERROR] UpdateUserPoints error: %v\n", err)
-			return err
-		}
-		fmt.Printf("[DEBUG] User %d point updated: %d -> %d\n", user.ID, userLatest.Points, newPoints)
-	} else {
-		// If mission has no points, add default points
-		userLatest, err := s.UserRepo.GetUserByID(user.ID)
-		if err != nil {
-			fmt.Printf("[ERROR] GetUserByID (latest) error: %v\n", err)
-			return err
-		}
-		newPoints := userLatest.Points + 10 // Default 10 points
-		if err := s.UserRepo.UpdateUserPoints(user.ID, newPoints); err != nil {
-			fmt.Printf("[ERROR] UpdateUserPoints error: %v\n", err)
-			return err
-		}
-		fmt.Printf("[DEBUG] User %d point updated (default): %d -> %d\n", user.ID, userLatest.Points, newPoints)
+	user, err := s.UserRepo.GetUserByID(mt.UserID)
+	if err != nil {
+		fmt.Printf("[ERROR] GetUserByID error: %v\n", err)
+		return nil, err
+	}
+	if user.IIPrincipal == "" || !user.PrincipalVerified {
+		fmt.Println("[ERROR] user belum memverifikasi ii_principal (ICP principal)")
+		return nil, ErrPrincipalNotVerified
 	}
+	return mt, nil
+}
 
-	fmt.Printf("[DEBUG] Mission verification completed successfully\n")
-	return nil
+// RequestClaim menandai NFT sebagai "claiming" lalu mengantrikan burn di canister.
+// Job yang dikembalikan dipakai client sebagai operation ID. NFT milik user lain
+// diperlakukan sama dengan NFT yang tidak ada.
+func (s *MissionTakenService) RequestClaim(userID uint, nftID string, certificateURL string) (*model.Job, error) {
+	userNFT, err := s.UserNFTRepo.GetUserNFTByNFTID(nftID)
+	if err != nil || userNFT.UserID != userID {
+		return nil, ErrNFTNotFound
+	}
+	ok, err := s.UserNFTRepo.UpdateStatusIf(nftID, "owned", map[string]interface{}{
+		"status":          "claiming",
+		"claimed_by":      userID,
+		"certificate_url": certificateURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNFTAlreadyClaimed
+	}
+	job, err := s.Jobs.Enqueue(model.JobTypeClaimNFT, "claim-nft-"+nftID, claimNFTPayload{NFTID: nftID}, userID)
+	if err != nil {
+		s.releaseClaim(nftID)
+		return nil, err
+	}
+	return job, nil
 }
 
-func (s *MissionTakenService) ClaimNFT(userID uint, nftID string, certificateURL string) error {
-	// Ambil user_nft by NFTID
+// claimNFT burns the NFT on the canister and finalises the claim made by RequestClaim.
+func (s *MissionTakenService) claimNFT(ctx context.Context, nftID string) error {
 	userNFT, err := s.UserNFTRepo.GetUserNFTByNFTID(nftID)
 	if err != nil {
-		return fmt.Errorf("NFT tidak ditemukan")
+		return err
 	}
-	if userNFT.Status != "owned" {
-		return fmt.Errorf("NFT sudah claimed")
+	if userNFT.Status == "claimed" {
+		return nil
+	}
+	if userNFT.Status != "claiming" {
+		return Permanent(fmt.Errorf("NFT %s berstatus %s, bukan claiming", nftID, userNFT.Status))
 	}
 	// Burn NFT di Motoko
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
-	if err := s.MotokoClient.BurnNFT(ctx, nftID); err != nil {
+	if err := s.Canister.BurnNFT(ctx, nftID); err != nil {
 		return err
 	}
-	now := time.Now()
-	userNFT.Status = "claimed"
-	userNFT.ClaimedBy = &userID
-	userNFT.ClaimedAt = &now
-	userNFT.CertificateURL = certificateURL
-	return s.UserNFTRepo.UpdateUserNFT(userNFT)
+	_, err = s.UserNFTRepo.UpdateStatusIf(nftID, "claiming", map[string]interface{}{
+		"status":     "claimed",
+		"claimed_at": time.Now(),
+	})
+	return err
+}
+
+// releaseClaim mengembalikan NFT ke "owned" bila claim gagal permanen.
+func (s *MissionTakenService) releaseClaim(nftID string) {
+	if _, err := s.UserNFTRepo.UpdateStatusIf(nftID, "claiming", map[string]interface{}{
+		"status":          "owned",
+		"claimed_by":      nil,
+		"certificate_url": "",
+	}); err != nil {
+		fmt.Printf("[ERROR] releaseClaim %s error: %v\n", nftID, err)
+	}
 }
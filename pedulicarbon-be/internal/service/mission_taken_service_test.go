@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/motoko"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{model.MissionStatusTaken, model.MissionStatusPending, true},
+		{model.MissionStatusPending, model.MissionStatusVerified, true},
+		{model.MissionStatusPending, model.MissionStatusRejected, true},
+		{model.MissionStatusRejected, model.MissionStatusPending, true},
+		{model.MissionStatusTaken, model.MissionStatusVerified, false},
+		{model.MissionStatusVerified, model.MissionStatusVerified, false},
+		{model.MissionStatusVerified, model.MissionStatusPending, false},
+		{model.MissionStatusRejected, model.MissionStatusVerified, false},
+		{"", model.MissionStatusPending, false},
+	}
+	for _, tc := range cases {
+		if got := canTransition(tc.from, tc.to); got != tc.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+// seedPendingMission creates a user with a verified principal and a pending mission submission.
+func seedPendingMission(t *testing.T, db *gorm.DB) (*model.User, *model.MissionTaken) {
+	t.Helper()
+	user := &model.User{Name: "Budi", Email: "budi@example.com", IIPrincipal: testPrincipal, PrincipalVerified: true}
+	mustCreate(t, db, user)
+	verifier := &model.User{Name: "Vera", Email: "vera@example.com", Role: model.RoleVerifier}
+	mustCreate(t, db, verifier)
+	mission := &model.Mission{Title: "Tanam pohon", Points: 25, AssetAmount: 1.5}
+	mustCreate(t, db, mission)
+	mt := &model.MissionTaken{UserID: user.ID, MissionID: mission.ID, Status: model.MissionStatusPending, ProofURL: "https://example.com/p.jpg"}
+	mustCreate(t, db, mt)
+	return user, mt
+}
+
+func TestVerifyMissionMintsOnce(t *testing.T) {
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	s := newTestMissionTakenService(t, db, canister)
+	user, mt := seedPendingMission(t, db)
+
+	if err := s.VerifyMission(mt.ID, 2); err != nil {
+		t.Fatalf("VerifyMission: %v", err)
+	}
+	if err := s.VerifyMission(mt.ID, 2); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second VerifyMission err = %v, want ErrInvalidTransition", err)
+	}
+
+	got, _ := s.MissionTakenRepo.GetByID(mt.ID)
+	if got.Status != model.MissionStatusVerified || got.VerifiedBy == nil || *got.VerifiedBy != 2 {
+		t.Fatalf("mission taken = %+v, want verified by 2", got)
+	}
+	onChain, _ := canister.GetUserNFTs(context.Background(), testPrincipal)
+	if len(onChain) != 1 {
+		t.Fatalf("on-chain NFTs = %v, want exactly one", onChain)
+	}
+	nfts, _ := s.UserNFTRepo.GetNFTsByUserID(user.ID)
+	if len(nfts) != 1 || nfts[0].NFTID != onChain[0] {
+		t.Fatalf("user NFTs = %+v, want mapping to %s", nfts, onChain[0])
+	}
+	u, _ := s.UserRepo.GetUserByID(user.ID)
+	if u.Points != 25 {
+		t.Fatalf("points = %d, want 25", u.Points)
+	}
+}
+
+func TestVerifyMissionResumesAfterMintFailure(t *testing.T) {
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	s := newTestMissionTakenService(t, db, canister)
+	user, mt := seedPendingMission(t, db)
+
+	canister.FailNext("mint_nft", errors.New("replica unavailable"))
+	if err := s.VerifyMission(mt.ID, 2); err == nil {
+		t.Fatal("VerifyMission succeeded, want mint failure")
+	}
+	saga, _ := s.SagaRepo.GetByMissionTakenID(mt.ID)
+	if saga.Step != model.SagaStepCanisterVerified {
+		t.Fatalf("saga step = %s, want %s", saga.Step, model.SagaStepCanisterVerified)
+	}
+
+	s.ResumeVerifications()
+
+	saga, _ = s.SagaRepo.GetByMissionTakenID(mt.ID)
+	if saga.Step != model.SagaStepCompleted {
+		t.Fatalf("saga step after resume = %s (%s), want completed", saga.Step, saga.LastError)
+	}
+	onChain, _ := canister.GetUserNFTs(context.Background(), testPrincipal)
+	nfts, _ := s.UserNFTRepo.GetNFTsByUserID(user.ID)
+	if len(onChain) != 1 || len(nfts) != 1 {
+		t.Fatalf("on-chain %v, local %d rows; want one each", onChain, len(nfts))
+	}
+}
+
+func TestVerifyMissionCompensatesRejectedSubmission(t *testing.T) {
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	s := newTestMissionTakenService(t, db, canister)
+	user, mt := seedPendingMission(t, db)
+
+	// Mint sukses tapi proses "mati" sebelum transaksi DB, lalu submission ditolak
+	saga, err := s.startVerificationSaga(mt, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nftID, _ := canister.MintNFT(context.Background(), testPrincipal, mt.MissionID, 1.5, saga.IdempotencyKey)
+	s.SagaRepo.UpdateSaga(saga.ID, map[string]interface{}{"step": model.SagaStepMinted, "nft_id": nftID})
+	if err := s.RejectMission(mt.ID, 2, "foto buram"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.ResumeVerifications()
+
+	saga, _ = s.SagaRepo.GetByID(saga.ID)
+	if saga.Step != model.SagaStepCompensated {
+		t.Fatalf("saga step = %s, want compensated", saga.Step)
+	}
+	if onChain, _ := canister.GetUserNFTs(context.Background(), testPrincipal); len(onChain) != 0 {
+		t.Fatalf("on-chain NFTs = %v, want burned", onChain)
+	}
+	if nfts, _ := s.UserNFTRepo.GetNFTsByUserID(user.ID); len(nfts) != 0 {
+		t.Fatalf("local NFTs = %+v, want none", nfts)
+	}
+}
+
+func TestClaimNFTFlow(t *testing.T) {
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	s := newTestMissionTakenService(t, db, canister)
+	user, mt := seedPendingMission(t, db)
+	if err := s.VerifyMission(mt.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	nfts, _ := s.UserNFTRepo.GetNFTsByUserID(user.ID)
+	nftID := nfts[0].NFTID
+
+	// User lain tidak bisa meng-claim NFT ini
+	if _, err := s.RequestClaim(2, nftID, ""); !errors.Is(err, ErrNFTNotFound) {
+		t.Fatalf("RequestClaim by other user err = %v, want ErrNFTNotFound", err)
+	}
+	if owned, _ := s.UserNFTRepo.GetUserNFTByNFTID(nftID); owned.Status != "owned" {
+		t.Fatalf("user NFT = %+v, want still owned", owned)
+	}
+	job, err := s.RequestClaim(user.ID, nftID, "https://example.com/cert.pdf")
+	if err != nil {
+		t.Fatalf("RequestClaim: %v", err)
+	}
+	if _, err := s.RequestClaim(user.ID, nftID, ""); !errors.Is(err, ErrNFTAlreadyClaimed) {
+		t.Fatalf("second RequestClaim err = %v, want ErrNFTAlreadyClaimed", err)
+	}
+
+	canister.FailNext("burn_nft", errors.New("replica unavailable"))
+	if _, err := s.runClaimNFTJob(context.Background(), []byte(job.Payload)); err == nil {
+		t.Fatal("claim job succeeded, want burn failure")
+	}
+	if _, err := s.runClaimNFTJob(context.Background(), []byte(job.Payload)); err != nil {
+		t.Fatalf("claim job retry: %v", err)
+	}
+
+	claimed, _ := s.UserNFTRepo.GetUserNFTByNFTID(nftID)
+	if claimed.Status != "claimed" || claimed.ClaimedAt == nil || claimed.CertificateURL == "" {
+		t.Fatalf("user NFT = %+v, want claimed with certificate", claimed)
+	}
+	if onChain, _ := canister.GetUserNFTs(context.Background(), testPrincipal); len(onChain) != 0 {
+		t.Fatalf("on-chain NFTs = %v, want burned", onChain)
+	}
+}
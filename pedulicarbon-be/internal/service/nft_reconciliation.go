@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Koreksi yang dilakukan Reconcile dalam mode repair. Selisih tanpa aksi
+// (mis. NFT claimed yang masih ada on-chain) hanya dilaporkan.
+const (
+	reconcileActionCreate   = "create_user_nft"
+	reconcileActionReassign = "reassign_owner"
+	reconcileActionOrphan   = "mark_orphaned"
+	reconcileActionClaimed  = "mark_claimed"
+	reconcileActionRestore  = "restore_owned"
+)
+
+// errReconcileConflict menandakan baris UserNFT berubah antara pengecekan dan repair.
+var errReconcileConflict = errors.New("user NFT changed since it was checked")
+
+// ReconcileIssue is one difference between the user_nfts table and the canister.
+type ReconcileIssue struct {
+	Kind           string `json:"kind"`
+	NFTID          string `json:"nft_id"`
+	UserID         uint   `json:"user_id,omitempty"`           // pemilik baris lokal
+	OnChainOwnerID uint   `json:"on_chain_owner_id,omitempty"` // user pemilik principal on-chain
+	LocalStatus    string `json:"local_status,omitempty"`
+	Detail         string `json:"detail"`
+	Action         string `json:"action,omitempty"`
+	Repaired       bool   `json:"repaired"`
+	RepairError    string `json:"repair_error,omitempty"`
+}
+
+// ReconcileReport is the outcome of one Reconcile run.
+type ReconcileReport struct {
+	RunID        string           `json:"run_id"`
+	Repair       bool             `json:"repair"`
+	StartedAt    time.Time        `json:"started_at"`
+	FinishedAt   time.Time        `json:"finished_at"`
+	UsersChecked int              `json:"users_checked"`
+	Issues       []ReconcileIssue `json:"issues"`
+	Errors       []string         `json:"errors"`
+}
+
+// Reconcile compares, per verified user principal, the local UserNFT rows with
+// the NFT IDs get_user_nfts reports. Simulated NFTs and NFTs of unfinished
+// verification sagas are skipped. With repair set, every repairable issue is
+// corrected in its own transaction together with an NFTReconciliationAudit row.
+func (s *NFTService) Reconcile(ctx context.Context, repair bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		RunID:     fmt.Sprintf("reconcile-%d", time.Now().UnixNano()),
+		Repair:    repair,
+		StartedAt: time.Now(),
+		Issues:    []ReconcileIssue{},
+		Errors:    []string{},
+	}
+	users, err := s.UserRepo.ListUsersWithVerifiedPrincipal()
+	if err != nil {
+		return nil, err
+	}
+	inFlight := map[string]bool{}
+	sagas, err := s.SagaRepo.ListUnfinished()
+	if err != nil {
+		return nil, err
+	}
+	for _, saga := range sagas {
+		if saga.NFTID != "" {
+			inFlight[saga.NFTID] = true
+		}
+	}
+
+	// Pass 1: kepemilikan on-chain untuk semua principal
+	onChainOwner := map[string]uint{}
+	fetched := map[uint]bool{}
+	for _, user := range users {
+		report.UsersChecked++
+		ids, err := s.Canister.GetUserNFTs(ctx, user.IIPrincipal)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("user %d: get_user_nfts: %v", user.ID, err))
+			continue
+		}
+		fetched[user.ID] = true
+		for _, id := range ids {
+			onChainOwner[id] = user.ID
+		}
+	}
+
+	// Pass 2: baris lokal dibandingkan dengan on-chain
+	seen := map[string]bool{}
+	for _, user := range users {
+		rows, err := s.UserNFTRepo.GetNFTsByUserID(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			seen[row.NFTID] = true
+			if row.Simulated || inFlight[row.NFTID] {
+				continue
+			}
+			if issue := compareUserNFT(row, onChainOwner, fetched[user.ID]); issue != nil {
+				report.Issues = append(report.Issues, *issue)
+			}
+		}
+	}
+
+	// Pass 3: NFT on-chain yang tidak punya baris di antara user terverifikasi
+	var orphanIDs []string
+	for id := range onChainOwner {
+		if !seen[id] && !inFlight[id] {
+			orphanIDs = append(orphanIDs, id)
+		}
+	}
+	sort.Strings(orphanIDs)
+	for _, id := range orphanIDs {
+		owner := onChainOwner[id]
+		row, err := s.UserNFTRepo.GetUserNFTByNFTID(id)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			report.Issues = append(report.Issues, ReconcileIssue{
+				Kind:           model.ReconcileOrphanOnChain,
+				NFTID:          id,
+				OnChainOwnerID: owner,
+				Detail:         "NFT exists on-chain but has no user_nfts row",
+				Action:         reconcileActionCreate,
+			})
+		case err != nil:
+			return nil, err
+		default:
+			report.Issues = append(report.Issues, ReconcileIssue{
+				Kind:           model.ReconcileOwnerMismatch,
+				NFTID:          id,
+				UserID:         row.UserID,
+				OnChainOwnerID: owner,
+				LocalStatus:    row.Status,
+				Detail:         "row belongs to a user without a verified principal",
+				Action:         reconcileActionReassign,
+			})
+		}
+	}
+
+	if repair {
+		for i := range report.Issues {
+			issue := &report.Issues[i]
+			if issue.Action == "" {
+				continue
+			}
+			if err := s.repairIssue(report.RunID, issue); err != nil {
+				issue.RepairError = err.Error()
+				continue
+			}
+			issue.Repaired = true
+		}
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// compareUserNFT checks one local row. present is only trusted when the row
+// owner's on-chain NFTs were fetched.
+func compareUserNFT(row model.UserNFT, onChainOwner map[string]uint, fetched bool) *ReconcileIssue {
+	owner, present := onChainOwner[row.NFTID]
+	if present && owner != row.UserID {
+		return &ReconcileIssue{
+			Kind:           model.ReconcileOwnerMismatch,
+			NFTID:          row.NFTID,
+			UserID:         row.UserID,
+			OnChainOwnerID: owner,
+			LocalStatus:    row.Status,
+			Detail:         "NFT is owned on-chain by another user's principal",
+			Action:         reconcileActionReassign,
+		}
+	}
+	if !fetched {
+		return nil
+	}
+	issue := &ReconcileIssue{NFTID: row.NFTID, UserID: row.UserID, LocalStatus: row.Status}
+	switch {
+	case row.Status == "owned" && !present:
+		issue.Kind = model.ReconcileOrphanLocal
+		issue.Detail = "row is owned but the NFT is not on-chain"
+		issue.Action = reconcileActionOrphan
+	case row.Status == "claiming" && !present:
+		issue.Kind = model.ReconcileStatusMismatch
+		issue.Detail = "NFT was burned on-chain but the claim was not recorded"
+		issue.Action = reconcileActionClaimed
+	case row.Status == "claimed" && present:
+		issue.Kind = model.ReconcileStatusMismatch
+		issue.Detail = "row is claimed but the NFT was not burned on-chain; needs manual review"
+	case row.Status == "orphaned" && present:
+		issue.Kind = model.ReconcileStatusMismatch
+		issue.Detail = "row is orphaned but the NFT is on-chain again"
+		issue.Action = reconcileActionRestore
+	default:
+		return nil
+	}
+	return issue
+}
+
+func (s *NFTService) repairIssue(runID string, issue *ReconcileIssue) error {
+	return s.UserNFTRepo.DB.Transaction(func(tx *gorm.DB) error {
+		nfts := s.UserNFTRepo.WithTx(tx)
+		var before string
+		if row, err := nfts.GetUserNFTByNFTID(issue.NFTID); err == nil {
+			before = mustJSON(row)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		ok := true
+		var err error
+		switch issue.Action {
+		case reconcileActionCreate:
+			err = nfts.CreateUserNFT(&model.UserNFT{UserID: issue.OnChainOwnerID, NFTID: issue.NFTID, Status: "owned"})
+		case reconcileActionReassign:
+			ok, err = nfts.UpdateOwnerIf(issue.NFTID, issue.UserID, issue.OnChainOwnerID)
+		case reconcileActionOrphan:
+			ok, err = nfts.UpdateStatusIf(issue.NFTID, "owned", map[string]interface{}{"status": "orphaned"})
+		case reconcileActionClaimed:
+			ok, err = nfts.UpdateStatusIf(issue.NFTID, "claiming", map[string]interface{}{"status": "claimed", "claimed_at": time.Now()})
+		case reconcileActionRestore:
+			ok, err = nfts.UpdateStatusIf(issue.NFTID, "orphaned", map[string]interface{}{"status": "owned"})
+		default:
+			return fmt.Errorf("unknown reconcile action %q", issue.Action)
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errReconcileConflict
+		}
+
+		row, err := nfts.GetUserNFTByNFTID(issue.NFTID)
+		if err != nil {
+			return err
+		}
+		return s.AuditRepo.WithTx(tx).CreateAudit(&model.NFTReconciliationAudit{
+			RunID:  runID,
+			Issue:  issue.Kind,
+			UserID: row.UserID,
+			NFTID:  issue.NFTID,
+			Action: issue.Action,
+			Before: before,
+			After:  mustJSON(row),
+		})
+	})
+}
+
+func mustJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
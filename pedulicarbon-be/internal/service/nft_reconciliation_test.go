@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/motoko"
+	"testing"
+
+	"github.com/aviate-labs/agent-go/principal"
+)
+
+func TestReconcileReportsAndRepairsDrift(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	s := newTestNFTService(db, canister)
+
+	bobPrincipal := principal.NewSelfAuthenticating([]byte("bob")).Encode()
+	alice := &model.User{Name: "Alice", Email: "alice@example.com", IIPrincipal: testPrincipal, PrincipalVerified: true}
+	bob := &model.User{Name: "Bob", Email: "bob@example.com", IIPrincipal: bobPrincipal, PrincipalVerified: true}
+	mustCreate(t, db, alice)
+	mustCreate(t, db, bob)
+
+	synced, _ := canister.MintNFT(ctx, testPrincipal, 1, 1, "k-synced")
+	noRow, _ := canister.MintNFT(ctx, testPrincipal, 1, 1, "k-norow")
+	bobs, _ := canister.MintNFT(ctx, bobPrincipal, 1, 1, "k-bob")
+	notBurned, _ := canister.MintNFT(ctx, testPrincipal, 1, 1, "k-claimed")
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: synced, Status: "owned"})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: bobs, Status: "owned"})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: "NFT-gone", Status: "owned"})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: "NFT-burned", Status: "claiming"})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: notBurned, Status: "claimed"})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: "SIM-1-NFT-0", Status: "owned", Simulated: true})
+
+	report, err := s.Reconcile(ctx, false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	want := map[string]string{
+		bobs:         model.ReconcileOwnerMismatch,
+		"NFT-gone":   model.ReconcileOrphanLocal,
+		"NFT-burned": model.ReconcileStatusMismatch,
+		notBurned:    model.ReconcileStatusMismatch,
+		noRow:        model.ReconcileOrphanOnChain,
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("issues = %+v, want %d", report.Issues, len(want))
+	}
+	for _, issue := range report.Issues {
+		if want[issue.NFTID] != issue.Kind || issue.Repaired {
+			t.Errorf("issue %+v, want kind %s and not repaired", issue, want[issue.NFTID])
+		}
+	}
+	if row, _ := s.UserNFTRepo.GetUserNFTByNFTID("NFT-gone"); row.Status != "owned" {
+		t.Fatalf("dry run changed NFT-gone to %s", row.Status)
+	}
+
+	report, err = s.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("Reconcile repair: %v", err)
+	}
+	for _, issue := range report.Issues {
+		if wantRepaired := issue.NFTID != notBurned; issue.Repaired != wantRepaired {
+			t.Errorf("issue %+v, repaired = %v, want %v", issue, issue.Repaired, wantRepaired)
+		}
+	}
+	checks := []struct {
+		nftID  string
+		userID uint
+		status string
+	}{
+		{bobs, bob.ID, "owned"},
+		{"NFT-gone", alice.ID, "orphaned"},
+		{"NFT-burned", alice.ID, "claimed"},
+		{noRow, alice.ID, "owned"},
+	}
+	for _, c := range checks {
+		row, err := s.UserNFTRepo.GetUserNFTByNFTID(c.nftID)
+		if err != nil || row.UserID != c.userID || row.Status != c.status {
+			t.Errorf("%s = %+v (%v), want user %d status %s", c.nftID, row, err, c.userID, c.status)
+		}
+	}
+	audits, _ := s.AuditRepo.ListByRunID(report.RunID)
+	if len(audits) != 4 {
+		t.Fatalf("audits = %+v, want one per repaired issue", audits)
+	}
+	for _, a := range audits {
+		if a.After == "" || (a.Action != reconcileActionCreate && a.Before == "") {
+			t.Errorf("audit %+v is missing a before/after snapshot", a)
+		}
+	}
+
+	// Run berikutnya hanya menyisakan selisih yang butuh review manual
+	report, _ = s.Reconcile(ctx, true)
+	if len(report.Issues) != 1 || report.Issues[0].NFTID != notBurned {
+		t.Fatalf("issues after repair = %+v, want only %s", report.Issues, notBurned)
+	}
+}
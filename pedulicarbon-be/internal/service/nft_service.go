@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/motoko"
+	"pedulicarbon/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// NFTService membaca NFT dari tabel UserNFT lokal sekaligus dari canister.
+type NFTService struct {
+	UserNFTRepo *repository.UserNFTRepository
+	UserRepo    *repository.UserRepository
+	SagaRepo    *repository.VerificationSagaRepository
+	AuditRepo   *repository.NFTReconciliationRepository
+	Canister    motoko.CanisterClient
+}
+
+func NewNFTService(userNFTRepo *repository.UserNFTRepository, userRepo *repository.UserRepository, sagaRepo *repository.VerificationSagaRepository, auditRepo *repository.NFTReconciliationRepository, canister motoko.CanisterClient) *NFTService {
+	return &NFTService{UserNFTRepo: userNFTRepo, UserRepo: userRepo, SagaRepo: sagaRepo, AuditRepo: auditRepo, Canister: canister}
+}
+
+// NFTDetail is a UserNFT row together with its on-chain record. OnChain is nil
+// when the canister does not know the NFT, e.g. after a claim burned it.
+type NFTDetail struct {
+	UserNFT *model.UserNFT
+	OnChain *motoko.NFTDetail
+}
+
+func (s *NFTService) GetNFTDetail(ctx context.Context, nftID string) (*NFTDetail, error) {
+	userNFT, err := s.UserNFTRepo.GetUserNFTByNFTID(nftID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNFTNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	onChain, err := s.Canister.GetNFTDetail(ctx, nftID)
+	if err != nil && !errors.Is(err, motoko.ErrNFTNotFound) {
+		return nil, err
+	}
+	return &NFTDetail{UserNFT: userNFT, OnChain: onChain}, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"pedulicarbon/internal/motoko"
+	"testing"
+)
+
+func TestGetNFTDetailMergesOnChainRecord(t *testing.T) {
+	db := newTestDB(t)
+	canister := motoko.NewFakeCanister()
+	mts := newTestMissionTakenService(t, db, canister)
+	s := newTestNFTService(db, canister)
+	user, mt := seedPendingMission(t, db)
+	if err := mts.VerifyMission(mt.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	nfts, _ := mts.UserNFTRepo.GetNFTsByUserID(user.ID)
+	nftID := nfts[0].NFTID
+
+	detail, err := s.GetNFTDetail(context.Background(), nftID)
+	if err != nil {
+		t.Fatalf("GetNFTDetail: %v", err)
+	}
+	if detail.UserNFT.UserID != user.ID || detail.OnChain == nil ||
+		detail.OnChain.Owner.Encode() != testPrincipal || detail.OnChain.MissionID.BigInt().Uint64() != uint64(mt.MissionID) {
+		t.Fatalf("detail = %+v / %+v", detail.UserNFT, detail.OnChain)
+	}
+	if !detail.UserNFT.Simulated {
+		t.Fatal("NFT minted by the fake canister should be tagged simulated")
+	}
+
+	// Setelah di-burn, baris lokal tetap ada tapi on_chain kosong
+	if err := canister.BurnNFT(context.Background(), nftID); err != nil {
+		t.Fatal(err)
+	}
+	detail, err = s.GetNFTDetail(context.Background(), nftID)
+	if err != nil || detail.OnChain != nil {
+		t.Fatalf("after burn: detail = %+v, err = %v", detail, err)
+	}
+
+	canister.FailNext("get_nft_detail", errors.New("replica unavailable"))
+	if _, err := s.GetNFTDetail(context.Background(), nftID); err == nil {
+		t.Fatal("canister failure was swallowed")
+	}
+	if _, err := s.GetNFTDetail(context.Background(), "NFT-404"); !errors.Is(err, ErrNFTNotFound) {
+		t.Fatalf("unknown NFT err = %v, want ErrNFTNotFound", err)
+	}
+}
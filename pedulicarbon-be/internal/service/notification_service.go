@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notifikasi tidak ditemukan")
+
+// Batas halaman GET /users/:user_id/notifications.
+const (
+	DefaultNotificationLimit = 20
+	MaxNotificationLimit     = 100
+)
+
+type NotificationService struct {
+	NotificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationService(repo *repository.NotificationRepository) *NotificationService {
+	return &NotificationService{NotificationRepo: repo}
+}
+
+// Notify menyimpan notifikasi in-app. Dengan refKey yang sama notifikasi hanya
+// dibuat sekali; hasil false berarti sudah pernah dikirim.
+func (s *NotificationService) Notify(userID uint, notificationType, title, message, refKey string) (bool, error) {
+	return s.NotificationRepo.CreateOnce(&model.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+		RefKey:  refKey,
+	})
+}
+
+func (s *NotificationService) List(userID uint, limit, offset int) ([]model.Notification, int64, error) {
+	if limit <= 0 {
+		limit = DefaultNotificationLimit
+	}
+	if limit > MaxNotificationLimit {
+		limit = MaxNotificationLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.NotificationRepo.ListByUserID(userID, limit, offset)
+}
+
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	ok, err := s.NotificationRepo.MarkRead(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
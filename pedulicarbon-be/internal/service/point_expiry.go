@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"time"
+)
+
+// MaxUpcomingExpirations membatasi jumlah tanggal kedaluwarsa di profil user.
+const MaxUpcomingExpirations = 10
+
+// PointExpiryPolicy: point kedaluwarsa Months bulan setelah diterima (0 = tidak
+// pernah), dan user diberi tahu NoticeDays hari sebelumnya.
+type PointExpiryPolicy struct {
+	Months     int
+	NoticeDays int
+}
+
+func (p PointExpiryPolicy) Enabled() bool {
+	return p.Months > 0
+}
+
+func (p PointExpiryPolicy) expiresAt(earnedAt time.Time) time.Time {
+	return earnedAt.AddDate(0, p.Months, 0)
+}
+
+// PointLot adalah sisa point dari satu baris kredit ledger (mission reward,
+// adjustment, transfer masuk, refund) setelah debit dikonsumsi FIFO.
+type PointLot struct {
+	EntryID   uint      `json:"entry_id"`
+	EarnedAt  time.Time `json:"earned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Remaining int       `json:"remaining"`
+}
+
+// PointExpiration adalah jumlah point yang kedaluwarsa pada satu tanggal.
+type PointExpiration struct {
+	Points    int       `json:"points"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PointExpiryReport adalah hasil satu kali ExpirePoints.
+type PointExpiryReport struct {
+	RanAt         time.Time `json:"ran_at"`
+	UsersChecked  int       `json:"users_checked"`
+	UsersExpired  int       `json:"users_expired"`
+	PointsExpired int       `json:"points_expired"`
+	NoticesSent   int       `json:"notices_sent"`
+	Errors        []string  `json:"errors,omitempty"`
+}
+
+// PointExpiryService menerapkan PointExpiryPolicy di atas ledger point. Lot tidak
+// disimpan terpisah: setiap kali dihitung ulang dari ledger, sehingga entri expiry
+// yang sudah diposting otomatis mengonsumsi lot tertua.
+type PointExpiryService struct {
+	Points        *PointService
+	Notifications *NotificationService
+	Policy        PointExpiryPolicy
+}
+
+func NewPointExpiryService(points *PointService, notifications *NotificationService, policy PointExpiryPolicy) *PointExpiryService {
+	return &PointExpiryService{Points: points, Notifications: notifications, Policy: policy}
+}
+
+// Lots returns the user's credit lots that still hold points, oldest first.
+func (s *PointExpiryService) Lots(userID uint) ([]PointLot, error) {
+	entries, err := s.Points.LedgerRepo.ListAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var lots []PointLot
+	for _, e := range entries {
+		if e.Amount > 0 {
+			lots = append(lots, PointLot{EntryID: e.ID, EarnedAt: e.CreatedAt, ExpiresAt: s.Policy.expiresAt(e.CreatedAt), Remaining: e.Amount})
+			continue
+		}
+		// Debit (redeem, transfer keluar, expiry) menghabiskan lot tertua dulu
+		debit := -e.Amount
+		for i := range lots {
+			if debit == 0 {
+				break
+			}
+			used := min(debit, lots[i].Remaining)
+			lots[i].Remaining -= used
+			debit -= used
+		}
+	}
+	open := lots[:0]
+	for _, lot := range lots {
+		if lot.Remaining > 0 {
+			open = append(open, lot)
+		}
+	}
+	return open, nil
+}
+
+// Upcoming returns the user's future expirations grouped per day, soonest first.
+func (s *PointExpiryService) Upcoming(userID uint, now time.Time) ([]PointExpiration, error) {
+	if !s.Policy.Enabled() {
+		return []PointExpiration{}, nil
+	}
+	lots, err := s.Lots(userID)
+	if err != nil {
+		return nil, err
+	}
+	return groupExpirations(lots, now, time.Time{}), nil
+}
+
+// groupExpirations menjumlahkan lot yang kedaluwarsa setelah from (dan sebelum
+// until bila diisi) per tanggal.
+func groupExpirations(lots []PointLot, from, until time.Time) []PointExpiration {
+	out := []PointExpiration{}
+	for _, lot := range lots {
+		if !lot.ExpiresAt.After(from) || (!until.IsZero() && lot.ExpiresAt.After(until)) {
+			continue
+		}
+		if n := len(out); n > 0 && sameDay(out[n-1].ExpiresAt, lot.ExpiresAt) {
+			out[n-1].Points += lot.Remaining
+			continue
+		}
+		if len(out) == MaxUpcomingExpirations {
+			break
+		}
+		out = append(out, PointExpiration{Points: lot.Remaining, ExpiresAt: lot.ExpiresAt})
+	}
+	return out
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// ExpirePoints memposting entri expiry untuk lot yang sudah lewat masa berlakunya
+// dan mengirim notifikasi untuk lot yang kedaluwarsa dalam NoticeDays hari ke depan.
+// Aman dijalankan berulang atau paralel: RefID expiry adalah lot terakhir yang
+// dihanguskan dan notifikasi dideduplikasi per user per tanggal.
+func (s *PointExpiryService) ExpirePoints(now time.Time) (*PointExpiryReport, error) {
+	report := &PointExpiryReport{RanAt: now}
+	if !s.Policy.Enabled() {
+		return report, nil
+	}
+	noticeUntil := now.AddDate(0, 0, s.Policy.NoticeDays)
+	// Lot yang diterima sebelum batas ini kedaluwarsa paling lambat noticeUntil
+	userIDs, err := s.Points.LedgerRepo.ListUserIDsWithCreditsBefore(noticeUntil.AddDate(0, -s.Policy.Months, 0))
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		report.UsersChecked++
+		lots, err := s.Lots(userID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("user %d: %v", userID, err))
+			continue
+		}
+		expired, lastLotID := 0, uint(0)
+		for _, lot := range lots {
+			if !lot.ExpiresAt.After(now) {
+				expired += lot.Remaining
+				lastLotID = lot.EntryID
+			}
+		}
+		if expired > 0 {
+			_, err := s.Points.Post(PointPosting{
+				UserID:      userID,
+				Amount:      -expired,
+				EntryType:   model.PointEntryExpiry,
+				RefType:     "point_expiry",
+				RefID:       fmt.Sprintf("lot-%d", lastLotID),
+				Description: fmt.Sprintf("point kedaluwarsa (%d bulan)", s.Policy.Months),
+			})
+			switch {
+			case err == nil:
+				report.UsersExpired++
+				report.PointsExpired += expired
+			case !errors.Is(err, ErrPointsAlreadyPosted):
+				report.Errors = append(report.Errors, fmt.Sprintf("user %d: %v", userID, err))
+			}
+		}
+		if s.Policy.NoticeDays <= 0 {
+			continue
+		}
+		for _, exp := range groupExpirations(lots, now, noticeUntil) {
+			date := exp.ExpiresAt.Format("2006-01-02")
+			sent, err := s.Notifications.Notify(userID, model.NotificationPointsExpiring,
+				"Point akan kedaluwarsa",
+				fmt.Sprintf("%d point kamu akan kedaluwarsa pada %s. Tukarkan sebelum hangus.", exp.Points, date),
+				fmt.Sprintf("points-expiring-%d-%s", userID, date))
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("notify user %d: %v", userID, err))
+				continue
+			}
+			if sent {
+				report.NoticesSent++
+			}
+		}
+	}
+	return report, nil
+}
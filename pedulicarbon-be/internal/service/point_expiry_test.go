@@ -0,0 +1,55 @@
+package service
+
+import (
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"testing"
+	"time"
+)
+
+func TestExpirePointsFIFO(t *testing.T) {
+	points, alice, _ := newTestPointService(t)
+	db := points.UserRepo.DB
+	s := NewPointExpiryService(points, NewNotificationService(repository.NewNotificationRepository(db)), PointExpiryPolicy{Months: 12, NoticeDays: 30})
+	now := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	// post menulis entri lalu memundurkan waktunya ke at
+	post := func(amount int, entryType string, at time.Time) {
+		t.Helper()
+		entry, err := points.Post(PointPosting{UserID: alice.ID, Amount: amount, EntryType: entryType})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Model(&model.PointTransaction{}).Where("journal_id = ?", entry.JournalID).Update("created_at", at)
+	}
+	post(100, model.PointEntryMissionReward, now.AddDate(0, -13, 0))
+	post(-60, model.PointEntryRedemption, now.AddDate(0, -12, -20)) // menghabiskan 60 dari lot pertama
+	post(50, model.PointEntryMissionReward, now.AddDate(0, -12, 10))
+	post(30, model.PointEntryAdjustment, now.AddDate(0, -1, 0))
+
+	report, err := s.ExpirePoints(now)
+	if err != nil || len(report.Errors) != 0 {
+		t.Fatalf("ExpirePoints = %+v, %v", report, err)
+	}
+	if report.PointsExpired != 40 || report.UsersExpired != 1 || report.NoticesSent != 1 {
+		t.Fatalf("report = %+v; want 40 points expired and 1 notice", report)
+	}
+	if u, _ := points.UserRepo.GetUserByID(alice.ID); u.Points != 80 {
+		t.Fatalf("balance = %d, want 80", u.Points)
+	}
+
+	// Jalan ulang tidak menghanguskan atau memberi tahu dua kali
+	if report, err = s.ExpirePoints(now.Add(time.Hour)); err != nil || report.PointsExpired != 0 || report.NoticesSent != 0 {
+		t.Fatalf("second run = %+v, %v", report, err)
+	}
+	notifications, unread, _ := s.Notifications.List(alice.ID, 0, 0)
+	if unread != 1 || notifications[0].Type != model.NotificationPointsExpiring {
+		t.Fatalf("notifications = %+v (unread %d)", notifications, unread)
+	}
+
+	upcoming, err := s.Upcoming(alice.ID, now)
+	if err != nil || len(upcoming) != 2 || upcoming[0].Points != 50 || !upcoming[0].ExpiresAt.Equal(now.AddDate(0, 0, 10)) || upcoming[1].Points != 30 {
+		t.Fatalf("Upcoming = %+v, %v", upcoming, err)
+	}
+	assertLedgerBalanced(t, points)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInsufficientPoints = errors.New("point tidak cukup")
+	ErrInvalidPointAmount = errors.New("jumlah point tidak valid")
+	// ErrPointsAlreadyPosted menandakan sumber (ref) yang sama sudah pernah diposting.
+	ErrPointsAlreadyPosted = errors.New("point untuk referensi ini sudah diposting")
+)
+
+// Akun lawan untuk setiap jenis entri; transfer memakai akun user penerima.
+var pointSystemAccounts = map[string]string{
+	model.PointEntryMissionReward: "system:mission_rewards",
+	model.PointEntryRedemption:    "system:redemptions",
+	model.PointEntryAdjustment:    "system:adjustments",
+	model.PointEntryExpiry:        "system:expired",
+}
+
+// Batas halaman GET /users/:user_id/points/history.
+const (
+	DefaultPointHistoryLimit = 50
+	MaxPointHistoryLimit     = 200
+)
+
+// PointPosting is one change to a user's balance. Amount is added to the user's
+// balance (negative to spend); the counter line goes to the entry type's system
+// account, or to CounterpartyUserID for transfers.
+type PointPosting struct {
+	UserID             uint
+	Amount             int
+	EntryType          string
+	RefType            string
+	RefID              string
+	CounterpartyUserID uint
+	Description        string
+}
+
+// PointService is the only writer of User.Points: every change is posted as a
+// balanced journal in point_transactions in the same transaction as the balance update.
+type PointService struct {
+	UserRepo   *repository.UserRepository
+	LedgerRepo *repository.PointTransactionRepository
+}
+
+func NewPointService(userRepo *repository.UserRepository, ledgerRepo *repository.PointTransactionRepository) *PointService {
+	return &PointService{UserRepo: userRepo, LedgerRepo: ledgerRepo}
+}
+
+func userAccount(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// Post posts p in its own transaction.
+func (s *PointService) Post(p PointPosting) (*model.PointTransaction, error) {
+	var entry *model.PointTransaction
+	err := s.UserRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = s.PostTx(tx, p)
+		return err
+	})
+	return entry, err
+}
+
+// PostTx posts p inside tx and returns the user's ledger line. A debit larger than
+// the balance fails with ErrInsufficientPoints; posting the same non-empty
+// RefType/RefID twice fails with ErrPointsAlreadyPosted.
+func (s *PointService) PostTx(tx *gorm.DB, p PointPosting) (*model.PointTransaction, error) {
+	if p.Amount == 0 {
+		return nil, ErrInvalidPointAmount
+	}
+	counterAccount, counterUserID := pointSystemAccounts[p.EntryType], (*uint)(nil)
+	if p.EntryType == model.PointEntryTransfer {
+		if p.CounterpartyUserID == 0 || p.CounterpartyUserID == p.UserID {
+			return nil, ErrInvalidPointAmount
+		}
+		counterAccount, counterUserID = userAccount(p.CounterpartyUserID), &p.CounterpartyUserID
+	}
+	if counterAccount == "" {
+		return nil, fmt.Errorf("jenis entri point tidak dikenal: %s", p.EntryType)
+	}
+	ledger := s.LedgerRepo.WithTx(tx)
+	if p.RefID != "" {
+		if _, err := ledger.FindByRef(userAccount(p.UserID), p.EntryType, p.RefType, p.RefID); err == nil {
+			return nil, ErrPointsAlreadyPosted
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	users := s.UserRepo.WithTx(tx)
+	userBalance, err := applyPoints(users, p.UserID, p.Amount)
+	if err != nil {
+		return nil, err
+	}
+	counterBalance := 0
+	if counterUserID != nil {
+		if counterBalance, err = applyPoints(users, *counterUserID, -p.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	journalID := fmt.Sprintf("%s-%d-%d", p.EntryType, p.UserID, time.Now().UnixNano())
+	entries := []model.PointTransaction{
+		{
+			JournalID: journalID, Account: userAccount(p.UserID), UserID: &p.UserID, EntryType: p.EntryType,
+			Amount: p.Amount, BalanceAfter: userBalance, RefType: p.RefType, RefID: p.RefID, Description: p.Description,
+		},
+		{
+			JournalID: journalID, Account: counterAccount, UserID: counterUserID, EntryType: p.EntryType,
+			Amount: -p.Amount, BalanceAfter: counterBalance, RefType: p.RefType, RefID: p.RefID, Description: p.Description,
+		},
+	}
+	if err := ledger.CreateEntries(entries); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// applyPoints changes the stored balance atomically and returns the new balance.
+func applyPoints(users *repository.UserRepository, userID uint, delta int) (int, error) {
+	ok, err := users.IncrementUserPoints(userID, delta)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		if _, err := users.GetUserByID(userID); err != nil {
+			return 0, err
+		}
+		return 0, ErrInsufficientPoints
+	}
+	user, err := users.GetUserByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.Points, nil
+}
+
+// Transfer moves points from one user to another as a single journal.
+func (s *PointService) Transfer(fromUserID, toUserID uint, amount int, description string) (*model.PointTransaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidPointAmount
+	}
+	return s.Post(PointPosting{
+		UserID:             fromUserID,
+		Amount:             -amount,
+		EntryType:          model.PointEntryTransfer,
+		CounterpartyUserID: toUserID,
+		Description:        description,
+	})
+}
+
+// History returns a page of the user's ledger lines, newest first.
+func (s *PointService) History(userID uint, limit, offset int) ([]model.PointTransaction, int64, error) {
+	if limit <= 0 {
+		limit = DefaultPointHistoryLimit
+	}
+	if limit > MaxPointHistoryLimit {
+		limit = MaxPointHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.LedgerRepo.ListByUserID(userID, limit, offset)
+}
+
+// BackfillOpeningBalances explains balances that predate the ledger with one
+// "opening_balance" adjustment line per user. users.points itself is not changed.
+func (s *PointService) BackfillOpeningBalances() (int, error) {
+	rows, err := s.LedgerRepo.ListUnexplainedBalances()
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		userID, diff := row.UserID, row.Points-row.LedgerSum
+		journalID := fmt.Sprintf("opening-balance-%d-%d", userID, time.Now().UnixNano())
+		entries := []model.PointTransaction{
+			{
+				JournalID: journalID, Account: userAccount(userID), UserID: &userID, EntryType: model.PointEntryAdjustment,
+				Amount: diff, BalanceAfter: row.Points, RefType: "opening_balance", RefID: journalID,
+				Description: "saldo sebelum ledger point",
+			},
+			{
+				JournalID: journalID, Account: pointSystemAccounts[model.PointEntryAdjustment], EntryType: model.PointEntryAdjustment,
+				Amount: -diff, RefType: "opening_balance", RefID: journalID,
+				Description: "saldo sebelum ledger point",
+			},
+		}
+		if err := s.LedgerRepo.CreateEntries(entries); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"testing"
+)
+
+func newTestPointService(t *testing.T) (*PointService, *model.User, *model.User) {
+	t.Helper()
+	db := newTestDB(t)
+	alice := &model.User{Name: "Alice", Email: "alice@example.com"}
+	bob := &model.User{Name: "Bob", Email: "bob@example.com"}
+	mustCreate(t, db, alice)
+	mustCreate(t, db, bob)
+	return NewPointService(repository.NewUserRepository(db), repository.NewPointTransactionRepository(db)), alice, bob
+}
+
+// assertLedgerBalanced checks that every user's balance is explained by ledger
+// lines and that all journals sum to zero.
+func assertLedgerBalanced(t *testing.T, s *PointService) {
+	t.Helper()
+	if rows, err := s.LedgerRepo.ListUnexplainedBalances(); err != nil || len(rows) != 0 {
+		t.Fatalf("unexplained balances = %+v, %v", rows, err)
+	}
+	var unbalanced []string
+	s.LedgerRepo.DB.Model(&model.PointTransaction{}).Group("journal_id").Having("SUM(amount) <> 0").Pluck("journal_id", &unbalanced)
+	if len(unbalanced) != 0 {
+		t.Fatalf("unbalanced journals: %v", unbalanced)
+	}
+}
+
+func TestPointPostingsKeepBalanceAndLedgerInSync(t *testing.T) {
+	s, alice, bob := newTestPointService(t)
+
+	if _, err := s.Post(PointPosting{UserID: alice.ID, Amount: 100, EntryType: model.PointEntryMissionReward, RefType: "mission_taken", RefID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Post(PointPosting{UserID: alice.ID, Amount: 100, EntryType: model.PointEntryMissionReward, RefType: "mission_taken", RefID: "1"}); !errors.Is(err, ErrPointsAlreadyPosted) {
+		t.Fatalf("duplicate reward err = %v, want ErrPointsAlreadyPosted", err)
+	}
+	// Redeem mengurangi saldo, bukan menimpanya dengan angka negatif
+	entry, err := s.Post(PointPosting{UserID: alice.ID, Amount: -30, EntryType: model.PointEntryRedemption})
+	if err != nil || entry.BalanceAfter != 70 {
+		t.Fatalf("redemption = %+v, %v; want balance 70", entry, err)
+	}
+	if _, err := s.Post(PointPosting{UserID: alice.ID, Amount: -71, EntryType: model.PointEntryRedemption}); !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("overspend err = %v, want ErrInsufficientPoints", err)
+	}
+	if _, err := s.Transfer(alice.ID, bob.ID, 20, "hadiah"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Transfer(bob.ID, alice.ID, 21, ""); !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("transfer overspend err = %v, want ErrInsufficientPoints", err)
+	}
+
+	a, _ := s.UserRepo.GetUserByID(alice.ID)
+	b, _ := s.UserRepo.GetUserByID(bob.ID)
+	if a.Points != 50 || b.Points != 20 {
+		t.Fatalf("balances = %d, %d; want 50, 20", a.Points, b.Points)
+	}
+	history, total, err := s.History(alice.ID, 2, 0)
+	if err != nil || total != 3 || len(history) != 2 || history[0].EntryType != model.PointEntryTransfer || history[0].BalanceAfter != 50 {
+		t.Fatalf("history = %+v (total %d), %v", history, total, err)
+	}
+	assertLedgerBalanced(t, s)
+}
+
+func TestBackfillOpeningBalances(t *testing.T) {
+	s, alice, _ := newTestPointService(t)
+	// Saldo dari sebelum ledger ada
+	s.UserRepo.DB.Model(&model.User{}).Where("id = ?", alice.ID).Update("points", 40)
+
+	if n, err := s.BackfillOpeningBalances(); err != nil || n != 1 {
+		t.Fatalf("backfill = %d, %v; want 1 user", n, err)
+	}
+	if n, _ := s.BackfillOpeningBalances(); n != 0 {
+		t.Fatalf("second backfill posted %d users, want 0", n)
+	}
+	if _, err := s.Post(PointPosting{UserID: alice.ID, Amount: -40, EntryType: model.PointEntryRedemption}); err != nil {
+		t.Fatal(err)
+	}
+	assertLedgerBalanced(t, s)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"time"
+
+	"github.com/aviate-labs/agent-go/certification"
+	"github.com/aviate-labs/agent-go/certification/ii"
+	"github.com/aviate-labs/agent-go/principal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChallengeInvalid     = errors.New("challenge tidak valid atau sudah kadaluarsa")
+	ErrDelegationInvalid    = errors.New("delegasi Internet Identity tidak valid")
+	ErrPrincipalTaken       = errors.New("principal sudah terhubung ke user lain")
+	ErrPrincipalNotVerified = errors.New("user belum memverifikasi ii_principal (ICP principal)")
+)
+
+// MainnetIICanisterID is the Internet Identity canister on the IC main net.
+const MainnetIICanisterID = "rdmx6-jaaaa-aaaaa-aaadq-cai"
+
+// PrincipalService menghubungkan user dengan principal ICP miliknya. User meminta
+// challenge, lalu meminta Internet Identity menandatangani delegasi dengan challenge
+// tersebut sebagai session key; principal diturunkan dari public key delegasi.
+type PrincipalService struct {
+	ChallengeRepo *repository.PrincipalChallengeRepository
+	UserRepo      *repository.UserRepository
+	IICanisterID  principal.Principal
+	RootKey       []byte
+	ChallengeTTL  time.Duration
+}
+
+// NewPrincipalService builds the service. Empty iiCanisterID or rootKeyHex fall back to main net values.
+func NewPrincipalService(challengeRepo *repository.PrincipalChallengeRepository, userRepo *repository.UserRepository, iiCanisterID, rootKeyHex string) (*PrincipalService, error) {
+	if iiCanisterID == "" {
+		iiCanisterID = MainnetIICanisterID
+	}
+	canister, err := principal.Decode(iiCanisterID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid II canister id: %v", err)
+	}
+	if rootKeyHex == "" {
+		rootKeyHex = certification.RootKey
+	}
+	rootKey, err := hex.DecodeString(rootKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IC root key: %v", err)
+	}
+	return &PrincipalService{
+		ChallengeRepo: challengeRepo,
+		UserRepo:      userRepo,
+		IICanisterID:  canister,
+		RootKey:       rootKey,
+		ChallengeTTL:  5 * time.Minute,
+	}, nil
+}
+
+func (s *PrincipalService) IssueChallenge(userID uint) (*model.PrincipalChallenge, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	ch := &model.PrincipalChallenge{
+		UserID:    userID,
+		Challenge: hex.EncodeToString(buf),
+		ExpiresAt: time.Now().Add(s.ChallengeTTL),
+	}
+	if err := s.ChallengeRepo.CreateChallenge(ch); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// VerifyPrincipal checks the delegation chain signed over challenge and stores the derived principal on the user.
+func (s *PrincipalService) VerifyPrincipal(userID uint, challenge string, chain ii.DelegationChain) (string, error) {
+	ch, err := s.ChallengeRepo.GetActiveChallenge(userID, challenge)
+	if err != nil {
+		return "", ErrChallengeInvalid
+	}
+	challengeBytes, err := hex.DecodeString(ch.Challenge)
+	if err != nil {
+		return "", ErrChallengeInvalid
+	}
+	if err := chain.VerifyChallenge(challengeBytes, uint64(time.Now().UnixNano()), s.IICanisterID, s.RootKey); err != nil {
+		fmt.Printf("[ERROR] VerifyChallenge error: %v\n", err)
+		return "", ErrDelegationInvalid
+	}
+	p := principal.NewSelfAuthenticating([]byte(chain.PublicKey)).Encode()
+
+	// Satu principal hanya boleh terhubung ke satu user
+	owner, err := s.UserRepo.GetUserByIIPrincipal(p)
+	if err == nil && owner.ID != userID {
+		return "", ErrPrincipalTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if err := s.ChallengeRepo.MarkUsed(ch.ID); err != nil {
+		return "", ErrChallengeInvalid
+	}
+	if err := s.UserRepo.SetVerifiedPrincipal(userID, p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
@@ -1,30 +1,357 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCatalogNotFound = errors.New("katalog reward tidak ditemukan")
+	ErrOutOfStock      = errors.New("stok reward habis")
+	// ErrShippingAddressRequired: reward produk butuh alamat kirim (address_id atau alamat default).
+	ErrShippingAddressRequired = errors.New("alamat pengiriman wajib dipilih untuk reward produk")
+	// ErrIdempotencyKeyReused menandakan Idempotency-Key sudah dipakai untuk katalog lain.
+	ErrIdempotencyKeyReused = errors.New("idempotency key sudah dipakai untuk redeem lain")
+	ErrInvalidCatalog       = errors.New("data katalog reward tidak valid")
+	// ErrCatalogUnavailable: item diarsipkan atau di luar jendela available_from/available_until.
+	ErrCatalogUnavailable = errors.New("reward sedang tidak tersedia")
+	ErrRedemptionLimit    = errors.New("batas redeem per user untuk reward ini sudah tercapai")
+	ErrNotCatalogOwner    = errors.New("item katalog bukan milik merchant ini")
+)
+
+// MaxIdempotencyKeyLength membatasi panjang header Idempotency-Key.
+const MaxIdempotencyKeyLength = 128
+
+// Batas halaman GET /rewards/catalog dan jumlah gambar per item.
+const (
+	DefaultCatalogListLimit = 20
+	MaxCatalogListLimit     = 100
+	MaxCatalogImages        = 10
 )
 
+// CatalogQuery adalah filter daftar katalog. AffordableForUserID membatasi ke item
+// yang point-nya cukup untuk saldo user tersebut. IncludeInactive (khusus pengelola
+// katalog) ikut menampilkan item yang diarsipkan atau belum/sudah lewat jadwalnya.
+type CatalogQuery struct {
+	Category            string
+	InStock             bool
+	AffordableForUserID uint
+	IncludeInactive     bool
+	Limit               int
+	Offset              int
+}
+
+// CatalogPatch berisi field katalog yang diubah; nil berarti tidak diubah. Stock
+// diabaikan untuk item voucher karena stoknya mengikuti kode voucher.
+type CatalogPatch struct {
+	Name           *string
+	Description    *string
+	Category       *string
+	ImageURLs      *[]string
+	PointsRequired *int
+	Stock          *int
+	MaxPerUser     *int
+	AvailableFrom  **time.Time
+	AvailableUntil **time.Time
+}
+
 type RewardCatalogService struct {
 	CatalogRepo *repository.RewardCatalogRepository
+	RewardRepo  *repository.RewardRepository
+	VoucherRepo *repository.VoucherCodeRepository
+	AddressRepo *repository.ShippingAddressRepository
+	Points      *PointService
 }
 
-func NewRewardCatalogService(repo *repository.RewardCatalogRepository) *RewardCatalogService {
-	return &RewardCatalogService{CatalogRepo: repo}
+func NewRewardCatalogService(repo *repository.RewardCatalogRepository, rewardRepo *repository.RewardRepository, voucherRepo *repository.VoucherCodeRepository, addressRepo *repository.ShippingAddressRepository, points *PointService) *RewardCatalogService {
+	return &RewardCatalogService{CatalogRepo: repo, RewardRepo: rewardRepo, VoucherRepo: voucherRepo, AddressRepo: addressRepo, Points: points}
 }
 
-func (s *RewardCatalogService) ListCatalog() ([]model.RewardCatalog, error) {
-	return s.CatalogRepo.ListCatalog()
+func (s *RewardCatalogService) ListCatalog(q CatalogQuery) ([]model.RewardCatalog, int64, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultCatalogListLimit
+	}
+	if q.Limit > MaxCatalogListLimit {
+		q.Limit = MaxCatalogListLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	f := repository.CatalogFilter{
+		Category:        normalizeCategory(q.Category),
+		InStock:         q.InStock,
+		IncludeArchived: q.IncludeInactive,
+	}
+	if !q.IncludeInactive {
+		now := time.Now()
+		f.AvailableAt = &now
+	}
+	if q.AffordableForUserID != 0 {
+		user, err := s.Points.UserRepo.GetUserByID(q.AffordableForUserID)
+		if err != nil {
+			return nil, 0, err
+		}
+		f.MaxPoints = &user.Points
+	}
+	return s.CatalogRepo.ListCatalog(f, q.Limit, q.Offset)
 }
 
 func (s *RewardCatalogService) GetCatalog(id uint) (*model.RewardCatalog, error) {
 	return s.CatalogRepo.GetCatalogByID(id)
 }
 
-func (s *RewardCatalogService) RedeemCatalog(id uint) error {
-	return s.CatalogRepo.RedeemCatalog(id)
+// Redeem menukar point user dengan satu item katalog. Stok, point dan Reward diubah
+// dalam satu transaksi: stok dikurangi dengan update bersyarat, point didebit lewat
+// ledger, dan bila salah satunya gagal tidak ada yang tersimpan. Item bertipe voucher
+// juga mendapat satu VoucherCode; update stok mengunci baris katalog sehingga
+// pemberian kode untuk katalog yang sama berjalan berurutan. Item bertipe produk
+// dikirim ke addressID, atau ke alamat default user bila addressID 0.
+//
+// idempotencyKey (boleh kosong) membuat retry aman: redeem kedua dengan key yang sama
+// mengembalikan Reward yang sudah ada dan replayed=true tanpa mendebit lagi.
+func (s *RewardCatalogService) Redeem(userID, catalogID, addressID uint, idempotencyKey string) (reward *model.Reward, replayed bool, err error) {
+	if idempotencyKey != "" {
+		if reward, err := s.findRedemption(userID, catalogID, idempotencyKey); err == nil {
+			return reward, true, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	err = s.CatalogRepo.DB.Transaction(func(tx *gorm.DB) error {
+		catalog, err := s.CatalogRepo.WithTx(tx).GetCatalogByID(catalogID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCatalogNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !catalog.AvailableAt(time.Now()) {
+			return ErrCatalogUnavailable
+		}
+		var address *model.ShippingAddress
+		if catalog.Type == model.CatalogTypeProduct {
+			if address, err = s.shippingAddress(tx, userID, addressID); err != nil {
+				return err
+			}
+		}
+		ok, err := s.CatalogRepo.WithTx(tx).DecrementStock(catalogID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrOutOfStock
+		}
+		// Dihitung setelah update stok yang mengunci baris katalog, sehingga redeem
+		// paralel oleh user yang sama tidak bisa melewati batas bersamaan
+		if catalog.MaxPerUser > 0 {
+			n, err := s.RewardRepo.WithTx(tx).CountByUserAndCatalog(userID, catalog.ID)
+			if err != nil {
+				return err
+			}
+			if n >= int64(catalog.MaxPerUser) {
+				return ErrRedemptionLimit
+			}
+		}
+		reward = &model.Reward{
+			UserID:         userID,
+			CatalogID:      &catalog.ID,
+			Points:         catalog.PointsRequired,
+			AssetType:      catalog.Type,
+			Status:         model.RewardStatusRedeemed,
+			IdempotencyKey: idempotencyKey,
+		}
+		if address != nil {
+			reward.ShippingAddressID = &address.ID
+			reward.RecipientName = address.RecipientName
+			reward.RecipientPhone = address.Phone
+			reward.ShippingAddress = address.Formatted()
+		}
+		if err := s.RewardRepo.WithTx(tx).CreateReward(reward); err != nil {
+			return err
+		}
+		if catalog.Type == model.CatalogTypeVoucher {
+			code, err := s.VoucherRepo.WithTx(tx).AssignNext(catalog.ID, reward.ID, userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOutOfStock
+			}
+			if err != nil {
+				return err
+			}
+			reward.VoucherCode = code
+		}
+		_, err = s.Points.PostTx(tx, PointPosting{
+			UserID:      userID,
+			Amount:      -catalog.PointsRequired,
+			EntryType:   model.PointEntryRedemption,
+			RefType:     "reward",
+			RefID:       strconv.FormatUint(uint64(reward.ID), 10),
+			Description: catalog.Name,
+		})
+		return err
+	})
+	if err != nil {
+		// Request paralel dengan key yang sama kalah di unique index; kembalikan hasil pemenangnya.
+		if idempotencyKey != "" {
+			if existing, findErr := s.findRedemption(userID, catalogID, idempotencyKey); findErr == nil {
+				return existing, true, nil
+			} else if errors.Is(findErr, ErrIdempotencyKeyReused) {
+				return nil, false, findErr
+			}
+		}
+		return nil, false, err
+	}
+	return reward, false, nil
+}
+
+func (s *RewardCatalogService) shippingAddress(tx *gorm.DB, userID, addressID uint) (*model.ShippingAddress, error) {
+	addresses := s.AddressRepo.WithTx(tx)
+	if addressID == 0 {
+		address, err := addresses.GetDefault(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShippingAddressRequired
+		}
+		return address, err
+	}
+	address, err := addresses.GetByID(userID, addressID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAddressNotFound
+	}
+	return address, err
+}
+
+func (s *RewardCatalogService) findRedemption(userID, catalogID uint, idempotencyKey string) (*model.Reward, error) {
+	reward, err := s.RewardRepo.GetByIdempotencyKey(userID, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if reward.CatalogID == nil || *reward.CatalogID != catalogID {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return reward, nil
 }
 
-func (s *RewardCatalogService) CreateCatalog(catalog *model.RewardCatalog) error {
+// CatalogActor adalah pemanggil endpoint pengelolaan katalog dan kode voucher.
+// Merchant hanya boleh mengelola item miliknya; AnyOwner (admin) boleh semua item
+// termasuk item platform tanpa pemilik.
+type CatalogActor struct {
+	UserID   uint
+	AnyOwner bool
+}
+
+func (a CatalogActor) owns(c *model.RewardCatalog) bool {
+	return a.AnyOwner || (c.MerchantID != nil && *c.MerchantID == a.UserID)
+}
+
+// CreateCatalog membuat item milik actor. Admin boleh mengisi MerchantID untuk
+// membuat item atas nama merchant, atau mengosongkannya untuk item platform.
+func (s *RewardCatalogService) CreateCatalog(actor CatalogActor, catalog *model.RewardCatalog) error {
+	if !actor.AnyOwner {
+		catalog.MerchantID = &actor.UserID
+	}
+	if catalog.Type != model.CatalogTypeVoucher && catalog.Type != model.CatalogTypeProduct {
+		return fmt.Errorf("%w: type harus voucher atau product", ErrInvalidCatalog)
+	}
+	// Stok voucher berasal dari kode yang di-upload, bukan dari request
+	if catalog.Type == model.CatalogTypeVoucher {
+		catalog.Stock = 0
+	}
+	catalog.ID, catalog.ArchivedAt = 0, nil
+	if err := validateCatalog(catalog); err != nil {
+		return err
+	}
 	return s.CatalogRepo.CreateCatalog(catalog)
 }
+
+// UpdateCatalog menerapkan patch ke item katalog (PUT mengirim semua field, PATCH sebagian).
+func (s *RewardCatalogService) UpdateCatalog(actor CatalogActor, id uint, p CatalogPatch) (*model.RewardCatalog, error) {
+	catalog, err := s.CatalogRepo.GetCatalogByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCatalogNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !actor.owns(catalog) {
+		return nil, ErrNotCatalogOwner
+	}
+	setIf(&catalog.Name, p.Name)
+	setIf(&catalog.Description, p.Description)
+	setIf(&catalog.Category, p.Category)
+	setIf(&catalog.ImageURLs, p.ImageURLs)
+	setIf(&catalog.PointsRequired, p.PointsRequired)
+	setIf(&catalog.MaxPerUser, p.MaxPerUser)
+	setIf(&catalog.AvailableFrom, p.AvailableFrom)
+	setIf(&catalog.AvailableUntil, p.AvailableUntil)
+	withStock := p.Stock != nil && catalog.Type != model.CatalogTypeVoucher
+	if withStock {
+		catalog.Stock = *p.Stock
+	}
+	if err := validateCatalog(catalog); err != nil {
+		return nil, err
+	}
+	if err := s.CatalogRepo.UpdateCatalog(catalog, withStock); err != nil {
+		return nil, err
+	}
+	return s.CatalogRepo.GetCatalogByID(id)
+}
+
+// ArchiveCatalog menyembunyikan item dari katalog tanpa menghapus riwayat redeem-nya.
+func (s *RewardCatalogService) ArchiveCatalog(actor CatalogActor, id uint) error {
+	catalog, err := s.CatalogRepo.GetCatalogByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCatalogNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !actor.owns(catalog) {
+		return ErrNotCatalogOwner
+	}
+	_, err = s.CatalogRepo.Archive(id, time.Now())
+	return err
+}
+
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+func validateCatalog(c *model.RewardCatalog) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Category = normalizeCategory(c.Category)
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("%w: name wajib diisi", ErrInvalidCatalog)
+	case c.PointsRequired <= 0:
+		return fmt.Errorf("%w: points_required harus > 0", ErrInvalidCatalog)
+	case c.Stock < 0:
+		return fmt.Errorf("%w: stock tidak boleh negatif", ErrInvalidCatalog)
+	case c.MaxPerUser < 0:
+		return fmt.Errorf("%w: max_per_user tidak boleh negatif", ErrInvalidCatalog)
+	case c.AvailableFrom != nil && c.AvailableUntil != nil && !c.AvailableUntil.After(*c.AvailableFrom):
+		return fmt.Errorf("%w: available_until harus setelah available_from", ErrInvalidCatalog)
+	case len(c.ImageURLs) > MaxCatalogImages:
+		return fmt.Errorf("%w: maksimal %d image_urls", ErrInvalidCatalog, MaxCatalogImages)
+	}
+	for _, raw := range c.ImageURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: image_urls harus URL http(s): %q", ErrInvalidCatalog, raw)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestRewardCatalogService(db *gorm.DB) *RewardCatalogService {
+	userRepo := repository.NewUserRepository(db)
+	return NewRewardCatalogService(
+		repository.NewRewardCatalogRepository(db),
+		repository.NewRewardRepository(db),
+		repository.NewVoucherCodeRepository(db),
+		repository.NewShippingAddressRepository(db),
+		NewPointService(userRepo, repository.NewPointTransactionRepository(db)),
+	)
+}
+
+// testAddress is a valid default shipping address for userID.
+func testAddress(userID uint) *model.ShippingAddress {
+	return &model.ShippingAddress{
+		UserID: userID, RecipientName: "Penerima", Phone: "081234567890",
+		AddressLine: "Jl. Merdeka 1", City: "Bandung", PostalCode: "40111", IsDefault: true,
+	}
+}
+
+func TestRedeemIsAtomicAndIdempotent(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestRewardCatalogService(db)
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+	mustCreate(t, db, testAddress(user.ID))
+	catalog := &model.RewardCatalog{Name: "Bibit Pohon", PointsRequired: 30, Stock: 5, Type: model.CatalogTypeProduct}
+	mustCreate(t, db, catalog)
+	if _, err := s.Points.Post(PointPosting{UserID: user.ID, Amount: 50, EntryType: model.PointEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, replayed, err := s.Redeem(user.ID, catalog.ID, 0, "key-1")
+	if err != nil || replayed {
+		t.Fatalf("Redeem = %+v, %v, %v", first, replayed, err)
+	}
+	again, replayed, err := s.Redeem(user.ID, catalog.ID, 0, "key-1")
+	if err != nil || !replayed || again.ID != first.ID {
+		t.Fatalf("retried Redeem = %+v, %v, %v; want reward %d replayed", again, replayed, err, first.ID)
+	}
+	if _, _, err := s.Redeem(user.ID, catalog.ID+1, 0, "key-1"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("key reuse err = %v, want ErrIdempotencyKeyReused", err)
+	}
+	// Point tinggal 20: redeem gagal dan stok tidak ikut berkurang
+	if _, _, err := s.Redeem(user.ID, catalog.ID, 0, "key-2"); !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("overspend err = %v, want ErrInsufficientPoints", err)
+	}
+
+	got, _ := s.GetCatalog(catalog.ID)
+	u, _ := s.Points.UserRepo.GetUserByID(user.ID)
+	var rewards int64
+	db.Model(&model.Reward{}).Count(&rewards)
+	if got.Stock != 4 || u.Points != 20 || rewards != 1 {
+		t.Fatalf("stock %d, points %d, rewards %d; want 4, 20, 1", got.Stock, u.Points, rewards)
+	}
+	assertLedgerBalanced(t, s.Points)
+}
+
+func TestConcurrentRedeemDoesNotOversell(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestRewardCatalogService(db)
+	catalog := &model.RewardCatalog{Name: "Tumbler", PointsRequired: 10, Stock: 3, Type: model.CatalogTypeProduct}
+	mustCreate(t, db, catalog)
+	var users []model.User
+	for i := 0; i < 8; i++ {
+		u := model.User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+		mustCreate(t, db, &u)
+		mustCreate(t, db, testAddress(u.ID))
+		if _, err := s.Points.Post(PointPosting{UserID: u.ID, Amount: 10, EntryType: model.PointEntryAdjustment}); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(users))
+	for i, u := range users {
+		wg.Add(1)
+		go func(i int, userID uint) {
+			defer wg.Done()
+			_, _, errs[i] = s.Redeem(userID, catalog.ID, 0, "")
+		}(i, u.ID)
+	}
+	wg.Wait()
+
+	redeemed := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			redeemed++
+		case !errors.Is(err, ErrOutOfStock):
+			t.Fatalf("Redeem err = %v", err)
+		}
+	}
+	got, _ := s.GetCatalog(catalog.ID)
+	var spent int64
+	db.Model(&model.User{}).Where("points = 0").Count(&spent)
+	if redeemed != 3 || got.Stock != 0 || spent != 3 {
+		t.Fatalf("redeemed %d, stock %d, users charged %d; want 3, 0, 3", redeemed, got.Stock, spent)
+	}
+	assertLedgerBalanced(t, s.Points)
+}
+
+func TestCatalogManagementAndListing(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestRewardCatalogService(db)
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+	mustCreate(t, db, testAddress(user.ID))
+	if _, err := s.Points.Post(PointPosting{UserID: user.ID, Amount: 100, EntryType: model.PointEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := CatalogActor{UserID: 1, AnyOwner: true}
+	if err := s.CreateCatalog(admin, &model.RewardCatalog{Name: "X", PointsRequired: 10, Type: model.CatalogTypeProduct, ImageURLs: []string{"ftp://x"}}); !errors.Is(err, ErrInvalidCatalog) {
+		t.Fatalf("invalid image url err = %v, want ErrInvalidCatalog", err)
+	}
+	tumbler := &model.RewardCatalog{Name: "Tumbler", Category: " Merchandise ", PointsRequired: 40, Stock: 5, Type: model.CatalogTypeProduct,
+		MaxPerUser: 1, ImageURLs: []string{"https://cdn.example.com/tumbler.jpg"}}
+	bag := &model.RewardCatalog{Name: "Tas", Category: "merchandise", PointsRequired: 150, Stock: 5, Type: model.CatalogTypeProduct}
+	tree := &model.RewardCatalog{Name: "Adopsi Pohon", Category: "donasi", PointsRequired: 10, Stock: 0, Type: model.CatalogTypeProduct}
+	for _, c := range []*model.RewardCatalog{tumbler, bag, tree} {
+		if err := s.CreateCatalog(admin, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := s.GetCatalog(tumbler.ID)
+	if got.Category != "merchandise" || len(got.ImageURLs) != 1 {
+		t.Fatalf("stored catalog = %+v", got)
+	}
+
+	list := func(q CatalogQuery) []string {
+		t.Helper()
+		items, total, err := s.ListCatalog(q)
+		if err != nil || int(total) < len(items) {
+			t.Fatalf("ListCatalog(%+v) = %d items (total %d), %v", q, len(items), total, err)
+		}
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	if names := list(CatalogQuery{Category: "merchandise", AffordableForUserID: user.ID}); len(names) != 1 || names[0] != "Tumbler" {
+		t.Fatalf("affordable merchandise = %v", names)
+	}
+	if names := list(CatalogQuery{InStock: true}); len(names) != 2 {
+		t.Fatalf("in stock = %v, want Tas and Tumbler", names)
+	}
+	if names := list(CatalogQuery{Limit: 1, Offset: 1}); len(names) != 1 || names[0] != "Tas" {
+		t.Fatalf("second page = %v", names)
+	}
+
+	// Belum mulai: tidak tampil dan tidak bisa diredeem
+	tomorrow := time.Now().Add(24 * time.Hour)
+	tomorrowPtr := &tomorrow
+	if _, err := s.UpdateCatalog(admin, bag.ID, CatalogPatch{AvailableFrom: &tomorrowPtr}); err != nil {
+		t.Fatal(err)
+	}
+	if names := list(CatalogQuery{Category: "merchandise"}); len(names) != 1 {
+		t.Fatalf("merchandise before bag starts = %v", names)
+	}
+	if _, _, err := s.Redeem(user.ID, bag.ID, 0, ""); !errors.Is(err, ErrCatalogUnavailable) {
+		t.Fatalf("redeem before start err = %v, want ErrCatalogUnavailable", err)
+	}
+	if names := list(CatalogQuery{IncludeInactive: true}); len(names) != 3 {
+		t.Fatalf("include inactive = %v", names)
+	}
+
+	if _, _, err := s.Redeem(user.ID, tumbler.ID, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Redeem(user.ID, tumbler.ID, 0, ""); !errors.Is(err, ErrRedemptionLimit) {
+		t.Fatalf("second redeem err = %v, want ErrRedemptionLimit", err)
+	}
+	if got, _ := s.GetCatalog(tumbler.ID); got.Stock != 4 {
+		t.Fatalf("stock after limited redeem = %d, want 4", got.Stock)
+	}
+
+	price := 0
+	if _, err := s.UpdateCatalog(admin, tree.ID, CatalogPatch{PointsRequired: &price}); !errors.Is(err, ErrInvalidCatalog) {
+		t.Fatalf("zero price err = %v, want ErrInvalidCatalog", err)
+	}
+	if err := s.ArchiveCatalog(admin, tumbler.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Redeem(user.ID, tumbler.ID, 0, ""); !errors.Is(err, ErrCatalogUnavailable) {
+		t.Fatalf("redeem archived err = %v, want ErrCatalogUnavailable", err)
+	}
+	if names := list(CatalogQuery{}); len(names) != 1 || names[0] != "Adopsi Pohon" {
+		t.Fatalf("catalog after archive = %v", names)
+	}
+}
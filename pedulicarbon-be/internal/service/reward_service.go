@@ -1,16 +1,61 @@
 package service
 
 import (
+	"errors"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/repository"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRewardNotFound          = errors.New("reward tidak ditemukan")
+	ErrInvalidRewardTransition = errors.New("perubahan status reward tidak diizinkan")
+	ErrTrackingNumberRequired  = errors.New("nomor resi wajib diisi saat status shipped")
+	ErrCancelReasonRequired    = errors.New("alasan pembatalan wajib diisi")
+)
+
+// Batas halaman GET /rewards.
+const (
+	DefaultRewardListLimit = 50
+	MaxRewardListLimit     = 200
 )
 
+// rewardTransitions lists the allowed status changes of a product Reward.
+var rewardTransitions = map[string][]string{
+	model.RewardStatusRedeemed: {model.RewardStatusPacked, model.RewardStatusCancelled},
+	model.RewardStatusPacked:   {model.RewardStatusShipped, model.RewardStatusCancelled},
+	model.RewardStatusShipped:  {model.RewardStatusDelivered},
+}
+
+func canTransitionReward(from, to string) bool {
+	for _, s := range rewardTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RewardStatusUpdate is an operator's change to a product reward. TrackingNumber is
+// required for shipped, Reason for cancelled.
+type RewardStatusUpdate struct {
+	Status         string
+	Courier        string
+	TrackingNumber string
+	Reason         string
+}
+
 type RewardService struct {
-	RewardRepo *repository.RewardRepository
+	RewardRepo  *repository.RewardRepository
+	CatalogRepo *repository.RewardCatalogRepository
+	Points      *PointService
 }
 
-func NewRewardService(rewardRepo *repository.RewardRepository) *RewardService {
-	return &RewardService{RewardRepo: rewardRepo}
+func NewRewardService(rewardRepo *repository.RewardRepository, catalogRepo *repository.RewardCatalogRepository, points *PointService) *RewardService {
+	return &RewardService{RewardRepo: rewardRepo, CatalogRepo: catalogRepo, Points: points}
 }
 
 func (s *RewardService) CreateReward(reward *model.Reward) error {
@@ -21,6 +66,95 @@ func (s *RewardService) GetUserRewards(userID uint) ([]model.Reward, error) {
 	return s.RewardRepo.GetRewardsByUserID(userID)
 }
 
-func (s *RewardService) UpdateRewardStatus(rewardID uint, status string) error {
-	return s.RewardRepo.UpdateRewardStatus(rewardID, status)
+// ListRewards returns a page of rewards for the fulfilment queue, oldest first.
+func (s *RewardService) ListRewards(f repository.RewardFilter, limit, offset int) ([]model.Reward, int64, error) {
+	if limit <= 0 {
+		limit = DefaultRewardListLimit
+	}
+	if limit > MaxRewardListLimit {
+		limit = MaxRewardListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.RewardRepo.List(f, limit, offset)
+}
+
+// UpdateRewardStatus memindahkan reward produk ke status berikutnya. Pembatalan
+// mengembalikan point user lewat ledger dan stok katalog dalam transaksi yang sama.
+func (s *RewardService) UpdateRewardStatus(rewardID uint, u RewardStatusUpdate) (*model.Reward, error) {
+	var reward *model.Reward
+	err := s.RewardRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		rewards := s.RewardRepo.WithTx(tx)
+		reward, err = rewards.GetByID(rewardID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRewardNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if reward.AssetType != model.CatalogTypeProduct || !canTransitionReward(reward.Status, u.Status) {
+			return ErrInvalidRewardTransition
+		}
+		now := time.Now()
+		updates := map[string]interface{}{"status": u.Status}
+		switch u.Status {
+		case model.RewardStatusPacked:
+			updates["packed_at"] = now
+		case model.RewardStatusShipped:
+			if u.TrackingNumber == "" {
+				return ErrTrackingNumberRequired
+			}
+			updates["shipped_at"] = now
+			updates["courier"] = u.Courier
+			updates["tracking_number"] = u.TrackingNumber
+		case model.RewardStatusDelivered:
+			updates["delivered_at"] = now
+		case model.RewardStatusCancelled:
+			if u.Reason == "" {
+				return ErrCancelReasonRequired
+			}
+			updates["cancelled_at"] = now
+			updates["cancel_reason"] = u.Reason
+		}
+		ok, err := rewards.UpdateStatusIf(rewardID, reward.Status, updates)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidRewardTransition
+		}
+		if u.Status == model.RewardStatusCancelled {
+			if err := s.refund(tx, reward); err != nil {
+				return err
+			}
+		}
+		reward, err = rewards.GetByID(rewardID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+// refund mengembalikan point dan stok dari reward yang dibatalkan sebelum dikirim.
+func (s *RewardService) refund(tx *gorm.DB, reward *model.Reward) error {
+	if reward.Points > 0 {
+		if _, err := s.Points.PostTx(tx, PointPosting{
+			UserID:      reward.UserID,
+			Amount:      reward.Points,
+			EntryType:   model.PointEntryRedemption,
+			RefType:     "reward_refund",
+			RefID:       strconv.FormatUint(uint64(reward.ID), 10),
+			Description: "pembatalan reward #" + strconv.FormatUint(uint64(reward.ID), 10),
+		}); err != nil {
+			return err
+		}
+	}
+	if reward.CatalogID != nil {
+		return s.CatalogRepo.WithTx(tx).IncrementStock(*reward.CatalogID)
+	}
+	return nil
 }
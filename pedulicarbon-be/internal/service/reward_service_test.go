@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"testing"
+)
+
+func TestProductRewardFulfilment(t *testing.T) {
+	db := newTestDB(t)
+	catalogs := newTestRewardCatalogService(db)
+	rewards := NewRewardService(catalogs.RewardRepo, catalogs.CatalogRepo, catalogs.Points)
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+	if _, err := catalogs.Points.Post(PointPosting{UserID: user.ID, Amount: 100, EntryType: model.PointEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+	catalog := &model.RewardCatalog{Name: "Tumbler", PointsRequired: 40, Stock: 2, Type: model.CatalogTypeProduct}
+	mustCreate(t, db, catalog)
+
+	if _, _, err := catalogs.Redeem(user.ID, catalog.ID, 0, ""); !errors.Is(err, ErrShippingAddressRequired) {
+		t.Fatalf("Redeem without address err = %v, want ErrShippingAddressRequired", err)
+	}
+	home, office := testAddress(user.ID), testAddress(user.ID)
+	office.IsDefault, office.AddressLine = false, "Jl. Asia Afrika 8"
+	mustCreate(t, db, home)
+	mustCreate(t, db, office)
+
+	shipped, _, err := catalogs.Redeem(user.ID, catalog.ID, office.ID, "")
+	if err != nil || shipped.ShippingAddress != "Jl. Asia Afrika 8, Bandung 40111" {
+		t.Fatalf("Redeem = %+v, %v; want the office address", shipped, err)
+	}
+	cancelled, _, err := catalogs.Redeem(user.ID, catalog.ID, 0, "")
+	if err != nil || cancelled.ShippingAddressID == nil || *cancelled.ShippingAddressID != home.ID {
+		t.Fatalf("Redeem with default address = %+v, %v", cancelled, err)
+	}
+
+	steps := []struct {
+		update RewardStatusUpdate
+		want   error
+	}{
+		{RewardStatusUpdate{Status: model.RewardStatusShipped, TrackingNumber: "JNE1"}, ErrInvalidRewardTransition},
+		{RewardStatusUpdate{Status: model.RewardStatusPacked}, nil},
+		{RewardStatusUpdate{Status: model.RewardStatusShipped}, ErrTrackingNumberRequired},
+		{RewardStatusUpdate{Status: model.RewardStatusShipped, Courier: "JNE", TrackingNumber: "JNE1"}, nil},
+		{RewardStatusUpdate{Status: model.RewardStatusCancelled, Reason: "terlambat"}, ErrInvalidRewardTransition},
+		{RewardStatusUpdate{Status: model.RewardStatusDelivered}, nil},
+	}
+	for _, step := range steps {
+		if _, err := rewards.UpdateRewardStatus(shipped.ID, step.update); !errors.Is(err, step.want) {
+			t.Fatalf("%s: err = %v, want %v", step.update.Status, err, step.want)
+		}
+	}
+	if got, _ := rewards.RewardRepo.GetByID(shipped.ID); got.TrackingNumber != "JNE1" || got.DeliveredAt == nil {
+		t.Fatalf("delivered reward = %+v", got)
+	}
+
+	if _, err := rewards.UpdateRewardStatus(cancelled.ID, RewardStatusUpdate{Status: model.RewardStatusCancelled}); !errors.Is(err, ErrCancelReasonRequired) {
+		t.Fatalf("cancel without reason err = %v", err)
+	}
+	if _, err := rewards.UpdateRewardStatus(cancelled.ID, RewardStatusUpdate{Status: model.RewardStatusCancelled, Reason: "stok rusak"}); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := catalogs.Points.UserRepo.GetUserByID(user.ID)
+	got, _ := catalogs.GetCatalog(catalog.ID)
+	if u.Points != 60 || got.Stock != 1 {
+		t.Fatalf("after cancel: points %d, stock %d; want 60, 1", u.Points, got.Stock)
+	}
+	assertLedgerBalanced(t, catalogs.Points)
+
+	queue, total, err := rewards.ListRewards(repository.RewardFilter{Status: model.RewardStatusCancelled, AssetType: model.CatalogTypeProduct}, 0, 0)
+	if err != nil || total != 1 || queue[0].ID != cancelled.ID {
+		t.Fatalf("ListRewards = %+v (total %d), %v", queue, total, err)
+	}
+}
+
+func TestShippingAddressDefaults(t *testing.T) {
+	db := newTestDB(t)
+	s := NewAddressService(repository.NewShippingAddressRepository(db))
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+
+	if err := s.Create(user.ID, &model.ShippingAddress{RecipientName: "A", Phone: "12345", AddressLine: "Jl. A", City: "Bandung", PostalCode: "40111"}); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("invalid phone err = %v, want ErrInvalidAddress", err)
+	}
+	first, second := testAddress(0), testAddress(0)
+	first.IsDefault, second.IsDefault = false, false
+	if err := s.Create(user.ID, first); err != nil || !first.IsDefault {
+		t.Fatalf("first address default = %v, %v; want true", first.IsDefault, err)
+	}
+	second.IsDefault = true
+	if err := s.Create(user.ID, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(user.ID, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := s.List(user.ID)
+	if len(list) != 1 || list[0].ID != first.ID || !list[0].IsDefault {
+		t.Fatalf("addresses after deleting default = %+v", list)
+	}
+	if err := s.Delete(user.ID+1, first.ID); !errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("deleting another user's address err = %v, want ErrAddressNotFound", err)
+	}
+}
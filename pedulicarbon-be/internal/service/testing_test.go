@@ -0,0 +1,70 @@
+package service
+
+import (
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/motoko"
+	"pedulicarbon/internal/repository"
+	"testing"
+
+	"github.com/aviate-labs/agent-go/principal"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testPrincipal is a valid self-authenticating principal used as NFT owner in tests.
+var testPrincipal = principal.NewSelfAuthenticating([]byte("pedulicarbon-test-user")).Encode()
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Satu koneksi supaya semua query melihat database in-memory yang sama
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.Mission{}, &model.Reward{}, &model.Wallet{}, &model.MissionTaken{},
+		&model.RewardCatalog{}, &model.Withdraw{}, &model.UserNFT{}, &model.PrincipalChallenge{},
+		&model.VerificationSaga{}, &model.Job{}, &model.NFTReconciliationAudit{}, &model.PointTransaction{}, &model.VoucherCode{}, &model.ShippingAddress{}, &model.Notification{}, &model.WalletTransaction{}, &model.PayoutEvent{}, &model.Beneficiary{}, &model.WithdrawDecision{}, &model.WalletQuarantine{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestMissionTakenService(t *testing.T, db *gorm.DB, canister motoko.CanisterClient) *MissionTakenService {
+	t.Helper()
+	jobs := NewJobService(repository.NewJobRepository(db))
+	userRepo := repository.NewUserRepository(db)
+	return NewMissionTakenService(
+		repository.NewMissionTakenRepository(db),
+		userRepo,
+		repository.NewMissionRepository(db),
+		canister,
+		repository.NewUserNFTRepository(db),
+		repository.NewVerificationSagaRepository(db),
+		jobs,
+		NewPointService(userRepo, repository.NewPointTransactionRepository(db)),
+	)
+}
+
+func newTestNFTService(db *gorm.DB, canister motoko.CanisterClient) *NFTService {
+	return NewNFTService(
+		repository.NewUserNFTRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewVerificationSagaRepository(db),
+		repository.NewNFTReconciliationRepository(db),
+		canister,
+	)
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, v interface{}) {
+	t.Helper()
+	if err := db.Create(v).Error; err != nil {
+		t.Fatal(err)
+	}
+}
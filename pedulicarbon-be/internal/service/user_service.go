@@ -1,24 +1,64 @@
 package service
 
 import (
+	"errors"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrWeakPassword       = errors.New("password minimal 8 karakter")
+	ErrInvalidRole        = errors.New("role tidak dikenal")
 )
 
+const minPasswordLength = 8
+
 type UserService struct {
-	UserRepo *repository.UserRepository
+	UserRepo   *repository.UserRepository
+	WalletRepo *repository.WalletRepository
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
-	return &UserService{UserRepo: userRepo}
+func NewUserService(userRepo *repository.UserRepository, walletRepo *repository.WalletRepository) *UserService {
+	return &UserService{UserRepo: userRepo, WalletRepo: walletRepo}
 }
 
-func (s *UserService) RegisterUser(user *model.User) error {
-	return s.UserRepo.CreateUser(user)
+// RegisterUser membuat user beserta wallet kosongnya dalam satu transaksi.
+func (s *UserService) RegisterUser(user *model.User, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	return s.UserRepo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.UserRepo.WithTx(tx).CreateUser(user); err != nil {
+			return err
+		}
+		_, err := s.WalletRepo.WithTx(tx).CreateIfMissing(user.ID)
+		return err
+	})
 }
 
-func (s *UserService) LoginUser(email string) (*model.User, error) {
-	return s.UserRepo.GetUserByEmail(email)
+func (s *UserService) LoginUser(email, password string) (*model.User, error) {
+	user, err := s.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		// Tetap jalankan bcrypt supaya waktu respon tidak membocorkan email terdaftar
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
 }
 
 func (s *UserService) GetProfile(userID uint) (*model.User, error) {
@@ -26,6 +66,14 @@ func (s *UserService) GetProfile(userID uint) (*model.User, error) {
 	return s.UserRepo.GetUserByID(userID)
 }
 
-func (s *UserService) AddPoints(userID uint, points int) error {
-	return s.UserRepo.UpdateUserPoints(userID, points)
+func (s *UserService) SetRole(userID uint, role string) error {
+	if !model.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	if _, err := s.UserRepo.GetUserByID(userID); err != nil {
+		return err
+	}
+	return s.UserRepo.UpdateUserRole(userID, role)
 }
+
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("pedulicarbon-dummy"), bcrypt.DefaultCost)
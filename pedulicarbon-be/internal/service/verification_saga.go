@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrVerificationInProgress = errors.New("verifikasi mission sedang diproses")
+
+const (
+	// sagaLeaseTTL bounds how long one worker may hold a saga before another may take over.
+	sagaLeaseTTL = 2 * time.Minute
+	// defaultMissionPoints dipakai bila mission tidak punya nilai points.
+	defaultMissionPoints = 10
+)
+
+func sagaIdempotencyKey(mtID uint, attempt int) string {
+	return fmt.Sprintf("verify-mission-taken-%d-attempt-%d", mtID, attempt)
+}
+
+// startVerificationSaga returns the saga for mt, creating it on first verification
+// and restarting it when a previous attempt failed or was compensated.
+func (s *MissionTakenService) startVerificationSaga(mt *model.MissionTaken, verifierID uint) (*model.VerificationSaga, error) {
+	saga, err := s.SagaRepo.GetByMissionTakenID(mt.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		saga = &model.VerificationSaga{
+			MissionTakenID: mt.ID,
+			UserID:         mt.UserID,
+			VerifierID:     verifierID,
+			Attempt:        1,
+			IdempotencyKey: sagaIdempotencyKey(mt.ID, 1),
+			Step:           model.SagaStepStarted,
+		}
+		if err := s.SagaRepo.CreateSaga(saga); err != nil {
+			// Unique index mission_taken_id: request lain baru saja membuat saga
+			return nil, ErrVerificationInProgress
+		}
+		return saga, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch saga.Step {
+	case model.SagaStepCompleted:
+		return nil, ErrInvalidTransition
+	case model.SagaStepFailed, model.SagaStepCompensated:
+		// Percobaan sebelumnya gagal; mulai ulang dengan idempotency key baru supaya
+		// canister tidak mengembalikan NFT yang sudah di-burn
+		attempt := saga.Attempt + 1
+		ok, err := s.SagaRepo.UpdateSagaFromStep(saga.ID, saga.Step, map[string]interface{}{
+			"attempt":         attempt,
+			"idempotency_key": sagaIdempotencyKey(mt.ID, attempt),
+			"step":            model.SagaStepStarted,
+			"nft_id":          "",
+			"last_error":      "",
+			"verifier_id":     verifierID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrVerificationInProgress
+		}
+	}
+	return saga, nil
+}
+
+// runVerificationSaga drives the saga from its persisted step to completion.
+// Every step is safe to repeat, so a crashed run can simply be run again.
+func (s *MissionTakenService) runVerificationSaga(sagaID uint) error {
+	ok, err := s.SagaRepo.AcquireLease(sagaID, sagaLeaseTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrVerificationInProgress
+	}
+	defer s.SagaRepo.ReleaseLease(sagaID)
+
+	saga, err := s.SagaRepo.GetByID(sagaID)
+	if err != nil {
+		return err
+	}
+	if saga.Step == model.SagaStepCompleted {
+		return nil
+	}
+	if saga.IsTerminal() {
+		return ErrInvalidTransition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mt, err := s.MissionTakenRepo.GetByID(saga.MissionTakenID)
+	if err != nil {
+		return err
+	}
+	if mt.Status != model.MissionStatusPending {
+		// Mission sudah ditolak/diubah selama saga berjalan: batalkan efek di canister
+		if err := s.compensateSaga(ctx, saga, mt, fmt.Sprintf("mission taken berstatus %s", mt.Status)); err != nil {
+			return err
+		}
+		return ErrInvalidTransition
+	}
+	user, err := s.UserRepo.GetUserByID(mt.UserID)
+	if err != nil {
+		return err
+	}
+	mission, err := s.MissionRepo.GetMissionByID(mt.MissionID)
+	if err != nil {
+		return err
+	}
+
+	// Step 1: Verify Action di Motoko
+	if saga.Step == model.SagaStepStarted {
+		fmt.Printf("[DEBUG] Saga %d: VerifyAction user=%s mission=%d\n", saga.ID, user.IIPrincipal, mt.MissionID)
+		verified, err := s.Canister.VerifyAction(ctx, user.IIPrincipal, mt.MissionID, mt.ProofURL, mt.GPS)
+		if err != nil {
+			s.recordSagaError(saga, err)
+			return err
+		}
+		if !verified {
+			s.SagaRepo.UpdateSaga(saga.ID, map[string]interface{}{
+				"step":       model.SagaStepFailed,
+				"last_error": "verify_action returned false",
+			})
+			return ErrCanisterRejected
+		}
+		if err := s.advanceSaga(saga, model.SagaStepCanisterVerified, nil); err != nil {
+			return err
+		}
+	}
+
+	// Step 2: Mint NFT ke Motoko (idempotent lewat idempotency key)
+	if saga.Step == model.SagaStepCanisterVerified {
+		nftID, err := s.Canister.MintNFT(ctx, user.IIPrincipal, mt.MissionID, mission.AssetAmount, saga.IdempotencyKey)
+		if err != nil {
+			s.recordSagaError(saga, err)
+			return err
+		}
+		fmt.Printf("[DEBUG] Saga %d: NFT minted %s\n", saga.ID, nftID)
+		if err := s.advanceSaga(saga, model.SagaStepMinted, map[string]interface{}{"nft_id": nftID}); err != nil {
+			return err
+		}
+		saga.NFTID = nftID
+	}
+
+	// Step 3: status, mapping NFT dan point dalam satu transaksi
+	return s.completeVerification(ctx, saga, mt, mission)
+}
+
+func (s *MissionTakenService) completeVerification(ctx context.Context, saga *model.VerificationSaga, mt *model.MissionTaken, mission *model.Mission) error {
+	points := mission.Points
+	if points <= 0 {
+		points = defaultMissionPoints
+	}
+	err := s.MissionTakenRepo.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		ok, err := s.MissionTakenRepo.WithTx(tx).UpdateStatus(mt.ID, model.MissionStatusPending, map[string]interface{}{
+			"status":      model.MissionStatusVerified,
+			"verified_at": now,
+			"verified_by": saga.VerifierID,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTransition
+		}
+		if err := s.UserNFTRepo.WithTx(tx).CreateUserNFT(&model.UserNFT{
+			UserID:       mt.UserID,
+			NFTID:        saga.NFTID,
+			Status:       "owned",
+			CarbonAmount: mission.AssetAmount,
+			Simulated:    s.Canister.Simulated(),
+		}); err != nil {
+			return err
+		}
+		if _, err := s.Points.PostTx(tx, PointPosting{
+			UserID:      mt.UserID,
+			Amount:      points,
+			EntryType:   model.PointEntryMissionReward,
+			RefType:     "mission_taken",
+			RefID:       strconv.FormatUint(uint64(mt.ID), 10),
+			Description: mission.Title,
+		}); err != nil {
+			return err
+		}
+		return s.SagaRepo.WithTx(tx).UpdateSaga(saga.ID, map[string]interface{}{
+			"step":         model.SagaStepCompleted,
+			"completed_at": now,
+			"last_error":   "",
+		})
+	})
+	if errors.Is(err, ErrInvalidTransition) {
+		if cerr := s.compensateSaga(ctx, saga, mt, "mission taken tidak lagi pending"); cerr != nil {
+			return cerr
+		}
+		return ErrInvalidTransition
+	}
+	if err != nil {
+		s.recordSagaError(saga, err)
+		return err
+	}
+	fmt.Printf("[DEBUG] Saga %d: mission taken %d verified, user %d +%d points\n", saga.ID, mt.ID, mt.UserID, points)
+	return nil
+}
+
+// compensateSaga membatalkan NFT yang mungkin sudah di-mint lalu menandai saga compensated.
+func (s *MissionTakenService) compensateSaga(ctx context.Context, saga *model.VerificationSaga, mt *model.MissionTaken, reason string) error {
+	nftID := saga.NFTID
+	if nftID == "" && saga.Step == model.SagaStepCanisterVerified {
+		// Proses bisa mati setelah mint sukses tapi sebelum nft_id tersimpan. Mint ulang
+		// dengan key yang sama mengembalikan NFT tersebut (atau membuatnya) untuk di-burn.
+		user, err := s.UserRepo.GetUserByID(mt.UserID)
+		if err != nil {
+			return err
+		}
+		nftID, err = s.Canister.MintNFT(ctx, user.IIPrincipal, mt.MissionID, 0, saga.IdempotencyKey)
+		if err != nil {
+			s.recordSagaError(saga, err)
+			return err
+		}
+	}
+	if nftID != "" {
+		if err := s.Canister.BurnNFT(ctx, nftID); err != nil {
+			s.recordSagaError(saga, err)
+			return err
+		}
+	}
+	fmt.Printf("[DEBUG] Saga %d compensated: %s\n", saga.ID, reason)
+	return s.SagaRepo.UpdateSaga(saga.ID, map[string]interface{}{
+		"step":       model.SagaStepCompensated,
+		"nft_id":     nftID,
+		"last_error": reason,
+	})
+}
+
+func (s *MissionTakenService) advanceSaga(saga *model.VerificationSaga, step string, extra map[string]interface{}) error {
+	updates := map[string]interface{}{"step": step, "last_error": ""}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	if err := s.SagaRepo.UpdateSaga(saga.ID, updates); err != nil {
+		return err
+	}
+	saga.Step = step
+	return nil
+}
+
+func (s *MissionTakenService) recordSagaError(saga *model.VerificationSaga, err error) {
+	fmt.Printf("[ERROR] Saga %d step %s: %v\n", saga.ID, saga.Step, err)
+	s.SagaRepo.UpdateSaga(saga.ID, map[string]interface{}{"last_error": err.Error()})
+}
+
+// ResumeVerifications melanjutkan atau me-rollback saga yang terhenti, misalnya
+// karena proses mati di tengah VerifyMission. Aman dipanggil berulang kali.
+func (s *MissionTakenService) ResumeVerifications() {
+	sagas, err := s.SagaRepo.ListUnfinished()
+	if err != nil {
+		fmt.Printf("[ERROR] ListUnfinished sagas error: %v\n", err)
+		return
+	}
+	for _, saga := range sagas {
+		if err := s.runVerificationSaga(saga.ID); err != nil && !errors.Is(err, ErrVerificationInProgress) {
+			fmt.Printf("[ERROR] Resume saga %d error: %v\n", saga.ID, err)
+		}
+	}
+}
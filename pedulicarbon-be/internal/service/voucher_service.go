@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotVoucherCatalog  = errors.New("item katalog bukan voucher")
+	ErrEmptyVoucherBatch  = errors.New("file tidak berisi kode voucher")
+	ErrVoucherBatchSize   = fmt.Errorf("maksimal %d kode voucher per upload", MaxVoucherBatchSize)
+	ErrVoucherNotFound    = errors.New("kode voucher tidak ditemukan")
+	ErrVoucherNotAssigned = errors.New("kode voucher belum ditukarkan user")
+	ErrVoucherUsed        = errors.New("kode voucher sudah dipakai")
+)
+
+// MaxVoucherBatchSize membatasi jumlah kode dalam satu file upload.
+const MaxVoucherBatchSize = 10000
+
+// VoucherUploadResult ringkasan satu batch upload kode voucher.
+type VoucherUploadResult struct {
+	BatchID  string `json:"batch_id"`
+	Received int    `json:"received"`
+	Inserted int64  `json:"inserted"`
+	Skipped  int64  `json:"skipped"` // kode yang sudah pernah di-upload untuk katalog ini
+	Stock    int64  `json:"stock"`
+}
+
+type VoucherService struct {
+	VoucherRepo *repository.VoucherCodeRepository
+	CatalogRepo *repository.RewardCatalogRepository
+	RewardRepo  *repository.RewardRepository
+}
+
+func NewVoucherService(voucherRepo *repository.VoucherCodeRepository, catalogRepo *repository.RewardCatalogRepository, rewardRepo *repository.RewardRepository) *VoucherService {
+	return &VoucherService{VoucherRepo: voucherRepo, CatalogRepo: catalogRepo, RewardRepo: rewardRepo}
+}
+
+// ParseVoucherCSV membaca kode voucher dari kolom pertama CSV. Baris kosong dan
+// header "code" dilewati, kode ganda dalam file yang sama hanya diambil sekali.
+func ParseVoucherCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	seen := map[string]bool{}
+	var codes []string
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		code := strings.TrimSpace(record[0])
+		if code == "" || (line == 0 && strings.EqualFold(code, "code")) || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+		if len(codes) > MaxVoucherBatchSize {
+			return nil, ErrVoucherBatchSize
+		}
+	}
+	if len(codes) == 0 {
+		return nil, ErrEmptyVoucherBatch
+	}
+	return codes, nil
+}
+
+// UploadCodes menambahkan satu batch kode ke item katalog voucher lalu menyamakan
+// stok dengan jumlah kode yang masih available.
+func (s *VoucherService) UploadCodes(actor CatalogActor, catalogID uint, codes []string) (*VoucherUploadResult, error) {
+	if len(codes) == 0 {
+		return nil, ErrEmptyVoucherBatch
+	}
+	if len(codes) > MaxVoucherBatchSize {
+		return nil, ErrVoucherBatchSize
+	}
+	result := &VoucherUploadResult{
+		BatchID:  fmt.Sprintf("batch-%d-%d", catalogID, time.Now().UnixNano()),
+		Received: len(codes),
+	}
+	err := s.VoucherRepo.DB.Transaction(func(tx *gorm.DB) error {
+		catalog, err := s.CatalogRepo.WithTx(tx).GetCatalogByID(catalogID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCatalogNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !actor.owns(catalog) {
+			return ErrNotCatalogOwner
+		}
+		if catalog.Type != model.CatalogTypeVoucher {
+			return ErrNotVoucherCatalog
+		}
+		rows := make([]model.VoucherCode, len(codes))
+		for i, code := range codes {
+			rows[i] = model.VoucherCode{CatalogID: catalogID, Code: code, BatchID: result.BatchID, Status: model.VoucherStatusAvailable}
+		}
+		if result.Inserted, err = s.VoucherRepo.WithTx(tx).CreateCodes(rows); err != nil {
+			return err
+		}
+		result.Skipped = int64(len(codes)) - result.Inserted
+		result.Stock, err = syncVoucherStock(tx, s.VoucherRepo, s.CatalogRepo, catalogID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MarkUsed dipanggil merchant saat user menunjukkan kodenya. Hanya kode yang sudah
+// diberikan ke user (assigned) yang bisa dipakai, hanya sekali, dan hanya oleh
+// merchant pemilik item.
+func (s *VoucherService) MarkUsed(actor CatalogActor, catalogID uint, code string) (*model.VoucherCode, error) {
+	merchantID := actor.UserID
+	var voucher *model.VoucherCode
+	err := s.VoucherRepo.DB.Transaction(func(tx *gorm.DB) error {
+		catalog, err := s.CatalogRepo.WithTx(tx).GetCatalogByID(catalogID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVoucherNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !actor.owns(catalog) {
+			return ErrNotCatalogOwner
+		}
+		voucher, err = s.VoucherRepo.WithTx(tx).GetByCode(catalogID, code)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVoucherNotFound
+		}
+		if err != nil {
+			return err
+		}
+		switch voucher.Status {
+		case model.VoucherStatusAvailable:
+			return ErrVoucherNotAssigned
+		case model.VoucherStatusUsed:
+			return ErrVoucherUsed
+		}
+		now := time.Now()
+		ok, err := s.VoucherRepo.WithTx(tx).UpdateStatusIf(voucher.ID, model.VoucherStatusAssigned, map[string]interface{}{
+			"status":  model.VoucherStatusUsed,
+			"used_at": now,
+			"used_by": merchantID,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrVoucherUsed
+		}
+		voucher.Status, voucher.UsedAt, voucher.UsedBy = model.VoucherStatusUsed, &now, &merchantID
+		if voucher.RewardID != nil {
+			return s.RewardRepo.WithTx(tx).UpdateRewardStatus(*voucher.RewardID, model.RewardStatusUsed)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return voucher, nil
+}
+
+// SyncVoucherStock menyamakan stok semua item katalog voucher dengan jumlah kode
+// yang masih available. Dipanggil saat startup untuk data dari sebelum ada upload kode.
+func (s *VoucherService) SyncVoucherStock() error {
+	catalogs, err := s.CatalogRepo.ListByType(model.CatalogTypeVoucher)
+	if err != nil {
+		return err
+	}
+	for _, catalog := range catalogs {
+		if _, err := syncVoucherStock(s.VoucherRepo.DB, s.VoucherRepo, s.CatalogRepo, catalog.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncVoucherStock(tx *gorm.DB, vouchers *repository.VoucherCodeRepository, catalogs *repository.RewardCatalogRepository, catalogID uint) (int64, error) {
+	n, err := vouchers.WithTx(tx).CountAvailable(catalogID)
+	if err != nil {
+		return 0, err
+	}
+	return n, catalogs.WithTx(tx).SetStock(catalogID, n)
+}
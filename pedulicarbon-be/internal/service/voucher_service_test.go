@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"strings"
+	"testing"
+)
+
+func TestParseVoucherCSV(t *testing.T) {
+	codes, err := ParseVoucherCSV(strings.NewReader("code,expires\nAAA-1,2026-12-31\n\n  BBB-2 \nAAA-1\n"))
+	if err != nil || len(codes) != 2 || codes[0] != "AAA-1" || codes[1] != "BBB-2" {
+		t.Fatalf("ParseVoucherCSV = %q, %v", codes, err)
+	}
+	if _, err := ParseVoucherCSV(strings.NewReader("code\n")); !errors.Is(err, ErrEmptyVoucherBatch) {
+		t.Fatalf("empty file err = %v, want ErrEmptyVoucherBatch", err)
+	}
+}
+
+func TestVoucherCodesDriveStockAndRedemption(t *testing.T) {
+	db := newTestDB(t)
+	catalogs := newTestRewardCatalogService(db)
+	vouchers := NewVoucherService(catalogs.VoucherRepo, catalogs.CatalogRepo, repository.NewRewardRepository(db))
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+	if _, err := catalogs.Points.Post(PointPosting{UserID: user.ID, Amount: 100, EntryType: model.PointEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+	merchant := CatalogActor{UserID: 50}
+	other := CatalogActor{UserID: 51}
+	catalog := &model.RewardCatalog{Name: "Voucher Kopi", PointsRequired: 20, Stock: 50, Type: model.CatalogTypeVoucher}
+	if err := catalogs.CreateCatalog(merchant, catalog); err != nil {
+		t.Fatal(err)
+	}
+	if catalog.MerchantID == nil || *catalog.MerchantID != merchant.UserID {
+		t.Fatalf("catalog merchant = %v, want %d", catalog.MerchantID, merchant.UserID)
+	}
+	// Merchant lain tidak bisa mengubah item ini
+	if _, err := vouchers.UploadCodes(other, catalog.ID, []string{"X-1"}); !errors.Is(err, ErrNotCatalogOwner) {
+		t.Fatalf("UploadCodes by other merchant err = %v, want ErrNotCatalogOwner", err)
+	}
+	if _, err := catalogs.UpdateCatalog(other, catalog.ID, CatalogPatch{}); !errors.Is(err, ErrNotCatalogOwner) {
+		t.Fatalf("UpdateCatalog by other merchant err = %v, want ErrNotCatalogOwner", err)
+	}
+	if err := catalogs.ArchiveCatalog(other, catalog.ID); !errors.Is(err, ErrNotCatalogOwner) {
+		t.Fatalf("ArchiveCatalog by other merchant err = %v, want ErrNotCatalogOwner", err)
+	}
+	if catalog.Stock != 0 {
+		t.Fatalf("new voucher catalog stock = %d, want 0 until codes are uploaded", catalog.Stock)
+	}
+
+	result, err := vouchers.UploadCodes(merchant, catalog.ID, []string{"KOPI-1", "KOPI-2"})
+	if err != nil || result.Inserted != 2 || result.Stock != 2 {
+		t.Fatalf("UploadCodes = %+v, %v", result, err)
+	}
+	if result, err = vouchers.UploadCodes(CatalogActor{UserID: 1, AnyOwner: true}, catalog.ID, []string{"KOPI-2", "KOPI-3"}); err != nil || result.Skipped != 1 || result.Stock != 3 {
+		t.Fatalf("second UploadCodes = %+v, %v; want 1 skipped, stock 3", result, err)
+	}
+
+	reward, _, err := catalogs.Redeem(user.ID, catalog.ID, 0, "")
+	if err != nil || reward.VoucherCode == nil || reward.VoucherCode.Code != "KOPI-1" {
+		t.Fatalf("Redeem = %+v, %v; want code KOPI-1", reward, err)
+	}
+	history, _ := vouchers.RewardRepo.GetRewardsByUserID(user.ID)
+	if len(history) != 1 || history[0].VoucherCode == nil || history[0].VoucherCode.Code != "KOPI-1" {
+		t.Fatalf("reward history = %+v, want the assigned code", history)
+	}
+	if got, _ := catalogs.GetCatalog(catalog.ID); got.Stock != 2 {
+		t.Fatalf("stock after redeem = %d, want 2", got.Stock)
+	}
+
+	if _, err := vouchers.MarkUsed(other, catalog.ID, "KOPI-1"); !errors.Is(err, ErrNotCatalogOwner) {
+		t.Fatalf("MarkUsed by other merchant err = %v, want ErrNotCatalogOwner", err)
+	}
+	if _, err := vouchers.MarkUsed(merchant, catalog.ID, "KOPI-3"); !errors.Is(err, ErrVoucherNotAssigned) {
+		t.Fatalf("MarkUsed unassigned err = %v, want ErrVoucherNotAssigned", err)
+	}
+	if used, err := vouchers.MarkUsed(merchant, catalog.ID, "KOPI-1"); err != nil || used.Status != model.VoucherStatusUsed || *used.UsedBy != merchant.UserID {
+		t.Fatalf("MarkUsed = %+v, %v", used, err)
+	}
+	if _, err := vouchers.MarkUsed(merchant, catalog.ID, "KOPI-1"); !errors.Is(err, ErrVoucherUsed) {
+		t.Fatalf("second MarkUsed err = %v, want ErrVoucherUsed", err)
+	}
+	if history, _ = vouchers.RewardRepo.GetRewardsByUserID(user.ID); history[0].Status != "used" {
+		t.Fatalf("reward status = %s, want used", history[0].Status)
+	}
+}
+
+func TestVoucherRedeemFailsWithoutCodes(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestRewardCatalogService(db)
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	mustCreate(t, db, user)
+	if _, err := s.Points.Post(PointPosting{UserID: user.ID, Amount: 50, EntryType: model.PointEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+	// Data lama: stok terisi tapi belum ada kode yang di-upload
+	catalog := &model.RewardCatalog{Name: "Voucher Lama", PointsRequired: 10, Stock: 5, Type: model.CatalogTypeVoucher}
+	mustCreate(t, db, catalog)
+
+	if _, _, err := s.Redeem(user.ID, catalog.ID, 0, ""); !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("Redeem err = %v, want ErrOutOfStock", err)
+	}
+	if u, _ := s.Points.UserRepo.GetUserByID(user.ID); u.Points != 50 {
+		t.Fatalf("points = %d, want 50 (nothing debited)", u.Points)
+	}
+}
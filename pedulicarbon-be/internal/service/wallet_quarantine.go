@@ -0,0 +1,182 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pedulicarbon/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuarantineNotFound = errors.New("saldo karantina tidak ditemukan")
+	ErrQuarantineResolved = errors.New("saldo karantina sudah diselesaikan")
+	ErrResolutionReason   = errors.New("alasan wajib diisi")
+)
+
+// QuarantineDuplicateWallets menghapus wallet ganda (POST /wallets dulu bisa
+// dipanggil berkali-kali) kecuali yang tertua, setelah menyalin setiap baris yang
+// dihapus ke wallet_quarantines. Harus dijalankan sebelum unique index user_id dibuat.
+func (s *WalletService) QuarantineDuplicateWallets() (int, error) {
+	n := 0
+	err := s.WalletRepo.DB.Transaction(func(tx *gorm.DB) error {
+		wallets := s.WalletRepo.WithTx(tx)
+		rows, err := wallets.ListDuplicateWallets()
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			for k, v := range row {
+				if b, ok := v.([]byte); ok {
+					row[k] = string(b)
+				}
+			}
+			snapshot, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			walletID, userID := rowUint(row["id"]), rowUint(row["user_id"])
+			amount := row["rupiah"]
+			if amount == nil {
+				amount = row["legacy_rupiah"]
+			}
+			if err := s.QuarantineRepo.WithTx(tx).Create(&model.WalletQuarantine{
+				UserID:   userID,
+				WalletID: walletID,
+				Source:   model.QuarantineDuplicateWallet,
+				Amount:   fmt.Sprint(amount),
+				Snapshot: string(snapshot),
+			}); err != nil {
+				return err
+			}
+			if err := wallets.DeleteByID(walletID); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func rowUint(v interface{}) uint {
+	n, _ := strconv.ParseUint(fmt.Sprint(v), 10, 64)
+	return uint(n)
+}
+
+// QuarantineUnexplainedBalances memindahkan saldo rupiah yang tidak berasal dari
+// ledger ke karantina: nilai float lama di legacy_rupiah dan wallets.rupiah yang
+// berbeda dengan jumlah ledger. Saldo wallet di-reset ke jumlah ledger; saldo
+// lama ditulis client lewat POST/PUT /wallets sehingga tidak boleh bisa ditarik.
+func (s *WalletService) QuarantineUnexplainedBalances() ([]model.WalletQuarantine, error) {
+	var quarantined []model.WalletQuarantine
+	legacy, err := s.WalletRepo.ListLegacyRupiah()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range legacy {
+		q := model.WalletQuarantine{UserID: row.UserID, WalletID: row.WalletID, Source: model.QuarantineLegacyColumn, Amount: row.Amount}
+		err := s.WalletRepo.DB.Transaction(func(tx *gorm.DB) error {
+			if err := s.QuarantineRepo.WithTx(tx).Create(&q); err != nil {
+				return err
+			}
+			return s.WalletRepo.WithTx(tx).ClearLegacyRupiah(row.WalletID)
+		})
+		if err != nil {
+			return quarantined, err
+		}
+		quarantined = append(quarantined, q)
+	}
+
+	rows, err := s.LedgerRepo.ListUnexplainedBalances()
+	if err != nil {
+		return quarantined, err
+	}
+	for _, row := range rows {
+		q := model.WalletQuarantine{
+			UserID:       row.UserID,
+			Source:       model.QuarantineUnexplained,
+			Amount:       strconv.FormatInt(row.Rupiah, 10),
+			LedgerRupiah: row.LedgerSum,
+		}
+		err := s.WalletRepo.DB.Transaction(func(tx *gorm.DB) error {
+			ok, err := s.WalletRepo.WithTx(tx).SetRupiahIf(row.UserID, row.Rupiah, row.LedgerSum)
+			if err != nil || !ok {
+				// Saldo berubah sejak dibaca; dicek lagi pada start berikutnya
+				return err
+			}
+			return s.QuarantineRepo.WithTx(tx).Create(&q)
+		})
+		if err != nil {
+			return quarantined, err
+		}
+		if q.ID != 0 {
+			quarantined = append(quarantined, q)
+		}
+	}
+	return quarantined, nil
+}
+
+// ListQuarantine returns quarantined balances; only unresolved ones unless all is set.
+func (s *WalletService) ListQuarantine(all bool) ([]model.WalletQuarantine, error) {
+	return s.QuarantineRepo.List(all)
+}
+
+// ResolveQuarantine menyelesaikan satu saldo karantina atas keputusan admin.
+// amount > 0 mengembalikan dana yang terbukti sah sebagai adjustment ke saldo
+// user; amount 0 menyatakan saldo tidak sah tanpa posting. Alasan wajib diisi.
+func (s *WalletService) ResolveQuarantine(adminID, id uint, amount int64, reason string) (*model.WalletQuarantine, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrResolutionReason
+	}
+	if amount < 0 {
+		return nil, ErrInvalidRupiahAmount
+	}
+	var q *model.WalletQuarantine
+	err := s.WalletRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.QuarantineRepo.WithTx(tx)
+		q, err = repo.LockByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrQuarantineNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if q.ResolvedAt != nil {
+			return ErrQuarantineResolved
+		}
+		resolution := "ditolak: " + reason
+		if amount > 0 {
+			resolution = fmt.Sprintf("dikembalikan Rp%d: %s", amount, reason)
+			if _, err := s.PostTx(tx, RupiahPosting{
+				UserID:      q.UserID,
+				Amount:      amount,
+				EntryType:   model.RupiahEntryAdjustment,
+				RefType:     "wallet_quarantine",
+				RefID:       strconv.FormatUint(uint64(q.ID), 10),
+				Description: reason + " (oleh user " + strconv.FormatUint(uint64(adminID), 10) + ")",
+			}); err != nil {
+				return err
+			}
+		}
+		ok, err := repo.Resolve(id, adminID, resolution, time.Now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrQuarantineResolved
+		}
+		q, err = repo.LockByID(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
@@ -1,26 +1,237 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"pedulicarbon/internal/model"
 	"pedulicarbon/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWalletNotFound      = errors.New("wallet tidak ditemukan")
+	ErrInsufficientRupiah  = errors.New("saldo rupiah tidak cukup")
+	ErrInvalidRupiahAmount = errors.New("jumlah rupiah tidak valid")
+	// ErrRupiahAlreadyPosted menandakan sumber (ref) yang sama sudah pernah diposting.
+	ErrRupiahAlreadyPosted = errors.New("rupiah untuk referensi ini sudah diposting")
+)
+
+// Akun lawan untuk setiap jenis entri ledger rupiah; hold dan release penarikan
+// memakai akun hold milik user (holdAccount).
+var rupiahSystemAccounts = map[string]string{
+	model.RupiahEntryAdjustment: "system:rupiah_adjustments",
+}
+
+// Akun tujuan saat hold penarikan dibayarkan.
+const (
+	rupiahPayoutAccount = "system:withdrawal_payouts"
+	rupiahFeeAccount    = "system:withdrawal_fees"
+)
+
+// holdAccount menampung rupiah user yang ditahan untuk penarikan yang belum selesai.
+func holdAccount(userID uint) string {
+	return "hold:" + userAccount(userID)
+}
+
+// Batas halaman GET /wallets/user/:user_id/transactions.
+const (
+	DefaultWalletHistoryLimit = 50
+	MaxWalletHistoryLimit     = 200
 )
 
+// RupiahPosting is one change to a user's rupiah balance. Amount is in whole
+// rupiah and added to the balance (negative to spend); the counter line goes to
+// the entry type's system account.
+type RupiahPosting struct {
+	UserID      uint
+	Amount      int64
+	EntryType   string
+	RefType     string
+	RefID       string
+	Description string
+}
+
+// WalletService is the only writer of Wallet.Rupiah: every change is posted as a
+// balanced journal in wallet_transactions in the same transaction as the balance
+// update. Points and carbon are read from their own ledgers.
 type WalletService struct {
-	WalletRepo *repository.WalletRepository
+	WalletRepo     *repository.WalletRepository
+	LedgerRepo     *repository.WalletTransactionRepository
+	UserRepo       *repository.UserRepository
+	UserNFTRepo    *repository.UserNFTRepository
+	QuarantineRepo *repository.WalletQuarantineRepository
 }
 
-func NewWalletService(walletRepo *repository.WalletRepository) *WalletService {
-	return &WalletService{WalletRepo: walletRepo}
+func NewWalletService(walletRepo *repository.WalletRepository, ledgerRepo *repository.WalletTransactionRepository, userRepo *repository.UserRepository, userNFTRepo *repository.UserNFTRepository, quarantineRepo *repository.WalletQuarantineRepository) *WalletService {
+	return &WalletService{WalletRepo: walletRepo, LedgerRepo: ledgerRepo, UserRepo: userRepo, UserNFTRepo: userNFTRepo, QuarantineRepo: quarantineRepo}
 }
 
+// GetWallet returns the user's wallet with points and carbon filled in from the
+// point ledger and the NFTs the user still owns.
 func (s *WalletService) GetWallet(userID uint) (*model.Wallet, error) {
-	return s.WalletRepo.GetWalletByUserID(userID)
+	wallet, err := s.WalletRepo.GetWalletByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Points = user.Points
+	if wallet.HeldRupiah, err = s.LedgerRepo.SumByAccount(holdAccount(userID)); err != nil {
+		return nil, err
+	}
+	if wallet.CarbonNFT, err = s.UserNFTRepo.SumCarbonByUserID(userID); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// EnsureWallet provisions the user's wallet if it does not exist yet and reports
+// whether it was created.
+func (s *WalletService) EnsureWallet(userID uint) (*model.Wallet, bool, error) {
+	if _, err := s.UserRepo.GetUserByID(userID); err != nil {
+		return nil, false, err
+	}
+	created, err := s.WalletRepo.CreateIfMissing(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	wallet, err := s.GetWallet(userID)
+	return wallet, created, err
+}
+
+// ProvisionMissingWallets creates wallets for users registered before wallets were
+// provisioned at registration.
+func (s *WalletService) ProvisionMissingWallets() (int, error) {
+	ids, err := s.WalletRepo.ListUserIDsWithoutWallet()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		created, err := s.WalletRepo.CreateIfMissing(id)
+		if err != nil {
+			return n, err
+		}
+		if created {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Post posts p in its own transaction.
+func (s *WalletService) Post(p RupiahPosting) (*model.WalletTransaction, error) {
+	var entry *model.WalletTransaction
+	err := s.WalletRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = s.PostTx(tx, p)
+		return err
+	})
+	return entry, err
+}
+
+// PostTx posts p inside tx and returns the user's ledger line. A debit larger than
+// the balance fails with ErrInsufficientRupiah; posting the same non-empty
+// RefType/RefID twice fails with ErrRupiahAlreadyPosted.
+func (s *WalletService) PostTx(tx *gorm.DB, p RupiahPosting) (*model.WalletTransaction, error) {
+	if p.Amount == 0 {
+		return nil, ErrInvalidRupiahAmount
+	}
+	counterAccount := rupiahSystemAccounts[p.EntryType]
+	if p.EntryType == model.RupiahEntryWithdrawalHold || p.EntryType == model.RupiahEntryWithdrawalRelease {
+		counterAccount = holdAccount(p.UserID)
+	}
+	if counterAccount == "" {
+		return nil, fmt.Errorf("jenis entri rupiah tidak dikenal: %s", p.EntryType)
+	}
+	ledger := s.LedgerRepo.WithTx(tx)
+	if p.RefID != "" {
+		if _, err := ledger.FindByRef(userAccount(p.UserID), p.EntryType, p.RefType, p.RefID); err == nil {
+			return nil, ErrRupiahAlreadyPosted
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	wallets := s.WalletRepo.WithTx(tx)
+	ok, err := wallets.IncrementRupiah(p.UserID, p.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if _, err := wallets.GetWalletByUserID(p.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		return nil, ErrInsufficientRupiah
+	}
+	wallet, err := wallets.GetWalletByUserID(p.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	journalID := fmt.Sprintf("%s-%d-%d", p.EntryType, p.UserID, time.Now().UnixNano())
+	entries := []model.WalletTransaction{
+		{
+			JournalID: journalID, Account: userAccount(p.UserID), UserID: &p.UserID, EntryType: p.EntryType,
+			Amount: p.Amount, BalanceAfter: wallet.Rupiah, RefType: p.RefType, RefID: p.RefID, Description: p.Description,
+		},
+		{
+			JournalID: journalID, Account: counterAccount, EntryType: p.EntryType,
+			Amount: -p.Amount, RefType: p.RefType, RefID: p.RefID, Description: p.Description,
+		},
+	}
+	if err := ledger.CreateEntries(entries); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
 }
 
-func (s *WalletService) CreateWallet(wallet *model.Wallet) error {
-	return s.WalletRepo.CreateWallet(wallet)
+// SettleHoldTx pays out a withdrawal hold inside tx: amount goes from the user's
+// hold account to the payout account and fee to the fee account. The user's
+// available balance does not change; it was debited when the hold was placed.
+func (s *WalletService) SettleHoldTx(tx *gorm.DB, userID uint, amount, fee int64, refType, refID, description string) error {
+	if amount <= 0 || fee < 0 {
+		return ErrInvalidRupiahAmount
+	}
+	ledger := s.LedgerRepo.WithTx(tx)
+	if _, err := ledger.FindByRef(holdAccount(userID), model.RupiahEntryWithdrawalPayout, refType, refID); err == nil {
+		return ErrRupiahAlreadyPosted
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	journalID := fmt.Sprintf("%s-%d-%d", model.RupiahEntryWithdrawalPayout, userID, time.Now().UnixNano())
+	line := func(account string, amount int64) model.WalletTransaction {
+		return model.WalletTransaction{
+			JournalID: journalID, Account: account, EntryType: model.RupiahEntryWithdrawalPayout,
+			Amount: amount, RefType: refType, RefID: refID, Description: description,
+		}
+	}
+	entries := []model.WalletTransaction{line(holdAccount(userID), -(amount + fee)), line(rupiahPayoutAccount, amount)}
+	if fee > 0 {
+		entries = append(entries, line(rupiahFeeAccount, fee))
+	}
+	return ledger.CreateEntries(entries)
 }
 
-func (s *WalletService) UpdateWallet(wallet *model.Wallet) error {
-	return s.WalletRepo.UpdateWallet(wallet)
+// History returns a page of the user's rupiah ledger lines, newest first.
+func (s *WalletService) History(userID uint, limit, offset int) ([]model.WalletTransaction, int64, error) {
+	if limit <= 0 {
+		limit = DefaultWalletHistoryLimit
+	}
+	if limit > MaxWalletHistoryLimit {
+		limit = MaxWalletHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.LedgerRepo.ListByUserID(userID, limit, offset)
 }
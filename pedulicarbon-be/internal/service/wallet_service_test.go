@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestWalletService(db *gorm.DB) *WalletService {
+	return NewWalletService(
+		repository.NewWalletRepository(db),
+		repository.NewWalletTransactionRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewUserNFTRepository(db),
+		repository.NewWalletQuarantineRepository(db),
+	)
+}
+
+func TestWalletBalancesComeFromLedgers(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestWalletService(db)
+	users := NewUserService(s.UserRepo, s.WalletRepo)
+
+	alice := &model.User{Name: "Alice", Email: "alice@example.com"}
+	if err := users.RegisterUser(alice, "rahasia-123"); err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := s.GetWallet(alice.ID)
+	if err != nil || wallet.Rupiah != 0 || wallet.Points != 0 || wallet.CarbonNFT != 0 {
+		t.Fatalf("wallet after register = %+v, %v; want an empty wallet", wallet, err)
+	}
+	if _, created, err := s.EnsureWallet(alice.ID); err != nil || created {
+		t.Fatalf("ensure = created %v, %v; want existing wallet", created, err)
+	}
+
+	if _, err := s.Post(RupiahPosting{UserID: alice.ID, Amount: 50000, EntryType: model.RupiahEntryAdjustment, RefType: "admin", RefID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Post(RupiahPosting{UserID: alice.ID, Amount: 50000, EntryType: model.RupiahEntryAdjustment, RefType: "admin", RefID: "1"}); !errors.Is(err, ErrRupiahAlreadyPosted) {
+		t.Fatalf("duplicate posting err = %v, want ErrRupiahAlreadyPosted", err)
+	}
+	if _, err := s.Post(RupiahPosting{UserID: alice.ID, Amount: -50001, EntryType: model.RupiahEntryAdjustment}); !errors.Is(err, ErrInsufficientRupiah) {
+		t.Fatalf("overdraft err = %v, want ErrInsufficientRupiah", err)
+	}
+	entry, err := s.Post(RupiahPosting{UserID: alice.ID, Amount: -20000, EntryType: model.RupiahEntryAdjustment})
+	if err != nil || entry.BalanceAfter != 30000 {
+		t.Fatalf("debit = %+v, %v; want balance 30000", entry, err)
+	}
+
+	points := NewPointService(s.UserRepo, repository.NewPointTransactionRepository(db))
+	if _, err := points.Post(PointPosting{UserID: alice.ID, Amount: 25, EntryType: model.PointEntryMissionReward, RefType: "mission_taken", RefID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: "nft-1", Status: "owned", CarbonAmount: 1.5})
+	mustCreate(t, db, &model.UserNFT{UserID: alice.ID, NFTID: "nft-2", Status: "claimed", CarbonAmount: 4})
+
+	wallet, err = s.GetWallet(alice.ID)
+	if err != nil || wallet.Rupiah != 30000 || wallet.Points != 25 || wallet.CarbonNFT != 1.5 {
+		t.Fatalf("wallet = %+v, %v; want rupiah 30000, points 25, carbon 1.5", wallet, err)
+	}
+	var unbalanced []string
+	db.Model(&model.WalletTransaction{}).Group("journal_id").Having("SUM(amount) <> 0").Pluck("journal_id", &unbalanced)
+	if rows, err := s.LedgerRepo.ListUnexplainedBalances(); err != nil || len(rows) != 0 || len(unbalanced) != 0 {
+		t.Fatalf("unexplained = %+v, unbalanced = %v, %v", rows, unbalanced, err)
+	}
+}
+
+func TestWalletBackfills(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestWalletService(db)
+	// User dan saldo dari sebelum wallet diprovisi saat registrasi
+	legacy := &model.User{Name: "Legacy", Email: "legacy@example.com"}
+	mustCreate(t, db, legacy)
+	if _, err := s.GetWallet(legacy.ID); !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("err = %v, want ErrWalletNotFound", err)
+	}
+	if n, err := s.ProvisionMissingWallets(); err != nil || n != 1 {
+		t.Fatalf("provision = %d, %v; want 1", n, err)
+	}
+	db.Model(&model.Wallet{}).Where("user_id = ?", legacy.ID).Update("rupiah", 12000)
+
+	// Saldo lama ditulis client: dikarantina, bukan dianggap opening balance
+	if err := db.Exec("ALTER TABLE wallets ADD COLUMN legacy_rupiah REAL").Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("UPDATE wallets SET legacy_rupiah = 7500.5 WHERE user_id = ?", legacy.ID)
+	quarantined, err := s.QuarantineUnexplainedBalances()
+	if err != nil || len(quarantined) != 2 {
+		t.Fatalf("quarantine = %+v, %v; want legacy column and unexplained balance", quarantined, err)
+	}
+	if quarantined[0].Source != model.QuarantineLegacyColumn || quarantined[0].Amount != "7500.5" ||
+		quarantined[1].Source != model.QuarantineUnexplained || quarantined[1].Amount != "12000" {
+		t.Fatalf("quarantine = %+v", quarantined)
+	}
+	if wallet, err := s.GetWallet(legacy.ID); err != nil || wallet.Rupiah != 0 {
+		t.Fatalf("wallet = %+v, %v; want rupiah reset to 0", wallet, err)
+	}
+	if again, err := s.QuarantineUnexplainedBalances(); err != nil || len(again) != 0 {
+		t.Fatalf("second quarantine = %+v, %v; want none", again, err)
+	}
+
+	// Dana yang sah dikembalikan admin dengan alasan, sekali saja
+	admin := &model.User{Name: "Admin", Email: "admin@example.com", Role: model.RoleAdmin}
+	mustCreate(t, db, admin)
+	if _, err := s.ResolveQuarantine(admin.ID, quarantined[1].ID, 12000, " "); !errors.Is(err, ErrResolutionReason) {
+		t.Fatalf("err = %v, want ErrResolutionReason", err)
+	}
+	q, err := s.ResolveQuarantine(admin.ID, quarantined[1].ID, 12000, "bukti transfer #881")
+	if err != nil || q.ResolvedAt == nil || q.ResolvedBy == nil || *q.ResolvedBy != admin.ID {
+		t.Fatalf("resolve = %+v, %v", q, err)
+	}
+	if _, err := s.ResolveQuarantine(admin.ID, quarantined[1].ID, 12000, "lagi"); !errors.Is(err, ErrQuarantineResolved) {
+		t.Fatalf("err = %v, want ErrQuarantineResolved", err)
+	}
+	if _, err := s.ResolveQuarantine(admin.ID, quarantined[0].ID, 0, "duplikat saldo lama"); err != nil {
+		t.Fatal(err)
+	}
+	history, total, err := s.History(legacy.ID, 0, 0)
+	if err != nil || total != 1 || history[0].Amount != 12000 || history[0].RefType != "wallet_quarantine" {
+		t.Fatalf("history = %+v (total %d), %v", history, total, err)
+	}
+	if open, _ := s.ListQuarantine(false); len(open) != 0 {
+		t.Fatalf("open quarantine = %+v, want none", open)
+	}
+
+	// NFT yang dicetak sebelum carbon_amount disimpan mengambil nilainya dari mission
+	mission := &model.Mission{Title: "Tanam pohon", AssetAmount: 2.5}
+	mustCreate(t, db, mission)
+	mt := &model.MissionTaken{UserID: legacy.ID, MissionID: mission.ID, Status: model.MissionStatusVerified}
+	mustCreate(t, db, mt)
+	mustCreate(t, db, &model.VerificationSaga{MissionTakenID: mt.ID, UserID: legacy.ID, IdempotencyKey: "k-1", NFTID: "nft-legacy"})
+	mustCreate(t, db, &model.UserNFT{UserID: legacy.ID, NFTID: "nft-legacy", Status: "owned"})
+	if n, err := s.UserNFTRepo.BackfillCarbonAmounts(); err != nil || n != 1 {
+		t.Fatalf("carbon backfill = %d, %v; want 1", n, err)
+	}
+	if wallet, err := s.GetWallet(legacy.ID); err != nil || wallet.CarbonNFT != 2.5 {
+		t.Fatalf("wallet = %+v, %v; want carbon 2.5", wallet, err)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"testing"
+	"time"
+)
+
+func decisionActions(t *testing.T, s *WithdrawService, id uint) []string {
+	t.Helper()
+	wd, err := s.GetWithdraw(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, d := range wd.Decisions {
+		actions = append(actions, d.Action)
+	}
+	return actions
+}
+
+func TestWithdrawMakerCheckerAndReview(t *testing.T) {
+	db := newTestDB(t)
+	s, user := newTestWithdrawService(t, db)
+	s.Policy.DailyCap = 0
+	s.Policy.ApprovalThreshold = 30000
+	s.Policy.VelocityMaxCount, s.Policy.VelocityWindow = 2, time.Hour
+	const maker, checker = 901, 902
+	bank := beneficiaryFor(t, s, user.ID, "bank:BCA:1234567890")
+
+	// Di atas threshold: butuh dua finance admin berbeda, bukan pemilik penarikan
+	big, err := s.CreateWithdraw(user.ID, 30000, bank)
+	if err != nil || big.Status != model.WithdrawStatusPending || big.RequiredApprovals != 2 {
+		t.Fatalf("big withdraw = %+v, %v", big, err)
+	}
+	if _, err := s.UpdateWithdrawStatus(big.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing, ActorID: maker}); !errors.Is(err, ErrWithdrawApprovalRequired) {
+		t.Fatalf("direct processing err = %v, want ErrWithdrawApprovalRequired", err)
+	}
+	if _, err := s.Approve(user.ID, big.ID, ""); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("self approval err = %v, want ErrSelfApproval", err)
+	}
+	if wd, err := s.Approve(maker, big.ID, "dokumen lengkap"); err != nil || wd.Status != model.WithdrawStatusPending {
+		t.Fatalf("first approval = %+v, %v", wd, err)
+	}
+	if _, err := s.Approve(maker, big.ID, ""); !errors.Is(err, ErrDuplicateApprover) {
+		t.Fatalf("same approver twice err = %v, want ErrDuplicateApprover", err)
+	}
+	if wd, err := s.Approve(checker, big.ID, ""); err != nil || wd.Status != model.WithdrawStatusProcessing {
+		t.Fatalf("second approval = %+v, %v", wd, err)
+	}
+	want := []string{model.WithdrawDecisionCreated, model.WithdrawDecisionApproved, model.WithdrawDecisionApproved, model.WithdrawDecisionStatusChanged}
+	if got := decisionActions(t, s, big.ID); len(got) != len(want) || got[1] != want[1] || got[3] != want[3] {
+		t.Fatalf("big decisions = %v, want %v", got, want)
+	}
+
+	// Penarikan kecil tetap bisa langsung diproses
+	small, err := s.CreateWithdraw(user.ID, 10000, bank)
+	if err != nil || small.Status != model.WithdrawStatusPending || small.RequiredApprovals != 0 {
+		t.Fatalf("small withdraw = %+v, %v", small, err)
+	}
+
+	// Penarikan ketiga dalam satu jam masuk review
+	third, err := s.CreateWithdraw(user.ID, 10000, bank)
+	if err != nil || third.Status != model.WithdrawStatusReview || third.RequiredApprovals != 1 {
+		t.Fatalf("third withdraw = %+v, %v", third, err)
+	}
+	// Antrian review untuk finance admin
+	if queue, total, err := s.ListWithdraws(model.WithdrawStatusReview, 0, 0); err != nil || total != 1 || queue[0].ID != third.ID {
+		t.Fatalf("review queue = %+v (total %d), %v", queue, total, err)
+	}
+	if queue, total, err := s.ListWithdraws(model.WithdrawStatusPending, 1, 0); err != nil || total != 1 || len(queue) != 1 || queue[0].ID != small.ID {
+		t.Fatalf("pending queue = %+v (total %d), %v", queue, total, err)
+	}
+	if _, _, err := s.ListWithdraws("all", 0, 0); !errors.Is(err, ErrInvalidWithdraw) {
+		t.Fatalf("unknown status err = %v, want ErrInvalidWithdraw", err)
+	}
+	if _, err := s.UpdateWithdrawStatus(third.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing, ActorID: maker}); !errors.Is(err, ErrWithdrawApprovalRequired) {
+		t.Fatalf("review -> processing err = %v, want ErrWithdrawApprovalRequired", err)
+	}
+	if _, err := s.Reject(maker, third.ID, ""); !errors.Is(err, ErrFailureReasonRequired) {
+		t.Fatalf("reject without reason err = %v, want ErrFailureReasonRequired", err)
+	}
+	if wd, err := s.Reject(maker, third.ID, "pola penarikan tidak wajar"); err != nil || wd.Status != model.WithdrawStatusFailed {
+		t.Fatalf("reject = %+v, %v", wd, err)
+	}
+	if _, err := s.Reject(maker, big.ID, "terlambat"); !errors.Is(err, ErrInvalidWithdrawTransition) {
+		t.Fatalf("reject processing err = %v, want ErrInvalidWithdrawTransition", err)
+	}
+	if got := decisionActions(t, s, third.ID); len(got) != 3 || got[1] != model.WithdrawDecisionFlagged || got[2] != model.WithdrawDecisionRejected {
+		t.Fatalf("third decisions = %v", got)
+	}
+
+	// Batas kumulatif: satu persetujuan cukup untuk melepas review
+	s.Policy.VelocityMaxCount = 0
+	s.Policy.CumulativeLimit, s.Policy.CumulativeWindow = 45000, 24*time.Hour
+	flagged, err := s.CreateWithdraw(user.ID, 10000, bank)
+	if err != nil || flagged.Status != model.WithdrawStatusReview {
+		t.Fatalf("cumulative withdraw = %+v, %v", flagged, err)
+	}
+	if wd, err := s.Approve(checker, flagged.ID, ""); err != nil || wd.Status != model.WithdrawStatusProcessing {
+		t.Fatalf("approve review = %+v, %v", wd, err)
+	}
+
+	wallet, _ := s.Wallets.GetWallet(user.ID)
+	if wallet.Rupiah != 100000-35000-15000-15000 || wallet.HeldRupiah != 65000 {
+		t.Fatalf("wallet = %+v; want rupiah 35000, held 65000", wallet)
+	}
+}
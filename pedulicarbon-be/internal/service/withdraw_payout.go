@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/payout"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownPayoutProvider = errors.New("payout provider tidak dikenal")
+	ErrPayoutStatusConflict  = errors.New("status dari payout provider bertentangan dengan status penarikan")
+)
+
+// MaxPayoutPollBatch membatasi jumlah penarikan processing yang dicek per putaran poller.
+const MaxPayoutPollBatch = 100
+
+type disbursePayload struct {
+	WithdrawID uint `json:"withdraw_id"`
+}
+
+// PayoutPollReport adalah hasil satu kali PollPayouts.
+type PayoutPollReport struct {
+	Checked   int      `json:"checked"`
+	Requeued  int      `json:"requeued"`
+	Completed int      `json:"completed"`
+	Failed    int      `json:"failed"`
+	StillOpen int      `json:"still_open"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func disburseDedupKey(withdrawID uint) string {
+	return "disburse-withdraw-" + strconv.FormatUint(uint64(withdrawID), 10)
+}
+
+// externalID adalah idempotency key disbursement di provider.
+func externalID(withdrawID uint) string {
+	return "withdraw-" + strconv.FormatUint(uint64(withdrawID), 10)
+}
+
+// enqueueDisburse mengantrikan pencairan. Bila gagal, PollPayouts akan
+// mengantrikan ulang penarikan processing yang belum punya provider_ref.
+func (s *WithdrawService) enqueueDisburse(withdrawID uint) {
+	if _, err := s.Jobs.Enqueue(model.JobTypeDisburse, disburseDedupKey(withdrawID), disbursePayload{WithdrawID: withdrawID}, 0); err != nil {
+		fmt.Printf("[ERROR] Enqueue disbursement withdraw %d error: %v\n", withdrawID, err)
+	}
+}
+
+func (s *WithdrawService) runDisburseJob(ctx context.Context, payload []byte) (interface{}, error) {
+	var p disbursePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, Permanent(err)
+	}
+	wd, err := s.disburse(ctx, p.WithdrawID)
+	if err != nil {
+		if errors.Is(err, ErrWithdrawNotFound) || errors.Is(err, ErrInvalidWithdrawTransition) || payout.IsPermanent(err) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+	return map[string]interface{}{"withdraw_id": wd.ID, "provider_ref": wd.ProviderRef, "status": wd.Status}, nil
+}
+
+// onDisburseJobDead menggagalkan penarikan yang ditolak secara permanen. Job yang
+// mati karena error sementara dibiarkan processing: disbursement mungkin sudah
+// dibuat, jadi PollPayouts yang mengantrikannya lagi dengan external_id yang sama.
+func (s *WithdrawService) onDisburseJobDead(payload []byte, cause error) {
+	var p disbursePayload
+	var perm *PermanentError
+	if err := json.Unmarshal(payload, &p); err != nil || !errors.As(cause, &perm) ||
+		errors.Is(cause, ErrWithdrawNotFound) || errors.Is(cause, ErrInvalidWithdrawTransition) {
+		return
+	}
+	if _, err := s.finishFromProvider(p.WithdrawID, model.WithdrawStatusFailed, "ditolak payout provider: "+cause.Error()); err != nil {
+		fmt.Printf("[ERROR] Fail withdraw %d after rejected disbursement: %v\n", p.WithdrawID, err)
+	}
+}
+
+// disburse membuat disbursement di provider untuk penarikan processing dan
+// mencatat provider_ref-nya. Aman diulang: external_id selalu sama.
+func (s *WithdrawService) disburse(ctx context.Context, withdrawID uint) (*model.Withdraw, error) {
+	wd, err := s.WithdrawRepo.GetByID(withdrawID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWithdrawNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if wd.ProviderRef != "" {
+		return wd, nil
+	}
+	if wd.Status != model.WithdrawStatusProcessing {
+		return nil, ErrInvalidWithdrawTransition
+	}
+	d, err := s.disbursementFor(wd)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	res, err := s.Provider.CreateDisbursement(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(&wd.ID, model.PayoutEventCreate, res)
+	if _, err := s.WithdrawRepo.SetProviderRef(wd.ID, s.Provider.Name(), res.ProviderRef); err != nil {
+		return nil, err
+	}
+	wd.Provider, wd.ProviderRef = s.Provider.Name(), res.ProviderRef
+	if res.Status != payout.StatusPending {
+		return s.applyResult(wd.ID, res)
+	}
+	return wd, nil
+}
+
+func (s *WithdrawService) disbursementFor(wd *model.Withdraw) (payout.Disbursement, error) {
+	holder := wd.HolderName
+	if holder == "" {
+		// Penarikan sebelum ada rekening tujuan tidak menyimpan nama pemilik
+		user, err := s.Wallets.UserRepo.GetUserByID(wd.UserID)
+		if err != nil {
+			return payout.Disbursement{}, err
+		}
+		holder = user.Name
+	}
+	parts := strings.Split(wd.Target, ":")
+	d := payout.Disbursement{
+		ExternalID:        externalID(wd.ID),
+		Amount:            wd.Amount,
+		AccountHolderName: holder,
+		Description:       "PeduliCarbon penarikan #" + strconv.FormatUint(uint64(wd.ID), 10),
+	}
+	switch {
+	case wd.Channel == model.WithdrawChannelBank && len(parts) == 3:
+		d.ChannelCode, d.AccountNumber = parts[1], parts[2]
+	case wd.Channel == model.WithdrawChannelEWallet && len(parts) == 2:
+		d.ChannelCode, d.AccountNumber = strings.ToUpper(parts[0]), parts[1]
+	default:
+		return d, fmt.Errorf("%w: %s", ErrInvalidWithdrawTarget, wd.Target)
+	}
+	return d, nil
+}
+
+// HandleCallback memproses webhook provider: signature diverifikasi, payload
+// disimpan apa adanya, lalu status penarikan dipindahkan. Callback yang sama boleh
+// datang berulang kali.
+func (s *WithdrawService) HandleCallback(providerName string, header http.Header, body []byte) (*model.Withdraw, error) {
+	if s.Provider == nil || providerName != s.Provider.Name() {
+		return nil, ErrUnknownPayoutProvider
+	}
+	res, err := s.Provider.ParseCallback(header, body)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := s.WithdrawRepo.GetByProviderRef(s.Provider.Name(), res.ProviderRef)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Callback bisa tiba sebelum provider_ref tersimpan; cocokkan lewat external_id
+		wd, err = s.withdrawByExternalID(res.ExternalID)
+	}
+	if err != nil {
+		s.recordEvent(nil, model.PayoutEventCallback, res)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWithdrawNotFound
+		}
+		return nil, err
+	}
+	s.recordEvent(&wd.ID, model.PayoutEventCallback, res)
+	if wd.ProviderRef == "" {
+		if _, err := s.WithdrawRepo.SetProviderRef(wd.ID, s.Provider.Name(), res.ProviderRef); err != nil {
+			return nil, err
+		}
+	}
+	if res.Status == payout.StatusPending {
+		return wd, nil
+	}
+	return s.applyResult(wd.ID, res)
+}
+
+func (s *WithdrawService) withdrawByExternalID(extID string) (*model.Withdraw, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(extID, "withdraw-"), 10, 64)
+	if err != nil || !strings.HasPrefix(extID, "withdraw-") {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.WithdrawRepo.GetByID(uint(id))
+}
+
+// PollPayouts menanyakan status disbursement penarikan processing ke provider,
+// untuk callback yang hilang, dan mengantrikan ulang yang belum punya provider_ref.
+func (s *WithdrawService) PollPayouts(ctx context.Context) (*PayoutPollReport, error) {
+	report := &PayoutPollReport{}
+	if s.Provider == nil {
+		return report, nil
+	}
+	withdraws, _, err := s.WithdrawRepo.ListByStatus(model.WithdrawStatusProcessing, MaxPayoutPollBatch, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, wd := range withdraws {
+		report.Checked++
+		if wd.ProviderRef == "" {
+			s.enqueueDisburse(wd.ID)
+			report.Requeued++
+			continue
+		}
+		res, err := s.Provider.GetDisbursement(ctx, wd.ProviderRef)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("withdraw %d: %v", wd.ID, err))
+			continue
+		}
+		if res.Status == payout.StatusPending {
+			report.StillOpen++
+			continue
+		}
+		s.recordEvent(&wd.ID, model.PayoutEventQuery, res)
+		if _, err := s.applyResult(wd.ID, res); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("withdraw %d: %v", wd.ID, err))
+			continue
+		}
+		if res.Status == payout.StatusCompleted {
+			report.Completed++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// applyResult memindahkan penarikan sesuai status final dari provider.
+func (s *WithdrawService) applyResult(withdrawID uint, res *payout.Result) (*model.Withdraw, error) {
+	if res.Status == payout.StatusCompleted {
+		return s.finishFromProvider(withdrawID, model.WithdrawStatusSuccess, "")
+	}
+	reason := "payout gagal"
+	if res.FailureCode != "" {
+		reason += ": " + res.FailureCode
+	}
+	return s.finishFromProvider(withdrawID, model.WithdrawStatusFailed, reason)
+}
+
+// finishFromProvider menjalankan transisi akhir secara idempoten: penarikan yang
+// sudah berada di status tujuan dikembalikan apa adanya.
+func (s *WithdrawService) finishFromProvider(withdrawID uint, status, reason string) (*model.Withdraw, error) {
+	wd, err := s.transition(withdrawID, WithdrawStatusUpdate{Status: status, Reason: reason})
+	if !errors.Is(err, ErrInvalidWithdrawTransition) {
+		return wd, err
+	}
+	current, err := s.WithdrawRepo.GetByID(withdrawID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == status {
+		return current, nil
+	}
+	return nil, fmt.Errorf("%w: penarikan %d berstatus %s, provider melaporkan %s", ErrPayoutStatusConflict, withdrawID, current.Status, status)
+}
+
+// recordEvent menyimpan respons atau callback provider. Gagal menyimpan hanya
+// di-log supaya tidak menghalangi perubahan status.
+func (s *WithdrawService) recordEvent(withdrawID *uint, kind string, res *payout.Result) {
+	err := s.EventRepo.Create(&model.PayoutEvent{
+		WithdrawID:  withdrawID,
+		Provider:    s.Provider.Name(),
+		Kind:        kind,
+		ProviderRef: res.ProviderRef,
+		Status:      res.Status,
+		Payload:     string(res.Raw),
+	})
+	if err != nil {
+		fmt.Printf("[ERROR] Record payout %s event %s error: %v\n", kind, res.ProviderRef, err)
+	}
+}
+
+// ListPayoutEvents returns the stored provider responses and callbacks of a withdrawal.
+func (s *WithdrawService) ListPayoutEvents(withdrawID uint) ([]model.PayoutEvent, error) {
+	return s.EventRepo.ListByWithdrawID(withdrawID)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/payout"
+	"pedulicarbon/internal/repository"
+	"strconv"
+	"testing"
+)
+
+func TestWithdrawPayoutFlow(t *testing.T) {
+	db := newTestDB(t)
+	manual, user := newTestWithdrawService(t, db)
+	fake := payout.NewFakeProvider("webhook-secret")
+	jobs := NewJobService(repository.NewJobRepository(db))
+	s := NewWithdrawService(manual.WithdrawRepo, manual.EventRepo, manual.DecisionRepo, manual.Wallets, manual.Beneficiaries, fake, jobs, manual.Policy)
+	ctx := context.Background()
+
+	wd, err := s.CreateWithdraw(user.ID, 20000, beneficiaryFor(t, s, user.ID, "bank:BCA:1234567890"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateWithdrawStatus(wd.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing}); err != nil {
+		t.Fatal(err)
+	}
+	var job model.Job
+	if err := db.Where("dedup_key = ?", disburseDedupKey(wd.ID)).First(&job).Error; err != nil {
+		t.Fatalf("disbursement job not enqueued: %v", err)
+	}
+	if _, err := s.runDisburseJob(ctx, []byte(job.Payload)); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ = s.WithdrawRepo.GetByID(wd.ID)
+	if wd.Provider != "fake" || wd.ProviderRef == "" || wd.Status != model.WithdrawStatusProcessing {
+		t.Fatalf("after disburse = %+v", wd)
+	}
+	if _, err := s.UpdateWithdrawStatus(wd.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusFailed, Reason: "manual"}); !errors.Is(err, ErrPayoutProviderManaged) {
+		t.Fatalf("manual fail after disburse err = %v, want ErrPayoutProviderManaged", err)
+	}
+
+	// Callback dengan signature salah ditolak, callback sah boleh datang berulang
+	body, sig, err := fake.Settle(wd.ProviderRef, payout.StatusCompleted, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.HandleCallback("fake", http.Header{payout.FakeSignatureHeader: {"00" + sig[2:]}}, body); !errors.Is(err, payout.ErrInvalidSignature) {
+		t.Fatalf("bad signature err = %v, want ErrInvalidSignature", err)
+	}
+	if _, err := s.HandleCallback("xendit", http.Header{}, body); !errors.Is(err, ErrUnknownPayoutProvider) {
+		t.Fatalf("other provider err = %v, want ErrUnknownPayoutProvider", err)
+	}
+	header := http.Header{payout.FakeSignatureHeader: {sig}}
+	for i := 0; i < 2; i++ {
+		got, err := s.HandleCallback("fake", header, body)
+		if err != nil || got.Status != model.WithdrawStatusSuccess {
+			t.Fatalf("callback %d = %+v, %v", i, got, err)
+		}
+	}
+	failedBody, failedSig, _ := fake.Settle(wd.ProviderRef, payout.StatusFailed, "INVALID_DESTINATION")
+	if _, err := s.HandleCallback("fake", http.Header{payout.FakeSignatureHeader: {failedSig}}, failedBody); !errors.Is(err, ErrPayoutStatusConflict) {
+		t.Fatalf("conflicting callback err = %v, want ErrPayoutStatusConflict", err)
+	}
+	if events, err := s.ListPayoutEvents(wd.ID); err != nil || len(events) != 4 {
+		t.Fatalf("payout events = %d, %v; want create + 3 callbacks", len(events), err)
+	}
+
+	// Penolakan permanen menggagalkan penarikan dan mengembalikan hold
+	rejected, err := s.CreateWithdraw(user.ID, 10000, beneficiaryFor(t, s, user.ID, "gopay:081234567890"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UpdateWithdrawStatus(rejected.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing})
+	fake.FailNext(&payout.ProviderError{Provider: "fake", Op: "create_disbursement", Code: "INVALID_DESTINATION", Err: errors.New("HTTP 400")})
+	payload := []byte(`{"withdraw_id":` + strconv.FormatUint(uint64(rejected.ID), 10) + `}`)
+	_, runErr := s.runDisburseJob(ctx, payload)
+	var perm *PermanentError
+	if !errors.As(runErr, &perm) {
+		t.Fatalf("rejected disbursement err = %v, want permanent", runErr)
+	}
+	s.onDisburseJobDead(payload, runErr)
+	if got, _ := s.WithdrawRepo.GetByID(rejected.ID); got.Status != model.WithdrawStatusFailed || got.FailureReason == "" {
+		t.Fatalf("rejected withdraw = %+v", got)
+	}
+
+	// Error sementara dibiarkan processing; poller mengantrikan ulang lalu menyelesaikannya
+	retried, _ := s.CreateWithdraw(user.ID, 10000, beneficiaryFor(t, s, user.ID, "ovo:081234567890"))
+	s.UpdateWithdrawStatus(retried.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing})
+	fake.FailNext(&payout.ProviderError{Provider: "fake", Op: "create_disbursement", Retryable: true, Err: errors.New("HTTP 503")})
+	payload = []byte(`{"withdraw_id":` + strconv.FormatUint(uint64(retried.ID), 10) + `}`)
+	_, runErr = s.runDisburseJob(ctx, payload)
+	s.onDisburseJobDead(payload, runErr)
+	if got, _ := s.WithdrawRepo.GetByID(retried.ID); got.Status != model.WithdrawStatusProcessing {
+		t.Fatalf("transient failure withdraw = %+v, want processing", got)
+	}
+	// Belum ada ProviderRef, tapi disbursement mungkin sudah dibuat: gagal manual ditolak
+	if _, err := s.UpdateWithdrawStatus(retried.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusFailed, Reason: "manual"}); !errors.Is(err, ErrPayoutProviderManaged) {
+		t.Fatalf("manual fail while processing err = %v, want ErrPayoutProviderManaged", err)
+	}
+	if wallet, _ := s.Wallets.GetWallet(user.ID); wallet.HeldRupiah != 11100 {
+		t.Fatalf("wallet = %+v; want retried withdrawal still held", wallet)
+	}
+	if report, err := s.PollPayouts(ctx); err != nil || report.Requeued != 1 {
+		t.Fatalf("poll = %+v, %v; want 1 requeued", report, err)
+	}
+	if _, err := s.runDisburseJob(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	fake.AutoComplete = true
+	if report, err := s.PollPayouts(ctx); err != nil || report.Completed != 1 {
+		t.Fatalf("poll = %+v, %v; want 1 completed", report, err)
+	}
+
+	wallet, _ := s.Wallets.GetWallet(user.ID)
+	if wallet.HeldRupiah != 0 || wallet.Rupiah != 100000-25000-11100 {
+		t.Fatalf("wallet = %+v; want held 0, rupiah 63900", wallet)
+	}
+}
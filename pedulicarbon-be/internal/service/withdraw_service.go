@@ -1,26 +1,500 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/payout"
 	"pedulicarbon/internal/repository"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidWithdraw           = errors.New("permintaan penarikan tidak valid")
+	ErrInvalidWithdrawTarget     = errors.New("format target penarikan tidak dikenal")
+	ErrWithdrawDailyCap          = errors.New("batas penarikan harian terlampaui")
+	ErrWithdrawNotFound          = errors.New("penarikan tidak ditemukan")
+	ErrInvalidWithdrawTransition = errors.New("perubahan status penarikan tidak diizinkan")
+	ErrFailureReasonRequired     = errors.New("alasan gagal wajib diisi")
+	ErrPayoutProviderManaged     = errors.New("status penarikan ini mengikuti payout provider")
+	ErrWithdrawApprovalRequired  = errors.New("penarikan ini harus disetujui finance admin")
+	ErrSelfApproval              = errors.New("tidak boleh memutuskan penarikan milik sendiri")
+	ErrDuplicateApprover         = errors.New("persetujuan berikutnya harus dari finance admin lain")
 )
 
+var (
+	// bank:<kode bank>:<nomor rekening>, mis. bank:BCA:1234567890
+	bankTargetPattern = regexp.MustCompile(`^bank:([A-Z0-9]{3,10}):([0-9]{6,20})$`)
+	// <ewallet>:<nomor HP>, mis. gopay:081234567890
+	ewalletTargetPattern = regexp.MustCompile(`^(gopay|ovo|dana|shopeepay):((\+62|62|0)8[0-9]{7,12})$`)
+)
+
+// withdrawTransitions lists the allowed status changes of a Withdraw.
+var withdrawTransitions = map[string][]string{
+	model.WithdrawStatusPending:    {model.WithdrawStatusProcessing, model.WithdrawStatusFailed},
+	model.WithdrawStatusReview:     {model.WithdrawStatusProcessing, model.WithdrawStatusFailed},
+	model.WithdrawStatusProcessing: {model.WithdrawStatusSuccess, model.WithdrawStatusFailed},
+}
+
+func canTransitionWithdraw(from, to string) bool {
+	for _, s := range withdrawTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// WithdrawFee adalah biaya satu channel: Flat rupiah ditambah BasisPoints/10000 dari Amount.
+type WithdrawFee struct {
+	Flat        int64
+	BasisPoints int64
+}
+
+func (f WithdrawFee) For(amount int64) int64 {
+	return f.Flat + amount*f.BasisPoints/10000
+}
+
+// WithdrawPolicy: minimal per penarikan, total maksimal per user per hari
+// (0 = tanpa batas), biaya per channel dan apakah rekening tujuan harus sudah
+// diverifikasi.
+//
+// Penarikan >= ApprovalThreshold butuh persetujuan dua finance admin berbeda.
+// Penarikan yang membuat jumlah penarikan user dalam VelocityWindow melebihi
+// VelocityMaxCount, atau totalnya dalam CumulativeWindow melebihi
+// CumulativeLimit, masuk review dan butuh minimal satu persetujuan. Nilai 0
+// mematikan aturan yang bersangkutan.
+type WithdrawPolicy struct {
+	MinAmount                  int64
+	DailyCap                   int64
+	Fees                       map[string]WithdrawFee
+	RequireVerifiedBeneficiary bool
+	ApprovalThreshold          int64
+	VelocityMaxCount           int
+	VelocityWindow             time.Duration
+	CumulativeLimit            int64
+	CumulativeWindow           time.Duration
+}
+
+// requiredApprovals menentukan jumlah persetujuan untuk penarikan baru.
+func (p WithdrawPolicy) requiredApprovals(amount int64, flagged bool) int {
+	if p.ApprovalThreshold > 0 && amount >= p.ApprovalThreshold {
+		return 2
+	}
+	if flagged {
+		return 1
+	}
+	return 0
+}
+
+// WithdrawStatusUpdate is an operator's change to a withdrawal. Reason is required
+// for failed. ActorID is the admin making the change, 0 for the system.
+type WithdrawStatusUpdate struct {
+	Status  string
+	Reason  string
+	ActorID uint
+}
+
 type WithdrawService struct {
-	WithdrawRepo *repository.WithdrawRepository
+	WithdrawRepo  *repository.WithdrawRepository
+	EventRepo     *repository.PayoutEventRepository
+	DecisionRepo  *repository.WithdrawDecisionRepository
+	Wallets       *WalletService
+	Beneficiaries *BeneficiaryService
+	Provider      payout.Provider // nil: penarikan dicairkan manual oleh admin
+	Jobs          *JobService
+	Policy        WithdrawPolicy
+}
+
+func NewWithdrawService(repo *repository.WithdrawRepository, eventRepo *repository.PayoutEventRepository, decisionRepo *repository.WithdrawDecisionRepository, wallets *WalletService, beneficiaries *BeneficiaryService, provider payout.Provider, jobs *JobService, policy WithdrawPolicy) *WithdrawService {
+	s := &WithdrawService{WithdrawRepo: repo, EventRepo: eventRepo, DecisionRepo: decisionRepo, Wallets: wallets, Beneficiaries: beneficiaries, Provider: provider, Jobs: jobs, Policy: policy}
+	if provider != nil {
+		jobs.Register(model.JobTypeDisburse, JobHandler{Run: s.runDisburseJob, OnDead: s.onDisburseJobDead})
+	}
+	return s
 }
 
-func NewWithdrawService(repo *repository.WithdrawRepository) *WithdrawService {
-	return &WithdrawService{WithdrawRepo: repo}
+// ParseWithdrawTarget menormalkan target penarikan dan mengembalikan channel-nya.
+func ParseWithdrawTarget(target string) (channel, normalized string, err error) {
+	target = strings.TrimSpace(target)
+	kind, rest, _ := strings.Cut(target, ":")
+	kind = strings.ToLower(kind)
+	if kind == model.WithdrawChannelBank {
+		bankCode, account, _ := strings.Cut(rest, ":")
+		normalized = kind + ":" + strings.ToUpper(bankCode) + ":" + account
+		if bankTargetPattern.MatchString(normalized) {
+			return model.WithdrawChannelBank, normalized, nil
+		}
+		return "", "", fmt.Errorf("%w: gunakan bank:<kode bank>:<nomor rekening>", ErrInvalidWithdrawTarget)
+	}
+	normalized = kind + ":" + rest
+	if ewalletTargetPattern.MatchString(normalized) {
+		return model.WithdrawChannelEWallet, normalized, nil
+	}
+	return "", "", fmt.Errorf("%w: gunakan bank:<kode bank>:<nomor rekening> atau gopay/ovo/dana/shopeepay:<nomor HP>", ErrInvalidWithdrawTarget)
 }
 
-func (s *WithdrawService) CreateWithdraw(wd *model.Withdraw) error {
-	return s.WithdrawRepo.CreateWithdraw(wd)
+// CreateWithdraw memvalidasi permintaan lalu menahan Amount + Fee dari saldo
+// wallet. Tujuan diambil dari rekening tujuan user yang sudah lewat masa
+// cool-down. Penarikan yang melanggar batas velocity/kumulatif masuk review.
+// Baris wallet dikunci selama transaksi supaya batas harian dan batas review
+// tidak bisa dilewati dengan permintaan paralel.
+func (s *WithdrawService) CreateWithdraw(userID uint, amount int64, beneficiaryID uint) (*model.Withdraw, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount harus lebih dari 0", ErrInvalidWithdraw)
+	}
+	if amount < s.Policy.MinAmount {
+		return nil, fmt.Errorf("%w: minimal penarikan Rp%d", ErrInvalidWithdraw, s.Policy.MinAmount)
+	}
+	b, err := s.Beneficiaries.ForWithdraw(userID, beneficiaryID, s.Policy.RequireVerifiedBeneficiary)
+	if err != nil {
+		return nil, err
+	}
+	wd := &model.Withdraw{
+		UserID:        userID,
+		Amount:        amount,
+		Fee:           s.Policy.Fees[b.Channel].For(amount),
+		Status:        model.WithdrawStatusPending,
+		Channel:       b.Channel,
+		BeneficiaryID: &b.ID,
+		Target:        b.Target(),
+		HolderName:    b.HolderName,
+	}
+	err = s.WithdrawRepo.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.Wallets.WalletRepo.WithTx(tx).LockByUserID(userID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWalletNotFound
+		} else if err != nil {
+			return err
+		}
+		withdraws := s.WithdrawRepo.WithTx(tx)
+		if s.Policy.DailyCap > 0 {
+			y, m, d := time.Now().Date()
+			today, err := withdraws.SumAmountSince(userID, time.Date(y, m, d, 0, 0, 0, 0, time.Local))
+			if err != nil {
+				return err
+			}
+			if today+amount > s.Policy.DailyCap {
+				return fmt.Errorf("%w: sisa hari ini Rp%d", ErrWithdrawDailyCap, max(s.Policy.DailyCap-today, 0))
+			}
+		}
+		flags, err := s.reviewFlags(withdraws, userID, amount)
+		if err != nil {
+			return err
+		}
+		if len(flags) > 0 {
+			wd.Status = model.WithdrawStatusReview
+		}
+		wd.RequiredApprovals = s.Policy.requiredApprovals(amount, len(flags) > 0)
+		if err := withdraws.CreateWithdraw(wd); err != nil {
+			return err
+		}
+		decisions := s.DecisionRepo.WithTx(tx)
+		if err := decisions.Create(newDecision(wd.ID, userID, model.WithdrawDecisionCreated, "", model.WithdrawStatusPending, "")); err != nil {
+			return err
+		}
+		if len(flags) > 0 {
+			if err := decisions.Create(newDecision(wd.ID, 0, model.WithdrawDecisionFlagged, model.WithdrawStatusPending, wd.Status, strings.Join(flags, "; "))); err != nil {
+				return err
+			}
+		}
+		_, err = s.Wallets.PostTx(tx, RupiahPosting{
+			UserID:      userID,
+			Amount:      -(wd.Amount + wd.Fee),
+			EntryType:   model.RupiahEntryWithdrawalHold,
+			RefType:     "withdraw",
+			RefID:       strconv.FormatUint(uint64(wd.ID), 10),
+			Description: "penarikan ke " + wd.Target,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wd, nil
 }
 
 func (s *WithdrawService) GetUserWithdraws(userID uint) ([]model.Withdraw, error) {
 	return s.WithdrawRepo.GetUserWithdraws(userID)
 }
 
-func (s *WithdrawService) UpdateWithdrawStatus(id uint, status string) error {
-	return s.WithdrawRepo.UpdateWithdrawStatus(id, status)
+// Batas halaman GET /wallets/withdraw.
+const (
+	DefaultWithdrawQueueLimit = 50
+	MaxWithdrawQueueLimit     = 200
+)
+
+// ListWithdraws mengembalikan antrian penarikan satu status untuk finance admin,
+// terlama dulu, beserta jumlah totalnya.
+func (s *WithdrawService) ListWithdraws(status string, limit, offset int) ([]model.Withdraw, int64, error) {
+	switch status {
+	case model.WithdrawStatusPending, model.WithdrawStatusReview, model.WithdrawStatusProcessing,
+		model.WithdrawStatusSuccess, model.WithdrawStatusFailed:
+	default:
+		return nil, 0, fmt.Errorf("%w: status %q tidak dikenal", ErrInvalidWithdraw, status)
+	}
+	if limit <= 0 {
+		limit = DefaultWithdrawQueueLimit
+	}
+	if limit > MaxWithdrawQueueLimit {
+		limit = MaxWithdrawQueueLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.WithdrawRepo.ListByStatus(status, limit, offset)
+}
+
+// GetWithdraw returns the withdrawal with its audit trail.
+func (s *WithdrawService) GetWithdraw(id uint) (*model.Withdraw, error) {
+	wd, err := s.WithdrawRepo.GetWithDecisions(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWithdrawNotFound
+	}
+	return wd, err
+}
+
+// reviewFlags mengembalikan alasan penarikan baru perlu direview, kosong bila tidak.
+func (s *WithdrawService) reviewFlags(withdraws *repository.WithdrawRepository, userID uint, amount int64) ([]string, error) {
+	var flags []string
+	now := time.Now()
+	if s.Policy.VelocityMaxCount > 0 && s.Policy.VelocityWindow > 0 {
+		n, err := withdraws.CountSince(userID, now.Add(-s.Policy.VelocityWindow))
+		if err != nil {
+			return nil, err
+		}
+		if n+1 > int64(s.Policy.VelocityMaxCount) {
+			flags = append(flags, fmt.Sprintf("velocity: penarikan ke-%d dalam %s, batas %d", n+1, s.Policy.VelocityWindow, s.Policy.VelocityMaxCount))
+		}
+	}
+	if s.Policy.CumulativeLimit > 0 && s.Policy.CumulativeWindow > 0 {
+		sum, err := withdraws.SumAmountSince(userID, now.Add(-s.Policy.CumulativeWindow))
+		if err != nil {
+			return nil, err
+		}
+		if sum+amount > s.Policy.CumulativeLimit {
+			flags = append(flags, fmt.Sprintf("kumulatif: Rp%d dalam %s, batas Rp%d", sum+amount, s.Policy.CumulativeWindow, s.Policy.CumulativeLimit))
+		}
+	}
+	return flags, nil
+}
+
+func newDecision(withdrawID, actorID uint, action, from, to, note string) *model.WithdrawDecision {
+	d := &model.WithdrawDecision{WithdrawID: withdrawID, Action: action, FromStatus: from, ToStatus: to, Note: note}
+	if actorID != 0 {
+		d.ActorID = &actorID
+	}
+	return d
+}
+
+// UpdateWithdrawStatus memindahkan penarikan ke status berikutnya atas perintah
+// admin. Penarikan yang butuh persetujuan hanya bisa ke processing lewat
+// Approve. Bila payout provider aktif, processing mengantrikan disbursement dan
+// hasil akhirnya datang dari provider: admin hanya boleh menggagalkan penarikan
+// yang belum processing. Selama processing disbursement bisa saja sudah dibuat
+// meski ProviderRef belum tercatat, sehingga gagal manual bisa membuat user
+// menerima refund sekaligus payout.
+func (s *WithdrawService) UpdateWithdrawStatus(id uint, u WithdrawStatusUpdate) (*model.Withdraw, error) {
+	wd, err := s.WithdrawRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWithdrawNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.ActorID != 0 && u.ActorID == wd.UserID {
+		return nil, ErrSelfApproval
+	}
+	if u.Status == model.WithdrawStatusProcessing && wd.RequiredApprovals > 0 {
+		return nil, ErrWithdrawApprovalRequired
+	}
+	if s.Provider != nil && u.Status != model.WithdrawStatusProcessing &&
+		(u.Status == model.WithdrawStatusSuccess || wd.Status == model.WithdrawStatusProcessing || wd.ProviderRef != "") {
+		return nil, ErrPayoutProviderManaged
+	}
+	wd, err = s.transition(id, u)
+	if err != nil {
+		return nil, err
+	}
+	if s.Provider != nil && wd.Status == model.WithdrawStatusProcessing {
+		s.enqueueDisburse(wd.ID)
+	}
+	return wd, nil
+}
+
+// Approve mencatat persetujuan satu finance admin atas penarikan pending atau
+// review. Pemilik penarikan tidak boleh menyetujui, dan setiap persetujuan harus
+// dari admin yang berbeda. Begitu jumlah persetujuan cukup, penarikan langsung
+// processing.
+func (s *WithdrawService) Approve(actorID, id uint, note string) (*model.Withdraw, error) {
+	var wd *model.Withdraw
+	err := s.WithdrawRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		wd, err = s.WithdrawRepo.WithTx(tx).LockByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWithdrawNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if wd.Status != model.WithdrawStatusPending && wd.Status != model.WithdrawStatusReview {
+			return ErrInvalidWithdrawTransition
+		}
+		if actorID == wd.UserID {
+			return ErrSelfApproval
+		}
+		decisions := s.DecisionRepo.WithTx(tx)
+		trail, err := decisions.ListByWithdrawID(id)
+		if err != nil {
+			return err
+		}
+		approvals := 1
+		for _, d := range trail {
+			if d.Action != model.WithdrawDecisionApproved {
+				continue
+			}
+			if d.ActorID != nil && *d.ActorID == actorID {
+				return ErrDuplicateApprover
+			}
+			approvals++
+		}
+		required := max(wd.RequiredApprovals, 1)
+		note = strings.TrimSpace(fmt.Sprintf("persetujuan %d/%d %s", approvals, required, note))
+		if err := decisions.Create(newDecision(id, actorID, model.WithdrawDecisionApproved, wd.Status, wd.Status, note)); err != nil {
+			return err
+		}
+		if approvals < required {
+			return nil
+		}
+		wd, err = s.transitionTx(tx, id, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing, ActorID: actorID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.Provider != nil && wd.Status == model.WithdrawStatusProcessing {
+		s.enqueueDisburse(wd.ID)
+	}
+	return wd, nil
+}
+
+// Reject menggagalkan penarikan pending atau review sebelum dicairkan dan
+// mengembalikan hold ke saldo user.
+func (s *WithdrawService) Reject(actorID, id uint, reason string) (*model.Withdraw, error) {
+	var wd *model.Withdraw
+	err := s.WithdrawRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		wd, err = s.WithdrawRepo.WithTx(tx).LockByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWithdrawNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if wd.Status != model.WithdrawStatusPending && wd.Status != model.WithdrawStatusReview {
+			return ErrInvalidWithdrawTransition
+		}
+		if actorID == wd.UserID {
+			return ErrSelfApproval
+		}
+		wd, err = s.transitionTx(tx, id, WithdrawStatusUpdate{Status: model.WithdrawStatusFailed, Reason: reason, ActorID: actorID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wd, nil
+}
+
+// transition menjalankan satu perubahan status dalam transaksinya sendiri.
+func (s *WithdrawService) transition(id uint, u WithdrawStatusUpdate) (*model.Withdraw, error) {
+	var wd *model.Withdraw
+	err := s.WithdrawRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		wd, err = s.transitionTx(tx, id, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wd, nil
+}
+
+// transitionTx menjalankan satu perubahan status dan mencatatnya di audit trail.
+// Sukses membayarkan hold ke akun payout dan biaya, gagal mengembalikan hold ke
+// saldo user; semuanya dalam transaksi tx.
+func (s *WithdrawService) transitionTx(tx *gorm.DB, id uint, u WithdrawStatusUpdate) (*model.Withdraw, error) {
+	withdraws := s.WithdrawRepo.WithTx(tx)
+	wd, err := withdraws.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWithdrawNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canTransitionWithdraw(wd.Status, u.Status) {
+		return nil, ErrInvalidWithdrawTransition
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"status": u.Status}
+	switch u.Status {
+	case model.WithdrawStatusProcessing:
+		updates["processing_at"] = now
+	case model.WithdrawStatusSuccess:
+		updates["completed_at"] = now
+	case model.WithdrawStatusFailed:
+		if u.Reason == "" {
+			return nil, ErrFailureReasonRequired
+		}
+		updates["completed_at"] = now
+		updates["failure_reason"] = u.Reason
+	}
+	ok, err := withdraws.UpdateStatusIf(id, wd.Status, updates)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidWithdrawTransition
+	}
+	action := model.WithdrawDecisionStatusChanged
+	if u.Status == model.WithdrawStatusFailed && wd.Status != model.WithdrawStatusProcessing {
+		action = model.WithdrawDecisionRejected
+	}
+	if err := s.DecisionRepo.WithTx(tx).Create(newDecision(id, u.ActorID, action, wd.Status, u.Status, u.Reason)); err != nil {
+		return nil, err
+	}
+	if err := s.postTransition(tx, wd, u.Status); err != nil {
+		return nil, err
+	}
+	return withdraws.GetByID(id)
+}
+
+// postTransition memposting entri ledger untuk status akhir penarikan. Penarikan
+// dari sebelum ada hold tidak punya entri hold, jadi hanya statusnya yang berubah.
+func (s *WithdrawService) postTransition(tx *gorm.DB, wd *model.Withdraw, status string) error {
+	if status != model.WithdrawStatusSuccess && status != model.WithdrawStatusFailed {
+		return nil
+	}
+	refID := strconv.FormatUint(uint64(wd.ID), 10)
+	if _, err := s.Wallets.LedgerRepo.WithTx(tx).FindByRef(userAccount(wd.UserID), model.RupiahEntryWithdrawalHold, "withdraw", refID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if status == model.WithdrawStatusSuccess {
+		return s.Wallets.SettleHoldTx(tx, wd.UserID, wd.Amount, wd.Fee, "withdraw", refID, "penarikan #"+refID+" ke "+wd.Target)
+	}
+	_, err := s.Wallets.PostTx(tx, RupiahPosting{
+		UserID:      wd.UserID,
+		Amount:      wd.Amount + wd.Fee,
+		EntryType:   model.RupiahEntryWithdrawalRelease,
+		RefType:     "withdraw",
+		RefID:       refID,
+		Description: "penarikan #" + refID + " gagal",
+	})
+	return err
 }
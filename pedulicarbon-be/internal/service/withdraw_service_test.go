@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestWithdrawService(t *testing.T, db *gorm.DB) (*WithdrawService, *model.User) {
+	t.Helper()
+	wallets := newTestWalletService(db)
+	user := &model.User{Name: "Alice", Email: "alice@example.com"}
+	if err := NewUserService(wallets.UserRepo, wallets.WalletRepo).RegisterUser(user, "rahasia-123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Post(RupiahPosting{UserID: user.ID, Amount: 100000, EntryType: model.RupiahEntryAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+	policy := WithdrawPolicy{
+		MinAmount: 10000,
+		DailyCap:  60000,
+		Fees: map[string]WithdrawFee{
+			model.WithdrawChannelBank:    {Flat: 5000},
+			model.WithdrawChannelEWallet: {Flat: 1000, BasisPoints: 100},
+		},
+	}
+	beneficiaries := NewBeneficiaryService(repository.NewBeneficiaryRepository(db), NewNotificationService(repository.NewNotificationRepository(db)), 0)
+	return NewWithdrawService(repository.NewWithdrawRepository(db), repository.NewPayoutEventRepository(db), repository.NewWithdrawDecisionRepository(db), wallets, beneficiaries, nil, nil, policy), user
+}
+
+// beneficiaryFor mengembalikan ID rekening tujuan user untuk target, dibuat bila belum ada.
+func beneficiaryFor(t *testing.T, s *WithdrawService, userID uint, target string) uint {
+	t.Helper()
+	list, _ := s.Beneficiaries.List(userID)
+	for _, b := range list {
+		if b.Target() == target {
+			return b.ID
+		}
+	}
+	parts := strings.Split(target, ":")
+	b := &model.Beneficiary{Channel: model.WithdrawChannelEWallet, Provider: parts[0], AccountNumber: parts[len(parts)-1], HolderName: "Alice"}
+	if parts[0] == model.WithdrawChannelBank {
+		b.Channel, b.Provider = model.WithdrawChannelBank, parts[1]
+	}
+	if err := s.Beneficiaries.Create(userID, b); err != nil {
+		t.Fatal(err)
+	}
+	return b.ID
+}
+
+func TestParseWithdrawTarget(t *testing.T) {
+	valid := map[string]string{
+		"bank:bca:1234567890": "bank:BCA:1234567890",
+		" GoPay:081234567890": "gopay:081234567890",
+		"dana:+6281234567890": "dana:+6281234567890",
+	}
+	for in, want := range valid {
+		if _, got, err := ParseWithdrawTarget(in); err != nil || got != want {
+			t.Errorf("ParseWithdrawTarget(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "BCA 1234567890", "bank:BCA:12ab", "bank:1234567890", "linkaja:081234567890", "ovo:12345"} {
+		if _, _, err := ParseWithdrawTarget(in); !errors.Is(err, ErrInvalidWithdrawTarget) {
+			t.Errorf("ParseWithdrawTarget(%q) err = %v, want ErrInvalidWithdrawTarget", in, err)
+		}
+	}
+}
+
+func TestWithdrawHoldsAndSettlesBalance(t *testing.T) {
+	db := newTestDB(t)
+	s, user := newTestWithdrawService(t, db)
+
+	if _, err := s.CreateWithdraw(user.ID, 5000, beneficiaryFor(t, s, user.ID, "bank:BCA:1234567890")); !errors.Is(err, ErrInvalidWithdraw) {
+		t.Fatalf("below minimum err = %v, want ErrInvalidWithdraw", err)
+	}
+	if _, err := s.CreateWithdraw(user.ID, -20000, beneficiaryFor(t, s, user.ID, "bank:BCA:1234567890")); !errors.Is(err, ErrInvalidWithdraw) {
+		t.Fatalf("negative amount err = %v, want ErrInvalidWithdraw", err)
+	}
+
+	bank, err := s.CreateWithdraw(user.ID, 40000, beneficiaryFor(t, s, user.ID, "bank:BCA:1234567890"))
+	if err != nil || bank.Fee != 5000 || bank.Status != model.WithdrawStatusPending {
+		t.Fatalf("bank withdraw = %+v, %v", bank, err)
+	}
+	if _, err := s.CreateWithdraw(user.ID, 30000, beneficiaryFor(t, s, user.ID, "gopay:081234567890")); !errors.Is(err, ErrWithdrawDailyCap) {
+		t.Fatalf("over daily cap err = %v, want ErrWithdrawDailyCap", err)
+	}
+	ewallet, err := s.CreateWithdraw(user.ID, 20000, beneficiaryFor(t, s, user.ID, "gopay:081234567890"))
+	if err != nil || ewallet.Fee != 1200 {
+		t.Fatalf("ewallet withdraw = %+v, %v; want fee 1200", ewallet, err)
+	}
+	wallet, _ := s.Wallets.GetWallet(user.ID)
+	if wallet.Rupiah != 33800 || wallet.HeldRupiah != 66200 {
+		t.Fatalf("wallet = %+v; want rupiah 33800, held 66200", wallet)
+	}
+
+	// Status hanya boleh maju pending -> processing -> success/failed
+	if _, err := s.UpdateWithdrawStatus(bank.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusSuccess}); !errors.Is(err, ErrInvalidWithdrawTransition) {
+		t.Fatalf("pending -> success err = %v, want ErrInvalidWithdrawTransition", err)
+	}
+	for _, id := range []uint{bank.ID, ewallet.ID} {
+		if _, err := s.UpdateWithdrawStatus(id, WithdrawStatusUpdate{Status: model.WithdrawStatusProcessing}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wd, err := s.UpdateWithdrawStatus(bank.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusSuccess}); err != nil || wd.CompletedAt == nil {
+		t.Fatalf("success = %+v, %v", wd, err)
+	}
+	if _, err := s.UpdateWithdrawStatus(ewallet.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusFailed}); !errors.Is(err, ErrFailureReasonRequired) {
+		t.Fatalf("failed without reason err = %v, want ErrFailureReasonRequired", err)
+	}
+	if _, err := s.UpdateWithdrawStatus(ewallet.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusFailed, Reason: "nomor tidak terdaftar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateWithdrawStatus(ewallet.ID, WithdrawStatusUpdate{Status: model.WithdrawStatusSuccess}); !errors.Is(err, ErrInvalidWithdrawTransition) {
+		t.Fatalf("failed -> success err = %v, want ErrInvalidWithdrawTransition", err)
+	}
+
+	// Penarikan gagal dikembalikan penuh dan tidak dihitung ke batas harian
+	wallet, _ = s.Wallets.GetWallet(user.ID)
+	if wallet.Rupiah != 55000 || wallet.HeldRupiah != 0 {
+		t.Fatalf("wallet = %+v; want rupiah 55000, held 0", wallet)
+	}
+	if _, err := s.CreateWithdraw(user.ID, 20000, beneficiaryFor(t, s, user.ID, "ovo:081234567890")); err != nil {
+		t.Fatalf("withdraw within remaining cap: %v", err)
+	}
+	var unbalanced []string
+	db.Model(&model.WalletTransaction{}).Group("journal_id").Having("SUM(amount) <> 0").Pluck("journal_id", &unbalanced)
+	if rows, err := s.Wallets.LedgerRepo.ListUnexplainedBalances(); err != nil || len(rows) != 0 || len(unbalanced) != 0 {
+		t.Fatalf("unexplained = %+v, unbalanced = %v, %v", rows, unbalanced, err)
+	}
+}
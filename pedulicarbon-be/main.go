@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"pedulicarbon/internal/api"
 	"pedulicarbon/internal/model"
+	"pedulicarbon/internal/repository"
+	"pedulicarbon/internal/service"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -29,7 +34,48 @@ func main() {
 	fmt.Printf("DB_PORT: %s\n", os.Getenv("DB_PORT"))
 	fmt.Println("=============================")
 
-	// Database connection
+	if os.Getenv("JWT_SECRET") == "" {
+		log.Fatal("JWT_SECRET environment variable is required")
+	}
+
+	db := openDB()
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile-nfts" {
+		reconcileNFTs(db, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		setRole(db, os.Args[2:])
+		return
+	}
+
+	// Job queue canister
+	jobService := service.NewJobService(repository.NewJobRepository(db))
+	jobService.MaxAttempts = max(api.EnvInt("JOB_MAX_ATTEMPTS", service.DefaultJobMaxAttempts), 1)
+
+	// Initialize router
+	r := api.InitRouter(db, jobService)
+
+	workers := max(api.EnvInt("JOB_WORKERS", service.DefaultJobWorkers), 1)
+	jobService.Start(context.Background(), workers)
+	fmt.Printf("[INFO] Started %d canister job workers\n", workers)
+
+	// Get port
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	fmt.Printf("[INFO] Starting server on port %s\n", port)
+	fmt.Println("[INFO] Health check available at: http://localhost:" + port + "/health")
+
+	if err := r.Run(":" + port); err != nil {
+		log.Fatal("Failed to start server: ", err)
+	}
+}
+
+// openDB membuka koneksi Postgres dan menjalankan auto migrate.
+func openDB() *gorm.DB {
 	dsn := "host=" + os.Getenv("DB_HOST") + " user=" + os.Getenv("DB_USER") + " password=" + os.Getenv("DB_PASSWORD") + " dbname=" + os.Getenv("DB_NAME") + " port=" + os.Getenv("DB_PORT") + " sslmode=disable"
 
 	fmt.Printf("[DEBUG] Connecting to database: %s\n", dsn)
@@ -40,27 +86,124 @@ func main() {
 	}
 	fmt.Println("[SUCCESS] Database connected successfully")
 
+	// Wallet dulu bisa dibuat berkali-kali lewat POST /wallets dan saldonya diisi
+	// client, bukan dari ledger. Wallet ganda diarsipkan ke wallet_quarantines lalu
+	// dihapus supaya unique index user_id bisa dibuat, dan kolom float rupiah lama
+	// disimpan sebagai legacy_rupiah alih-alih di-cast (dibulatkan) oleh AutoMigrate.
+	wallets := service.NewWalletService(repository.NewWalletRepository(db), repository.NewWalletTransactionRepository(db), repository.NewUserRepository(db), repository.NewUserNFTRepository(db), repository.NewWalletQuarantineRepository(db))
+	if db.Migrator().HasTable(&model.Wallet{}) {
+		if err := db.AutoMigrate(&model.WalletQuarantine{}); err != nil {
+			log.Fatal("Failed to migrate wallet quarantine: ", err)
+		}
+		if n, err := wallets.QuarantineDuplicateWallets(); err != nil {
+			log.Fatal("Failed to remove duplicate wallets: ", err)
+		} else if n > 0 {
+			fmt.Printf("[WARNING] Wallet: %d duplicate wallets archived to wallet_quarantines and removed\n", n)
+		}
+		if renamed, err := wallets.WalletRepo.ArchiveFloatRupiahColumn(); err != nil {
+			log.Fatal("Failed to archive legacy rupiah column: ", err)
+		} else if renamed {
+			fmt.Println("[INFO] Wallet: float rupiah column kept as legacy_rupiah")
+		}
+	}
+
 	// Auto migrate
 	fmt.Println("[DEBUG] Running database migrations...")
-	err = db.AutoMigrate(&model.User{}, &model.Mission{}, &model.Reward{}, &model.Wallet{}, &model.MissionTaken{}, &model.RewardCatalog{}, &model.Withdraw{}, &model.UserNFT{})
+	err = db.AutoMigrate(&model.User{}, &model.Mission{}, &model.Reward{}, &model.Wallet{}, &model.MissionTaken{}, &model.RewardCatalog{}, &model.Withdraw{}, &model.UserNFT{}, &model.PrincipalChallenge{}, &model.VerificationSaga{}, &model.Job{}, &model.NFTReconciliationAudit{}, &model.PointTransaction{}, &model.VoucherCode{}, &model.ShippingAddress{}, &model.Notification{}, &model.WalletTransaction{}, &model.PayoutEvent{}, &model.Beneficiary{}, &model.WithdrawDecision{}, &model.WalletQuarantine{})
 	if err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
 	fmt.Println("[SUCCESS] Database migrations completed")
 
-	// Initialize router
-	r := api.InitRouter(db)
+	// Saldo point lama yang belum punya baris ledger dicatat sebagai opening balance
+	points := service.NewPointService(repository.NewUserRepository(db), repository.NewPointTransactionRepository(db))
+	if n, err := points.BackfillOpeningBalances(); err != nil {
+		log.Fatal("Failed to backfill point ledger: ", err)
+	} else if n > 0 {
+		fmt.Printf("[INFO] Point ledger: opening balance posted for %d users\n", n)
+	}
+	// Setiap user punya wallet; saldo rupiah di luar ledger dikarantina dan baru
+	// kembali ke user lewat POST /wallets/quarantine/:id/resolve oleh admin
+	if n, err := wallets.ProvisionMissingWallets(); err != nil {
+		log.Fatal("Failed to provision wallets: ", err)
+	} else if n > 0 {
+		fmt.Printf("[INFO] Wallet: provisioned %d missing wallets\n", n)
+	}
+	quarantined, err := wallets.QuarantineUnexplainedBalances()
+	if err != nil {
+		log.Fatal("Failed to quarantine unexplained rupiah balances: ", err)
+	}
+	for _, q := range quarantined {
+		fmt.Printf("[WARNING] Wallet quarantine %d: user %d, %s Rp%s (ledger Rp%d)\n", q.ID, q.UserID, q.Source, q.Amount, q.LedgerRupiah)
+	}
+	if n, err := wallets.UserNFTRepo.BackfillCarbonAmounts(); err != nil {
+		log.Fatal("Failed to backfill NFT carbon amounts: ", err)
+	} else if n > 0 {
+		fmt.Printf("[INFO] UserNFT: carbon amount filled for %d NFTs\n", n)
+	}
+	// Stok katalog voucher selalu mengikuti jumlah kode voucher yang tersedia
+	catalogRepo := repository.NewRewardCatalogRepository(db)
+	vouchers := service.NewVoucherService(repository.NewVoucherCodeRepository(db), catalogRepo, repository.NewRewardRepository(db))
+	if err := vouchers.SyncVoucherStock(); err != nil {
+		log.Fatal("Failed to sync voucher stock: ", err)
+	}
+	return db
+}
 
-	// Get port
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// reconcileNFTs menjalankan `pedulicarbon reconcile-nfts [-repair]`: membandingkan
+// UserNFT dengan canister sekali, mencetak laporan JSON ke stdout, lalu keluar.
+// Exit code 1 bila ada selisih yang belum diperbaiki atau error saat membaca canister.
+func reconcileNFTs(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("reconcile-nfts", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "fix repairable differences and write an audit record for each")
+	fs.Parse(args)
+
+	s := service.NewNFTService(
+		repository.NewUserNFTRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewVerificationSagaRepository(db),
+		repository.NewNFTReconciliationRepository(db),
+		api.NewCanisterClient(),
+	)
+	report, err := s.Reconcile(context.Background(), *repair)
+	if err != nil {
+		log.Fatal("NFT reconciliation failed: ", err)
 	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
 
-	fmt.Printf("[INFO] Starting server on port %s\n", port)
-	fmt.Println("[INFO] Health check available at: http://localhost:" + port + "/health")
+	clean := len(report.Errors) == 0
+	for _, issue := range report.Issues {
+		if !issue.Repaired {
+			clean = false
+		}
+	}
+	if !clean {
+		os.Exit(1)
+	}
+}
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server: ", err)
+// setRole menjalankan `pedulicarbon set-role -email <email> -role <role>`. Semua
+// registrasi mendapat role user dan PUT /users/:id/role butuh user:manage, jadi
+// admin pertama dibuat dari shell server dengan perintah ini.
+func setRole(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email of the registered user")
+	role := fs.String("role", model.RoleAdmin, "role to assign")
+	fs.Parse(args)
+	if *email == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	users := service.NewUserService(repository.NewUserRepository(db), repository.NewWalletRepository(db))
+	user, err := users.UserRepo.GetUserByEmail(*email)
+	if err != nil {
+		log.Fatal("User not found: ", *email)
+	}
+	if err := users.SetRole(user.ID, *role); err != nil {
+		log.Fatal("Failed to set role: ", err)
 	}
+	fmt.Printf("[INFO] Role of user %d (%s) changed from %s to %s\n", user.ID, user.Email, user.Role, *role)
 }